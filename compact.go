@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+)
+
+// RunCompact rewrites every month under BaseDir so that data.quantdev holds
+// exactly one blob per indexed day and index.quantdev is contiguous and
+// duplicate-free. For each day the LAST index row whose blob passes the
+// checksum wins (updateIndex appends, so later rows are newer ingests);
+// orphaned and stale blobs are dropped, and so is a day none of whose rows
+// is valid. Such days are listed, since compaction loses them for good.
+//
+// Months that are already compact are left alone. The files a rewrite
+// replaces are kept as data.quantdev.bak and index.quantdev.bak. With dryRun
+// set nothing is written; the report shows what would change.
+func RunCompact(dryRun bool) {
+	start := time.Now()
+
+	fmt.Println(">>> INDEX COMPACTION <<<")
+	fmt.Printf("BaseDir: %s\n", BaseDir)
+	if dryRun {
+		fmt.Println("Dry run: nothing is written.")
+	}
+	fmt.Println()
+
+	var symbols []string
+	for sym := range discoverSymbols() {
+		symbols = append(symbols, sym)
+	}
+	sort.Strings(symbols)
+
+	var months, unchanged, failed, lostDays int
+	var reclaimed int64
+	for _, sym := range symbols {
+		for dir := range discoverMonthDirs(sym) {
+			st, err := compactMonth(dir, dryRun)
+			if err != nil {
+				failed++
+				fmt.Printf("  [%s] %s  STATUS=FAIL reason=%v\n", sym, dir, err)
+				continue
+			}
+			if !st.Changed {
+				unchanged++
+				continue
+			}
+			months++
+			reclaimed += st.BytesBefore - st.BytesAfter
+			lostDays += len(st.DroppedDays)
+			fmt.Printf("  [%s] %s  days=%d dropped_rows=%d reclaimed=%d bytes\n",
+				sym, dir, st.Days, st.DroppedRows, st.BytesBefore-st.BytesAfter)
+			if len(st.DroppedDays) > 0 {
+				fmt.Printf("      DROPPED days with no valid blob: %v\n", st.DroppedDays)
+			}
+		}
+	}
+
+	verb := "compacted"
+	if dryRun {
+		verb = "would be compacted"
+	}
+	fmt.Printf("\n[compact] %d months %s, %d unchanged, %d failed, %d days dropped, %d bytes reclaimed, in %s\n",
+		months, verb, unchanged, failed, lostDays, reclaimed, time.Since(start))
+}
+
+type compactStats struct {
+	Days        int
+	DroppedRows int
+	DroppedDays []int // indexed days with no valid row, lost by compaction
+	BytesBefore int64
+	BytesAfter  int64
+	Changed     bool // the compacted files differ from the current ones
+}
+
+// compactMonth compacts a single YYYY/MM directory. Both files are written
+// to .tmp siblings and renamed into place data-first, each after moving the
+// current file to a .bak sibling; if the process dies between the renames,
+// `reindex` can rebuild the index from the new data file. Nothing is
+// written when dryRun is set or the month is already compact.
+func compactMonth(dir string, dryRun bool) (compactStats, error) {
+	idxPath := filepath.Join(dir, "index.quantdev")
+	dataPath := filepath.Join(dir, "data.quantdev")
+
+	rows, err := readIndexRows(idxPath)
+	if err != nil {
+		return compactStats{}, err
+	}
+	st, newRows, err := packBlobs(dataPath, rows, !dryRun)
+	if err != nil || !st.Changed || dryRun {
+		return st, err
+	}
+
+	tmp := dataPath + ".tmp"
+	if err := os.Rename(dataPath, dataPath+".bak"); err != nil {
+		return st, err
+	}
+	if err := os.Rename(tmp, dataPath); err != nil {
+		return st, err
+	}
+	if err := copyFile(idxPath, idxPath+".bak"); err != nil {
+		return st, err
+	}
+	if err := writeIndex(idxPath, newRows); err != nil {
+		return st, err
+	}
+	return st, nil
+}
+
+// packBlobs plans the compacted layout of the data file at dataPath, indexed
+// by rows: the last valid row per day, re-packed in day order, each blob
+// starting on a cache line. When write is set and the layout differs from
+// the current one, the kept blobs are streamed from a mapping of the file
+// (see mapDataFile) into dataPath.tmp, so a month is never held in memory.
+// The mapping is released before packBlobs returns.
+func packBlobs(dataPath string, rows []indexRow, write bool) (compactStats, []indexRow, error) {
+	var st compactStats
+	data, release, err := mapDataFile(dataPath)
+	if err != nil {
+		return st, nil, err
+	}
+	defer release()
+	st.BytesBefore = int64(len(data))
+
+	// Last valid row per day.
+	keep := make(map[int]indexRow, len(rows))
+	indexed := make(map[int]bool, len(rows))
+	for _, r := range rows {
+		indexed[r.Day] = true
+		end := r.Offset + r.Length
+		if r.Length == 0 || end < r.Offset || end > uint64(len(data)) {
+			continue
+		}
+		raw := data[r.Offset:end]
+		if blobChecksum(raw) != r.Checksum {
+			continue
+		}
+		if _, err := parseTBHeader(raw, r.Length); err != nil {
+			continue
+		}
+		keep[r.Day] = r
+	}
+	if len(keep) == 0 {
+		return st, nil, fmt.Errorf("no valid rows in index")
+	}
+	st.Days = len(keep)
+	st.DroppedRows = len(rows) - len(keep)
+	for d := range indexed {
+		if _, ok := keep[d]; !ok {
+			st.DroppedDays = append(st.DroppedDays, d)
+		}
+	}
+	sort.Ints(st.DroppedDays)
+
+	days := make([]int, 0, len(keep))
+	for d := range keep {
+		days = append(days, d)
+	}
+	sort.Ints(days)
+
+	// The new layout. A month that already has it, with zeroed padding and
+	// nothing past the last blob, is left alone.
+	newRows := make([]indexRow, 0, len(days))
+	var size uint64
+	zeroPad := true
+	for _, d := range days {
+		r := keep[d]
+		if pad := size % CacheLine; pad != 0 {
+			if size+CacheLine-pad <= uint64(len(data)) {
+				zeroPad = zeroPad && !slices.ContainsFunc(data[size:size+CacheLine-pad], func(b byte) bool { return b != 0 })
+			}
+			size += CacheLine - pad
+		}
+		newRows = append(newRows, indexRow{
+			Day: d,
+			indexEntry: indexEntry{
+				Offset:   size,
+				Length:   r.Length,
+				Checksum: r.Checksum,
+			},
+		})
+		size += r.Length
+	}
+	st.BytesAfter = int64(size)
+	st.Changed = !slices.Equal(rows, newRows) || st.BytesAfter != st.BytesBefore || !zeroPad
+	if !st.Changed || !write {
+		return st, newRows, nil
+	}
+
+	tmp := dataPath + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return st, nil, err
+	}
+	var pad [CacheLine]byte
+	for i, r := range newRows {
+		src := keep[r.Day]
+		if i > 0 {
+			prev := newRows[i-1]
+			if _, err = f.Write(pad[:r.Offset-prev.Offset-prev.Length]); err != nil {
+				break
+			}
+		}
+		if _, err = f.Write(data[src.Offset : src.Offset+src.Length]); err != nil {
+			break
+		}
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return st, nil, err
+	}
+	return st, newRows, nil
+}
+
+// copyFile copies src to dst, replacing dst.
+func copyFile(src, dst string) error {
+	b, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, b, 0o644)
+}
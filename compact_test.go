@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestCompactMonth compacts a month holding a duplicate row (day 1 indexed
+// twice), a stale row (day 2 re-ingested, its first blob superseded), a
+// corrupt row (day 3's only blob damaged) and an orphan blob, and checks
+// the result round-trips through LoadGNCFile. A dry run must write nothing.
+func TestCompactMonth(t *testing.T) {
+	root := t.TempDir()
+	withBaseDir(t, root)
+	defer func(prev bool) { VerifyBlobChecksums = prev }(VerifyBlobChecksums)
+	VerifyBlobChecksums = true
+
+	rng := rand.New(rand.NewSource(1))
+	days := map[int]synthDay{}
+	for d := 1; d <= 4; d++ {
+		days[d] = randomDay(ofiTask{2024, 1, d}, 500+100*d, 40000, rng)
+	}
+	writeSynthMonth(t, root, "BTCUSDT", 2024, 1, days)
+	dir := filepath.Join(root, "BTCUSDT", "2024", "01")
+	idxPath := filepath.Join(dir, "index.quantdev")
+	dataPath := filepath.Join(dir, "data.quantdev")
+
+	rows, err := readIndexRows(idxPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(dataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Re-ingest day 2 at the end of the file, then an orphan blob.
+	days[2] = randomDay(ofiTask{2024, 1, 2}, 800, 40000, rng)
+	blob := encodeTBV1(days[2])
+	rows = append(rows, rows[0], indexRow{Day: 2, indexEntry: indexEntry{
+		Offset: uint64(len(data)), Length: uint64(len(blob)), Checksum: blobChecksum(blob),
+	}})
+	data = append(data, blob...)
+	data = append(data, encodeTBV1(randomDay(ofiTask{2024, 1, 9}, 300, 40000, rng))...)
+	// Damage day 3's only blob.
+	data[rows[2].Offset+TBHdrSize] ^= 0xff
+	if err := os.WriteFile(dataPath, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeIndex(idxPath, rows); err != nil {
+		t.Fatal(err)
+	}
+	oldIdx, err := os.ReadFile(idxPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := compactMonth(dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !st.Changed || st.Days != 3 || st.DroppedRows != 3 || !slices.Equal(st.DroppedDays, []int{3}) {
+		t.Fatalf("dry-run stats = %+v", st)
+	}
+	RunCompact(true)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("dry run left %d files in the month", len(entries))
+	}
+	if now, _ := os.ReadFile(dataPath); !bytes.Equal(now, data) {
+		t.Fatal("dry run changed data.quantdev")
+	}
+	if now, _ := os.ReadFile(idxPath); !bytes.Equal(now, oldIdx) {
+		t.Fatal("dry run changed index.quantdev")
+	}
+
+	RunCompact(false)
+	if _, err := os.Stat(dataPath + ".tmp"); !os.IsNotExist(err) {
+		t.Fatalf("temp file left behind: %v", err)
+	}
+	if bak, _ := os.ReadFile(dataPath + ".bak"); !bytes.Equal(bak, data) {
+		t.Fatal("data.quantdev.bak isn't the original data file")
+	}
+	fi, err := os.Stat(dataPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != st.BytesAfter {
+		t.Fatalf("compacted size = %d, want %d", fi.Size(), st.BytesAfter)
+	}
+
+	var buf []byte
+	for d := 1; d <= 4; d++ {
+		ok := LoadGNCFile(root, "BTCUSDT", ofiTask{2024, 1, d}, &buf)
+		if d == 3 {
+			if ok {
+				t.Fatal("day 3 still loads after its only blob was dropped")
+			}
+			continue
+		}
+		if !ok {
+			t.Fatalf("day %d doesn't load after compaction", d)
+		}
+		cols := &DayColumns{}
+		if _, err := InflateGNC(buf, cols); err != nil {
+			t.Fatalf("day %d: %v", d, err)
+		}
+		checkDecoded(t, days[d], cols)
+	}
+
+	// A compacted month is left alone.
+	if st, err := compactMonth(dir, false); err != nil || st.Changed {
+		t.Fatalf("second compaction: changed=%v err=%v", st.Changed, err)
+	}
+}
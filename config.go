@@ -10,11 +10,296 @@ import (
 //
 //	Z:\DATA\data\BTCUSDT\2020\01\...
 //	Z:\DATA\data\ETHUSDT\2020\01\...
-const BaseDir = `Z:\DATA\data`
+//
+// Overridden by the -base flag.
+var BaseDir = `Z:\DATA\data`
 
 // SamplingRateSec: How often we "snapshot" the continuous physics.
 const SamplingRateSec = 60
 
+// SamplingMode picks the clock test/sweep sample on: "time" (every
+// SamplingRateSec), "trades" (every SampleTrades trades) or "volume" (every
+// SampleVolume of traded quantity). Event clocks sample bursts densely and
+// quiet periods sparsely. 0 sizes the trade/volume clock per day to the time
+// clock's average rate over the previous day (see newDaySampler), so the
+// first day of the data and each day after a gap are skipped. Set with
+// test/sweep/jobs -sampling, -sample-trades and -sample-volume.
+var SamplingMode = "time"
+var SampleTrades = 0
+var SampleVolume = 0.0
+
+// AlignSamplingGrid snaps the sampling grid to UTC multiples of
+// SamplingRateSec (e.g. whole minutes) instead of starting it at each day's
+// first trade, so every day shares the same grid regardless of when trading
+// picked up. Off by default, which keeps the original per-day grid. Set
+// with test/sweep/jobs -align-grid.
+var AlignSamplingGrid = false
+
+// CrossDayLabels labels samples whose horizon runs past the day's last trade
+// from the next calendar day's trades instead of dropping them. Without it a
+// 1h horizon loses the last hour of every day, which skews time-of-day
+// results. Costs one extra day load per day. Set with test/sweep/jobs
+// -cross-day.
+var CrossDayLabels = false
+
+// AdaptiveClamp clips each model output to its own running ClampLowQ and
+// ClampHighQ quantiles (streaming P² estimates over the day's ticks) before
+// sampling, so outliers are tamed on each feature's natural scale. Set with
+// test/sweep/jobs -adaptive-clamp.
+var AdaptiveClamp = false
+var ClampLowQ = 0.01
+var ClampHighQ = 0.99
+
+// ReturnMode selects the price the forward-return labels are measured on:
+// "last" (last trade) or "micromid" (mean of the latest buy- and
+// sell-aggressor prices, which removes most bid-ask bounce; matters most at
+// second-scale horizons). Set with test/sweep -returns.
+var ReturnMode = "last"
+
+// TripleBarrier switches labels from fixed-horizon returns to the triple
+// barrier: the return at the first touch of ±BarrierK sigmas or at the
+// horizon, whichever comes first (see RunStream). Sigma is the trailing tick
+// volatility over BarrierVolTau seconds, scaled by sqrt(horizon). Set with
+// test/sweep/jobs -triple-barrier and -barrier-k; the report then adds a
+// summary of barrier hits and holding periods.
+var TripleBarrier = false
+var BarrierK = 2.0
+var BarrierVolTau = 900.0
+
+// VerifyBlobChecksums re-hashes every blob on read and rejects it if the
+// digest doesn't match the checksum stored in its index row. Off by default:
+// it roughly doubles the cost of a load on cached data. Set with the
+// -verify-checksums flag.
+var VerifyBlobChecksums = false
+
+// SafeDecode makes InflateGNC always use InflateGNCSafe (bounds-checked
+// little-endian reads) instead of mapping columns over the blob with
+// unsafe. Set it for data from outside the downloader; probe always decodes
+// safely. Set with the -safe-decode flag.
+var SafeDecode = false
+
+// UseMmap lets DayLoader serve blobs straight out of a memory-mapped
+// data.quantdev. Loading silently falls back to plain reads if mapping fails.
+// Off by default, so loading goes through LoadGNCFile's plain reads as it
+// always has. Set with the -mmap flag.
+var UseMmap = false
+
+// RankNormMetrics additionally reports MI and ΔLogLoss on the rank-normalized
+// (uniform) signal, which isolates the dependence structure from the shape of
+// the signal's marginal distribution. Off by default, when the text report
+// prints "-" in those columns. Set with test/sweep/jobs -rank-norm.
+var RankNormMetrics = false
+
+// ModelsConfigPath is an optional JSON file (see ModelSpec) that replaces the
+// built-in model list; when absent, GetContinuousModels uses the defaults.
+var ModelsConfigPath = "models.json"
+
+// DistCorrMaxSamples caps the test samples DistanceCorrelation uses: its
+// cost is quadratic, so longer test segments are thinned to an evenly
+// strided subsample of this size (4000 is ~8M pairs per cell). 0 disables
+// the cap.
+var DistCorrMaxSamples = 4000
+
+// MIBins is the per-margin bin count of the report's mutual information;
+// 0 picks each margin's from its Freedman-Diaconis width (see autoBins).
+// MIBiasCorrection applies the Miller-Madow correction to the entropies,
+// without which MI is biased upward on small test segments. Off by default,
+// which keeps the original plug-in estimate. Set with test/sweep/jobs
+// -mi-bias-correction.
+var MIBins = 10
+var MIBiasCorrection = false
+
+// VolTarget, when > 0, scales the sign(signal) strategy in StrategyRiskStats
+// so the underlying returns have this per-sample volatility (e.g. 0.001 =
+// 10 bps). Makes drawdowns and per-trade PnL comparable across symbols with
+// very different volatility. 0 disables scaling. Set with test/sweep/jobs
+// -vol-target.
+var VolTarget = 0.0
+
+// GARCHVolGain makes Kalman_Adapt scale its process noise by a GARCH(1,1)
+// conditional-to-long-run variance ratio on tick log returns (see GARCH11)
+// instead of the ratio of two fixed-window realized variances. GARCHAlpha
+// and GARCHBeta are the starting ARCH and GARCH coefficients (α+β < 1),
+// learned online at GARCHLearnRate (0 keeps them fixed). Set with
+// test/sweep/jobs -garch-vol-gain.
+var GARCHVolGain = false
+var GARCHAlpha = 0.05
+var GARCHBeta = 0.94
+var GARCHLearnRate = 1e-4
+
+// VolRegimeGARCH assigns samples to the report's volatility regimes (and
+// the gated ensemble's) by a GARCH(1,1) conditional vol of the closed
+// labels (see BackwardGARCHVol) instead of their EWMA over VolRegimeWindow
+// samples. Set with test/sweep/jobs -vol-regime-garch.
+var VolRegimeGARCH = false
+
+// AdditiveDrawdown reports MaxDrawdown as the largest drop in the running
+// sum of strategy returns (the original definition) instead of the default
+// fractional drawdown of the compounded equity curve, for comparison. Set
+// with test/sweep/jobs -additive-drawdown.
+var AdditiveDrawdown = false
+
+// PurgeSplit purges every train/test cut of the OOS metrics (the core
+// split, the section splits and each walk-forward fold): train samples
+// whose label window (one horizon) reaches the test period are dropped, so
+// longer horizons purge more. Off by default, which keeps the original
+// unpurged split. Set with test/sweep/jobs -purge.
+var PurgeSplit = false
+
+// EmbargoSamples is how many test samples are skipped right after every
+// OOS cut, so slowly decaying features don't carry train information into
+// the test period. Negative uses the label overlap (horizon / realized
+// sample spacing), i.e. one horizon's worth of samples; 0, the default,
+// disables it. Set with test/sweep/jobs -embargo.
+var EmbargoSamples = 0
+
+// MinEffectiveSamples is the minimum test-segment size, in independent
+// observations, for AnalyzeFullSuiteOOS to compute a (model, horizon) cell's
+// metrics; smaller cells are reported as suppressed, with their counts only.
+// Overlapping labels count fractionally: with samples 60s apart a 1h horizon
+// needs 60x as many raw samples as a 1m one.
+var MinEffectiveSamples = 30.0
+
+// CostBps is the round-trip cost, in bps of notional, charged each time the
+// sign(signal) strategy flips position. Used for NetSharpe in the report.
+var CostBps = 4.0
+
+// ICHACLag is the Newey-West lag for ICTStatHAC. 0 uses the horizon's label
+// overlap, ceil(horizon / realized sample spacing).
+var ICHACLag = 0
+
+// FDRLevel is the false discovery rate at which the report's FDR column
+// flags a cell's IC as significant, after the Benjamini-Hochberg adjustment
+// across every reported (model, horizon) cell.
+var FDRLevel = 0.1
+
+// DailyICLag is the Newey-West lag (days) for the daily-IC t-stat. 0 uses
+// the horizon in days, rounded up.
+var DailyICLag = 0
+
+// BootstrapIntervals adds stationary-bootstrap 95% intervals on PearsonIC
+// and Sharpe to the core table. Off by default: with BootstrapResamples
+// resamples it costs several times the rest of AnalyzeFullSuiteOOS. Set
+// with test/sweep/jobs -bootstrap.
+var BootstrapIntervals = false
+
+// BootstrapResamples is the number of bootstrap resamples; 1000 gives stable
+// 2.5%/97.5% percentiles. Set with test/sweep/jobs -bootstrap-resamples.
+var BootstrapResamples = 1000
+
+// BootstrapMeanBlock is the mean block length, in samples, of the stationary
+// bootstrap. 0 uses the horizon's label overlap (horizon / realized sample
+// spacing), which keeps overlapping labels together.
+var BootstrapMeanBlock = 0.0
+
+// CurveBuckets is the number of signal quantiles in the report's
+// conditional return curve, whose top and bottom buckets give the
+// Spread/TopDecile/BotDecile columns. Use fewer than 10 for sparse signals.
+// Buckets with fewer than MinBucketCount test points are counted in the
+// Thin column: their means, and any spread built on them, are noise.
+var CurveBuckets = 10
+var MinBucketCount = 30
+
+// VolRegimeWindow is the span, in samples, of the backward realized vol
+// that assigns samples to the report's volatility regimes (60 = one hour at
+// the default 60s grid).
+var VolRegimeWindow = 60
+
+// BoundaryFracs are the train fractions at which the report re-runs the
+// train/test split to check that OOS ICs don't hinge on one boundary.
+var BoundaryFracs = []float64{0.5, 0.6, 0.7, 0.8}
+
+// SampleFrac, when < 1 (test/sweep -sample-frac), restricts runs to a
+// pseudo-random subset of roughly that fraction of days, for quick
+// approximate results. SampleSeed (-seed) fixes which days: the same seed
+// always selects the same dates.
+var SampleFrac = 1.0
+var SampleSeed uint64 = 1
+
+// DayFrom and DayTo, when non-zero, restrict runs to the days between them
+// (inclusive). Set per job by RunJobs.
+var DayFrom, DayTo ofiTask
+
+// WalkForwardFolds is the number of expanding-window folds in the report's
+// walk-forward section.
+var WalkForwardFolds = 5
+
+// SweepMinIC, when > 0 (sweep -min-ic), screens sweep candidates before the
+// full run: each is streamed over the earliest SweepScreenFrac of days and
+// dropped unless its best |IC| across horizons reaches SweepMinIC. Keep
+// SweepScreenFrac below the train fraction so screening stays in-sample.
+// 0 (the default) runs every candidate.
+var SweepMinIC = 0.0
+var SweepScreenFrac = 0.2
+
+// RidgeLambda is the L2 penalty of the report's ridge combiner, added to
+// the diagonal of the models' train correlation matrix (signals are
+// z-scored first). 0 is ordinary least squares; larger values shrink the
+// weights toward zero and toward each other for correlated models.
+var RidgeLambda = 0.1
+
+// HoldingStepSec is the bucket width of the report's holding-period PnL
+// (alpha decay) section, which marks sign(signal) entries to market at
+// every later sample up to the longest horizon. Steps finer than the
+// sampling grid leave empty buckets; 0 disables the section.
+var HoldingStepSec = 60.0
+
+// FlushReportSections flushes the text and CSV reports after every report
+// section instead of only once the symbol is done, and the JSON report is
+// rewritten after every symbol, so an interrupted run keeps what it has
+// finished.
+var FlushReportSections = true
+
+// CorrFlagAbove is the |Pearson| between two models' features above which
+// the report's feature-correlation section flags the pair as redundant.
+var CorrFlagAbove = 0.9
+
+// SelectMaxCorr and SelectCount drive the feature-orthogonality section's
+// suggested subset (see SelectModels): at most SelectCount models, picked by
+// train |IC|, no two with train-segment feature |rho| >= SelectMaxCorr.
+var SelectMaxCorr = 0.5
+var SelectCount = 5
+
+// LowMemory (test/jobs -low-mem) keeps only running moments per (model,
+// horizon) cell while streaming (see Moments) instead of every sample, so
+// memory no longer grows with history length. The report is then limited
+// to the moment-based core columns (IC, hit rate, Sharpe) and the sections
+// built on them; deciles, MI, log-loss, drawdowns and every per-sample
+// section are dropped. The train/test cut falls on a day boundary.
+var LowMemory = false
+
+// DumpParquet (test/sweep -dump-parquet) also writes each symbol's sampled
+// features and labels to Features_<SYMBOL>.parquet (see
+// exportResultContainers).
+var DumpParquet = false
+
+// PriceBreakRatio flags a price discontinuity (contract multiplier change,
+// 1000x symbol rename) when a day's first trade is more than this factor
+// above or below the previous day's last trade. Breaks are reported; values
+// <= 1 disable detection.
+var PriceBreakRatio = 1.5
+
+// PriceBreakMode (test/sweep/jobs -price-breaks) says how cross-day labels
+// (CrossDayLabels) treat a break: "split" drops them, so no label spans it;
+// "adjust" rescales the next day's prices by the continuity factor first.
+// Models reset every day and same-day labels are log-ratios, so nothing
+// else sees the jump: without -cross-day no label spans a break and the
+// mode has nothing to do, so the flag is rejected there. The history itself
+// is never split at a break; train/test and rolling segments run across it.
+var PriceBreakMode = "split"
+
+// ReportFormat (test/sweep -format) selects the report output: "text" writes
+// the per-symbol tabwriter reports; "csv" (also -csv) writes the per-symbol
+// CSV (see csvReport) instead, with every ReportStats field as a column;
+// "json" writes a single JSON document per run (see JSONReport) with the
+// core, rolling-window and regime stats nested per cell. Column and key
+// order is fixed, so outputs diff cleanly across runs.
+var ReportFormat = "text"
+
+// ResultsDBPath, when set (test -db <path>), also writes every report row to
+// a SQLite database for querying across runs. Empty disables export.
+var ResultsDBPath = ""
+
 // Horizon definitions for the regression targets.
 var HorizonLabels = []string{"15m", "30m", "1h"}
 var HorizonDelays = []int64{
@@ -24,6 +309,7 @@ var HorizonDelays = []int64{
 }
 
 // System tuning for Ryzen 9 7900X (leave 2 cores free for OS/other work).
+// Overridden by the -threads flag.
 var CPUThreads = func() int {
 	n := runtime.GOMAXPROCS(0)
 	if n > 4 {
@@ -32,8 +318,24 @@ var CPUThreads = func() int {
 	return n
 }()
 
+// SymbolFilter, set by the -symbols flag, restricts symbol discovery (and
+// so test, sweep, jobs, probe, reindex and compact) to the symbols matching
+// one of its comma-separated globs, e.g. "BTCUSDT,ETH*". Empty keeps every
+// symbol under BaseDir.
+var SymbolFilter = ""
+
+// SymbolOverride, set by the -symbol flag, restricts symbol discovery to
+// that one symbol, for every command, and so replaces Symbol's preference
+// order when non-empty. It must name a symbol under BaseDir that also
+// passes SymbolFilter.
+var SymbolOverride = ""
+
 // Symbol selects which symbol to run research/OOS on.
 func Symbol() string {
+	if SymbolOverride != "" {
+		return SymbolOverride
+	}
+
 	// Preference order among discovered symbols.
 	preferred := []string{
 		"BTCUSDT",
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"text/tabwriter"
+)
+
+// symbolStats is one symbol's core table, core[model][horizon], as returned
+// by RunTestForSymbol.
+type symbolStats struct {
+	Symbol string
+	Core   [][]ReportStats
+}
+
+// crossSymbolRow aggregates one (model, horizon) cell over the symbols that
+// reported it. ICT is the t-stat of the mean IC across symbols, treating
+// each symbol as one independent draw.
+type crossSymbolRow struct {
+	N                     int
+	ICMean, ICStd         float64
+	ICMin, ICMax, ICT     float64
+	SharpeMean, SharpeStd float64
+	Positive, FDRPass     int
+}
+
+// Verdict classifies a row: "ROBUST" when every symbol's IC has the same
+// sign, "MIXED" when they disagree, and "-" with fewer than two symbols.
+func (r crossSymbolRow) Verdict() string {
+	switch {
+	case r.N < 2:
+		return "-"
+	case r.Positive == r.N || r.Positive == 0:
+		return "ROBUST"
+	default:
+		return "MIXED"
+	}
+}
+
+// crossSymbolCell collects cell (m, h) from every symbol that reported it;
+// suppressed and empty cells are left out.
+func crossSymbolCell(all []symbolStats, m, h int) crossSymbolRow {
+	var ics, sharpes []float64
+	var r crossSymbolRow
+	for _, s := range all {
+		if m >= len(s.Core) || h >= len(s.Core[m]) {
+			continue
+		}
+		st := s.Core[m][h]
+		if st.TestCount == 0 || st.Suppressed {
+			continue
+		}
+		ics = append(ics, st.PearsonIC)
+		sharpes = append(sharpes, st.Sharpe)
+		if st.PearsonIC > 0 {
+			r.Positive++
+		}
+		if st.FDRPass() {
+			r.FDRPass++
+		}
+	}
+	r.N = len(ics)
+	if r.N == 0 {
+		return r
+	}
+	r.ICMean, r.ICStd = meanStd(ics)
+	r.SharpeMean, r.SharpeStd = meanStd(sharpes)
+	r.ICMin, r.ICMax = ics[0], ics[0]
+	for _, ic := range ics[1:] {
+		r.ICMin = math.Min(r.ICMin, ic)
+		r.ICMax = math.Max(r.ICMax, ic)
+	}
+	if r.N > 1 && r.ICStd > 0 {
+		// meanStd's std is the population one: sample std / √n = it / √(n-1).
+		r.ICT = r.ICMean / (r.ICStd / math.Sqrt(float64(r.N-1)))
+	}
+	return r
+}
+
+// writeCrossSymbolSummary writes, per (model, horizon), the mean and
+// dispersion of the OOS IC and Sharpe across symbols, so a signal that holds
+// everywhere stands apart from one symbol's fluke. Pos counts the symbols
+// with positive IC and FDR those whose IC passes the per-symbol
+// Benjamini-Hochberg cut.
+func writeCrossSymbolSummary(filename string, modelNames []string, allStats []symbolStats) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := tabwriter.NewWriter(f, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(w, "# Cross-symbol summary (%d symbols; ROBUST = IC sign agrees on every symbol)\n", len(allStats))
+	fmt.Fprintf(w, "MODEL\tHORIZON\tN\tICMean\tICStd\tICMin\tICMax\tICt\tSharpeMean\tSharpeStd\tPos\tFDR\tVerdict\n")
+	fmt.Fprintf(w, "-----\t-------\t-\t------\t-----\t-----\t-----\t---\t----------\t---------\t---\t---\t-------\n")
+	for mIdx, name := range modelNames {
+		for hIdx, hName := range HorizonLabels {
+			r := crossSymbolCell(allStats, mIdx, hIdx)
+			if r.N == 0 {
+				continue
+			}
+			fmt.Fprintf(
+				w,
+				"%s\t%s\t%d\t%.4f\t%.4f\t%.4f\t%.4f\t%.2f\t%.4f\t%.4f\t%d/%d\t%d\t%s\n",
+				name,
+				hName,
+				r.N,
+				r.ICMean,
+				r.ICStd,
+				r.ICMin,
+				r.ICMax,
+				r.ICT,
+				r.SharpeMean,
+				r.SharpeStd,
+				r.Positive,
+				r.N,
+				r.FDRPass,
+				r.Verdict(),
+			)
+		}
+		fmt.Fprintf(w, "\n")
+	}
+	return w.Flush()
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// reportField is one ReportStats column. csv is its machine-readable name
+// ("" = text report only); text is its header in the tabwriter core table
+// ("" = CSV only) and format the verb used there.
+type reportField struct {
+	csv, text, format string
+	get               func(s ReportStats) any
+}
+
+// statsFields lists the ReportStats columns in core-table order. Both the
+// text report and the CSV export are driven from it via statsToRecord.
+var statsFields = []reportField{
+	{"train_n", "TrainN", "%d", func(s ReportStats) any { return s.TrainCount }},
+	{"test_n", "TestN", "%d", func(s ReportStats) any { return s.TestCount }},
+	{"effective_n", "", "", func(s ReportStats) any { return s.EffectiveN }},
+	{"suppressed", "", "", func(s ReportStats) any { return s.Suppressed }},
+	{"pearson_ic", "PearsonIC", "%.4f", func(s ReportStats) any { return s.PearsonIC }},
+	{"", "IC_95%", "%s", func(s ReportStats) any { return ciString(s.PearsonICLow, s.PearsonICHigh, 4) }},
+	{"pearson_ic_low", "", "", func(s ReportStats) any { return s.PearsonICLow }},
+	{"pearson_ic_high", "", "", func(s ReportStats) any { return s.PearsonICHigh }},
+	{"ic_tstat", "IC_t", "%.2f", func(s ReportStats) any { return s.ICTStat }},
+	{"ic_tstat_hac", "IC_t(HAC)", "%.2f", func(s ReportStats) any { return s.ICTStatHAC }},
+	{"ic_p", "IC_p", "%.3f", func(s ReportStats) any { return s.ICPValue }},
+	{"ic_q", "IC_q", "%.3f", func(s ReportStats) any { return s.ICQValue }},
+	{"", "FDR", "%s", func(s ReportStats) any { return fdrFlag(s) }},
+	{"fdr_pass", "", "", func(s ReportStats) any { return s.FDRPass() }},
+	{"daily_ic_days", "", "", func(s ReportStats) any { return s.DailyICDays }},
+	{"daily_ic_mean", "", "", func(s ReportStats) any { return s.DailyICMean }},
+	{"daily_ic_tstat", "", "", func(s ReportStats) any { return s.DailyICTStat }},
+	{"daily_ic_tstat_nw", "", "", func(s ReportStats) any { return s.DailyICTStatNW }},
+	{"spearman_ic", "SpearmanIC", "%.4f", func(s ReportStats) any { return s.SpearmanIC }},
+	{"kendall_tau", "KendallTau", "%.4f", func(s ReportStats) any { return s.KendallTau }},
+	{"dcor", "dCor", "%.4f", func(s ReportStats) any { return s.DistCorr }},
+	{"hit_rate", "HitRate", "%.3f", func(s ReportStats) any { return s.HitRate }},
+	{"hit_rate_z", "HitZ", "%.2f", func(s ReportStats) any { return s.HitRateZ }},
+	{"is_sharpe", "", "", func(s ReportStats) any { return s.ISSharpe }},
+	{"sharpe", "Sharpe", "%.3f", func(s ReportStats) any { return s.Sharpe }},
+	{"", "Sharpe_95%", "%s", func(s ReportStats) any { return ciString(s.SharpeLow, s.SharpeHigh, 3) }},
+	{"sharpe_low", "", "", func(s ReportStats) any { return s.SharpeLow }},
+	{"sharpe_high", "", "", func(s ReportStats) any { return s.SharpeHigh }},
+	{"sharpe_ann", "SharpeAnn", "%.2f", func(s ReportStats) any { return s.SharpeAnn }},
+	{"dsr", "DSR", "%.3f", func(s ReportStats) any { return s.DSR }},
+	{"gross_sharpe", "GrossSharpe", "%.3f", func(s ReportStats) any { return s.GrossSharpe }},
+	{"net_sharpe", "NetSharpe", "%.3f", func(s ReportStats) any { return s.NetSharpe }},
+	{"flips", "", "", func(s ReportStats) any { return s.Flips }},
+	{"turnover", "Turnover", "%.3f", func(s ReportStats) any { return s.Turnover }},
+	{"spread_bps", "Spread(bps)", "%+.1f", func(s ReportStats) any { return s.SpreadBps }},
+	{"top_decile_bps", "TopDecile(bps)", "%+.1f", func(s ReportStats) any { return s.TopDecileRetBps }},
+	{"bottom_decile_bps", "BotDecile(bps)", "%+.1f", func(s ReportStats) any { return s.BottomDecileRetBps }},
+	{"thin_buckets", "Thin", "%d", func(s ReportStats) any { return s.ThinBuckets }},
+	{"decile_mean", "", "", func(s ReportStats) any { return s.DecileMean }},
+	{"decile_count", "", "", func(s ReportStats) any { return s.DecileCount }},
+	{"decile_stderr", "", "", func(s ReportStats) any { return s.DecileStdErr }},
+	{"mi", "MI(bits)", "%.3f", func(s ReportStats) any { return s.MutualInfo }},
+	{"nmi", "NMI", "%.3f", func(s ReportStats) any { return s.NormalizedMI }},
+	{"", "MI_Rank(bits)", "%s", func(s ReportStats) any { return rankString(s.MutualInfoRank, 3) }},
+	{"mi_rank", "", "", func(s ReportStats) any { return s.MutualInfoRank }},
+	{"nmi_rank", "", "", func(s ReportStats) any { return s.NormalizedMIRank }},
+	{"baseline_logloss", "", "", func(s ReportStats) any { return s.BaselineLogLoss }},
+	{"signal_logloss", "", "", func(s ReportStats) any { return s.SignalLogLoss }},
+	{"delta_logloss", "ΔLogLoss", "%.4f", func(s ReportStats) any { return s.DeltaLogLoss }},
+	{"", "ΔLogLoss_Rank", "%s", func(s ReportStats) any { return rankString(s.DeltaLogLossRank, 4) }},
+	{"delta_logloss_rank", "", "", func(s ReportStats) any { return s.DeltaLogLossRank }},
+	{"vol_scale", "", "", func(s ReportStats) any { return s.VolScale }},
+	{"max_drawdown", "", "", func(s ReportStats) any { return s.MaxDrawdown }},
+	{"max_dd_duration", "DDDur", "%d", func(s ReportStats) any { return s.MaxDDDuration }},
+	{"avg_underwater", "AvgUW", "%.1f", func(s ReportStats) any { return s.AvgUnderwater }},
+	{"avg_trade", "", "", func(s ReportStats) any { return s.AvgTrade }},
+	{"avg_win", "", "", func(s ReportStats) any { return s.AvgWin }},
+	{"avg_loss", "", "", func(s ReportStats) any { return s.AvgLoss }},
+	{"win_loss_ratio", "", "", func(s ReportStats) any { return s.WinLossRatio }},
+	{"trade_skew", "", "", func(s ReportStats) any { return s.TradeSkew }},
+	{"trade_kurtosis", "", "", func(s ReportStats) any { return s.TradeKurtosis }},
+}
+
+// statsHeader returns the column names of statsToRecord.
+func statsHeader(text bool) []string {
+	var out []string
+	for _, f := range statsFields {
+		if name := f.name(text); name != "" {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// statsToRecord formats s as one row: the text-report columns with their
+// display precision when text is set, else every CSV column at full
+// precision.
+func statsToRecord(s ReportStats, text bool) []string {
+	var out []string
+	for _, f := range statsFields {
+		if f.name(text) == "" {
+			continue
+		}
+		if text {
+			out = append(out, fmt.Sprintf(f.format, f.get(s)))
+		} else {
+			out = append(out, csvValue(f.get(s)))
+		}
+	}
+	return out
+}
+
+func (f reportField) name(text bool) string {
+	if text {
+		return f.text
+	}
+	return f.csv
+}
+
+func csvValue(v any) string {
+	switch x := v.(type) {
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	case []float64:
+		parts := make([]string, len(x))
+		for i, f := range x {
+			parts[i] = strconv.FormatFloat(f, 'g', -1, 64)
+		}
+		return strings.Join(parts, ";")
+	case []int:
+		parts := make([]string, len(x))
+		for i, n := range x {
+			parts[i] = strconv.Itoa(n)
+		}
+		return strings.Join(parts, ";")
+	default:
+		return fmt.Sprint(x)
+	}
+}
+
+// csvReport is the machine-readable twin of the text report: one row per
+// core (symbol, model, horizon) cell plus the rolling-window and regime
+// rows, told apart by the section column. Window and regime rows only fill
+// the columns they have. A nil *csvReport discards everything.
+type csvReport struct {
+	sym string
+	f   *os.File
+	w   *csv.Writer
+	col map[string]int
+	n   int
+}
+
+var csvLeadColumns = []string{"section", "symbol", "model", "horizon", "key", "start_time", "end_time"}
+
+func createCSVReport(path, sym string) (*csvReport, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	header := append(append([]string{}, csvLeadColumns...), statsHeader(false)...)
+	c := &csvReport{sym: sym, f: f, w: csv.NewWriter(f), col: make(map[string]int, len(header)), n: len(header)}
+	for i, h := range header {
+		c.col[h] = i
+	}
+	c.w.Write(header)
+	return c, nil
+}
+
+// Stats writes a core-table row.
+func (c *csvReport) Stats(model, horizon string, s ReportStats) {
+	if c == nil {
+		return
+	}
+	c.w.Write(append([]string{"core", c.sym, model, horizon, "", "", ""}, statsToRecord(s, false)...))
+}
+
+// Window writes a rolling-window row keyed by window index.
+func (c *csvReport) Window(model, horizon string, win int, wm WindowMetrics) {
+	c.partial("rolling", model, horizon, strconv.Itoa(win), map[string]any{
+		"start_time": wm.StartTime, "end_time": wm.EndTime, "test_n": wm.Count,
+		"pearson_ic": wm.PearsonIC, "spearman_ic": wm.SpearmanIC, "hit_rate": wm.HitRate, "sharpe": wm.Sharpe,
+	})
+}
+
+// Regime writes a regime row keyed by regime name; kind is "vol" or "tod".
+func (c *csvReport) Regime(model, horizon, kind string, rm RegimeMetrics) {
+	c.partial(kind+"_regime", model, horizon, rm.Name, map[string]any{
+		"test_n":     rm.Count,
+		"pearson_ic": rm.PearsonIC, "spearman_ic": rm.SpearmanIC, "hit_rate": rm.HitRate, "sharpe": rm.Sharpe,
+	})
+}
+
+func (c *csvReport) partial(section, model, horizon, key string, vals map[string]any) {
+	if c == nil {
+		return
+	}
+	rec := make([]string, c.n)
+	rec[0], rec[1], rec[2], rec[3], rec[4] = section, c.sym, model, horizon, key
+	for k, v := range vals {
+		rec[c.col[k]] = csvValue(v)
+	}
+	c.w.Write(rec)
+}
+
+// Flush writes buffered rows through to the file.
+func (c *csvReport) Flush() {
+	if c == nil {
+		return
+	}
+	c.w.Flush()
+}
+
+func (c *csvReport) Close() error {
+	if c == nil {
+		return nil
+	}
+	c.w.Flush()
+	if err := c.w.Error(); err != nil {
+		c.f.Close()
+		return err
+	}
+	return c.f.Close()
+}
@@ -0,0 +1,109 @@
+package main
+
+import "math"
+
+// GARCH11 is an online GARCH(1,1) conditional variance of a return stream,
+// per unit of time so that irregularly spaced returns compare:
+//
+//	σ²ₜ₊₁ = ω + α·xₜ + β·σ²ₜ,  xₜ = rₜ²/dtₜ
+//
+// ω is variance-targeted, ω = (1-α-β)·v̄, with v̄ the running mean of x so
+// far. α and β start at the given values and, with a positive learning
+// rate, follow the Gaussian quasi-likelihood online: one normalized
+// gradient step per update, kept to α, β ≥ garchMinCoef and α+β ≤
+// garchMaxPersist. Unlike a fixed-lambda EWMA the forecast mean-reverts to
+// v̄, so a vol burst decays at rate α+β instead of lingering.
+//
+// It drives Kalman_Adapt's process-noise gain (GARCHVolGain), standing in
+// for the Hawkes adaptive normalization this tree doesn't have, and the
+// vol-regime buckets (VolRegimeGARCH, see BackwardGARCHVol).
+type GARCH11 struct {
+	alpha, beta   float64
+	alpha0, beta0 float64
+	rate          float64
+	omega         float64
+	variance      float64
+	longVar       float64
+	n             int
+
+	pending      float64 // r² of returns with dt = 0, folded into the next step
+	dVarA, dVarB float64 // ∂σ²/∂α and ∂σ²/∂β, carried by the recursion
+}
+
+// Bounds that keep the online α, β a stationary GARCH.
+const (
+	garchMinCoef    = 1e-4
+	garchMaxPersist = 0.999
+)
+
+// NewGARCH11 returns an estimator starting at α, β (α+β < 1) that learns
+// them at rate (0 keeps them fixed).
+func NewGARCH11(alpha, beta, rate float64) *GARCH11 {
+	return &GARCH11{alpha: alpha, beta: beta, alpha0: alpha, beta0: beta, rate: rate}
+}
+
+// Reset clears the state, including learned coefficients.
+func (g *GARCH11) Reset() {
+	*g = GARCH11{alpha: g.alpha0, beta: g.beta0, alpha0: g.alpha0, beta0: g.beta0, rate: g.rate}
+}
+
+// Update folds in one return r realized over dt (> 0, any unit) and
+// returns the next-step variance forecast per unit of dt. Returns with
+// dt <= 0 (trades in the same millisecond) are held and added to the next
+// one's squared return.
+func (g *GARCH11) Update(r, dt float64) float64 {
+	g.pending += r * r
+	if dt <= 0 {
+		return g.variance
+	}
+	x := g.pending / dt
+	g.pending = 0
+
+	g.n++
+	g.longVar += (x - g.longVar) / float64(g.n)
+	if g.n == 1 {
+		g.variance = x
+		g.omega = (1 - g.alpha - g.beta) * g.longVar
+		return g.variance
+	}
+	prev := g.variance
+	if g.rate > 0 && prev > 0 {
+		g.learn(x, prev)
+	}
+	// ω depends on α, β through the targeting: ∂ω/∂α = ∂ω/∂β = -v̄.
+	g.dVarA = -g.longVar + x + g.beta*g.dVarA
+	g.dVarB = -g.longVar + prev + g.beta*g.dVarB
+	g.omega = (1 - g.alpha - g.beta) * g.longVar
+	g.variance = g.omega + g.alpha*x + g.beta*prev
+	return g.variance
+}
+
+// learn takes one gradient step on the quasi-likelihood loss of x under the
+// forecast sigma2, ℓ = log σ² + x/σ². The gradient is scaled by 1/σ² so
+// the step doesn't depend on the units of the returns.
+func (g *GARCH11) learn(x, sigma2 float64) {
+	e := (1 - x/sigma2) / sigma2
+	g.alpha -= g.rate * e * g.dVarA
+	g.beta -= g.rate * e * g.dVarB
+	g.alpha = math.Max(g.alpha, garchMinCoef)
+	g.beta = math.Max(g.beta, garchMinCoef)
+	if s := g.alpha + g.beta; s > garchMaxPersist {
+		g.alpha *= garchMaxPersist / s
+		g.beta *= garchMaxPersist / s
+	}
+}
+
+// Sigma is the current conditional volatility (per square root of the
+// unit of dt).
+func (g *GARCH11) Sigma() float64 { return math.Sqrt(g.variance) }
+
+// Coefs returns the current α and β.
+func (g *GARCH11) Coefs() (alpha, beta float64) { return g.alpha, g.beta }
+
+// Ratio is the conditional over the long-run variance, 1 until both exist.
+func (g *GARCH11) Ratio() float64 {
+	if g.longVar <= 0 {
+		return 1
+	}
+	return g.variance / g.longVar
+}
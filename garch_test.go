@@ -0,0 +1,89 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// simulateGARCH draws n returns from a GARCH(1,1) with long-run variance 1.
+func simulateGARCH(alpha, beta float64, n int, rng *rand.Rand) []float64 {
+	omega := 1 - alpha - beta
+	v := 1.0
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = math.Sqrt(v) * rng.NormFloat64()
+		v = omega + alpha*out[i]*out[i] + beta*v
+	}
+	return out
+}
+
+func TestGARCH11LearnsCoefficients(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	rets := simulateGARCH(0.15, 0.80, 1000000, rng)
+	g := NewGARCH11(0.05, 0.90, 1e-4)
+	for _, r := range rets {
+		g.Update(r, 1)
+	}
+	a, b := g.Coefs()
+	if math.Abs(a-0.15) > 0.04 || math.Abs(b-0.80) > 0.06 {
+		t.Fatalf("learned α=%.3f β=%.3f, want about 0.15 and 0.80", a, b)
+	}
+
+	fixed := NewGARCH11(0.05, 0.90, 0)
+	for _, r := range rets[:1000] {
+		fixed.Update(r, 1)
+	}
+	if a, b := fixed.Coefs(); a != 0.05 || b != 0.90 {
+		t.Fatalf("rate 0 moved the coefficients to α=%g β=%g", a, b)
+	}
+}
+
+// TestGARCH11ScalesByDt feeds constant-vol returns at irregular spacing:
+// the variance is per unit of dt, so it doesn't depend on the spacing.
+func TestGARCH11ScalesByDt(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	g := NewGARCH11(0.05, 0.90, 0)
+	const perUnit = 4.0
+	for range 200000 {
+		dt := 0.1 + 10*rng.Float64()
+		g.Update(math.Sqrt(perUnit*dt)*rng.NormFloat64(), dt)
+	}
+	if got := g.longVar; math.Abs(got/perUnit-1) > 0.02 {
+		t.Fatalf("long-run variance %.3f per unit, want %.1f", got, perUnit)
+	}
+
+	// Two returns in the same millisecond count as one step.
+	h := NewGARCH11(0.05, 0.90, 0)
+	h.Update(0.3, 0)
+	h.Update(0.4, 1)
+	if h.n != 1 || h.variance != 0.25 {
+		t.Fatalf("n=%d variance=%g, want one step of 0.3²+0.4²", h.n, h.variance)
+	}
+}
+
+// TestBackwardGARCHVolNoLookahead checks that a sample's vol ignores its
+// own label and every label still open at its time.
+func TestBackwardGARCHVolNoLookahead(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	const n, horizonMs = 500, 5000
+	times := make([]float64, n)
+	rets := make([]float64, n)
+	for i := range times {
+		times[i] = float64(i * 1000)
+		rets[i] = 0.001 * rng.NormFloat64()
+	}
+	base := BackwardGARCHVol(times, rets, horizonMs)
+	const k = 300
+	rets[k] *= 100
+	bumped := BackwardGARCHVol(times, rets, horizonMs)
+	for i := range n {
+		closed := times[k]+horizonMs <= times[i]
+		if !closed && bumped[i] != base[i] {
+			t.Fatalf("sample %d saw label %d before it closed", i, k)
+		}
+		if closed && i == k+horizonMs/1000 && bumped[i] <= base[i] {
+			t.Fatalf("sample %d didn't see label %d once closed", i, k)
+		}
+	}
+}
@@ -1,14 +1,18 @@
 package main
 
 import (
+	"crypto/sha256"
 	"encoding/binary"
 	"fmt"
 	"io"
 	"iter"
+	"math"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 	"unsafe"
 )
 
@@ -56,6 +60,10 @@ func parseTBHeader(hdr []byte, blobLen uint64) (tbHeader, error) {
 	if rows == 0 {
 		return h, fmt.Errorf("zero rows")
 	}
+	// Keeps rows*8 below from overflowing on a corrupt row count.
+	if rows > blobLen/8 {
+		return h, fmt.Errorf("row count %d exceeds blob", rows)
+	}
 	h.Rows = rows
 	h.OffAgg = binary.LittleEndian.Uint32(hdr[16:20])
 	h.OffPrice = binary.LittleEndian.Uint32(hdr[20:24])
@@ -153,6 +161,9 @@ func mapTradeBlock(raw []byte) (*TradeBlock, error) {
 
 	tb := &TradeBlock{Count: count}
 	base := unsafe.Pointer(&raw[0])
+	if uintptr(base)%8 != 0 {
+		return nil, fmt.Errorf("blob not 8-byte aligned")
+	}
 
 	tb.AggTradeIDs = unsafe.Slice((*uint64)(unsafe.Add(base, uintptr(h.OffAgg))), count)
 	tb.Prices = unsafe.Slice((*float64)(unsafe.Add(base, uintptr(h.OffPrice))), count)
@@ -178,13 +189,17 @@ func (tb *TradeBlock) IsBuyerMaker(i int) bool {
 // --- DayColumns (simple SoA view used by RunStream) ---
 
 // DayColumns is the SoA representation of a single day's trades,
-// used by the streaming feature engine. Right now we only need
-// time/price/quantity for the continuous models.
+// used by the streaming feature engine. Sides (aggressor: +1 buy, -1 sell,
+// from the buyer-maker bit) feeds the micro-mid labels (see ReturnMode);
+// Sides and Matches (fills aggregated into the trade) reach models through
+// Tick (see TickModel).
 type DayColumns struct {
-	Count  int
-	Times  []int64
-	Prices []float64
-	Qtys   []float64
+	Count   int
+	Times   []int64
+	Prices  []float64
+	Qtys    []float64
+	Sides   []int8
+	Matches []int32
 }
 
 // DayColumnPool reduces allocation pressure (critical for GOGC=200).
@@ -193,9 +208,11 @@ var DayColumnPool = sync.Pool{
 		// Pre-allocate for ~1.5M rows (typical busy day).
 		const initCap = 1_500_000
 		return &DayColumns{
-			Times:  make([]int64, 0, initCap),
-			Prices: make([]float64, 0, initCap),
-			Qtys:   make([]float64, 0, initCap),
+			Times:   make([]int64, 0, initCap),
+			Prices:  make([]float64, 0, initCap),
+			Qtys:    make([]float64, 0, initCap),
+			Sides:   make([]int8, 0, initCap),
+			Matches: make([]int32, 0, initCap),
 		}
 	},
 }
@@ -206,29 +223,47 @@ func (c *DayColumns) Reset() {
 	c.Times = c.Times[:0]
 	c.Prices = c.Prices[:0]
 	c.Qtys = c.Qtys[:0]
+	c.Sides = c.Sides[:0]
+	c.Matches = c.Matches[:0]
 }
 
-// FillFromTradeBlock copies the TBV1 SoA into the DayColumns view.
-func (c *DayColumns) FillFromTradeBlock(tb *TradeBlock) {
-	c.Reset()
-	n := tb.Count
-	if n == 0 {
-		return
-	}
-
-	if cap(c.Times) < n {
+// resize sets every column to length n, reallocating only when too small.
+func (c *DayColumns) resize(n int) {
+	if cap(c.Times) < n || cap(c.Sides) < n || cap(c.Matches) < n {
 		c.Times = make([]int64, n)
 		c.Prices = make([]float64, n)
 		c.Qtys = make([]float64, n)
+		c.Sides = make([]int8, n)
+		c.Matches = make([]int32, n)
 	} else {
 		c.Times = c.Times[:n]
 		c.Prices = c.Prices[:n]
 		c.Qtys = c.Qtys[:n]
+		c.Sides = c.Sides[:n]
+		c.Matches = c.Matches[:n]
+	}
+}
+
+// FillFromTradeBlock copies the TBV1 SoA into the DayColumns view.
+func (c *DayColumns) FillFromTradeBlock(tb *TradeBlock) {
+	c.Reset()
+	n := tb.Count
+	if n == 0 {
+		return
 	}
 
+	c.resize(n)
 	copy(c.Times, tb.Times)
 	copy(c.Prices, tb.Prices)
 	copy(c.Qtys, tb.Quantities)
+	for i := range c.Sides {
+		// Buyer is maker => the seller hit the bid.
+		c.Sides[i] = 1
+		if tb.IsBuyerMaker(i) {
+			c.Sides[i] = -1
+		}
+		c.Matches[i] = int32(tb.LastTradeIDs[i] - tb.FirstTradeIDs[i] + 1)
+	}
 
 	c.Count = n
 }
@@ -238,6 +273,32 @@ type ofiTask struct {
 	Year, Month, Day int
 }
 
+// next returns the following calendar day.
+func (t ofiTask) next() ofiTask {
+	d := time.Date(t.Year, time.Month(t.Month), t.Day+1, 0, 0, 0, 0, time.UTC)
+	return ofiTask{Year: d.Year(), Month: int(d.Month()), Day: d.Day()}
+}
+
+// prev returns the preceding calendar day.
+func (t ofiTask) prev() ofiTask {
+	d := time.Date(t.Year, time.Month(t.Month), t.Day-1, 0, 0, 0, 0, time.UTC)
+	return ofiTask{Year: d.Year(), Month: int(d.Month()), Day: d.Day()}
+}
+
+func (t ofiTask) before(u ofiTask) bool {
+	if t.Year != u.Year {
+		return t.Year < u.Year
+	}
+	if t.Month != u.Month {
+		return t.Month < u.Month
+	}
+	return t.Day < u.Day
+}
+
+func (t ofiTask) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", t.Year, t.Month, t.Day)
+}
+
 // LoadGNCFile locates and reads a single TBV1 blob for (sym, day) into buf.
 // Returns false on any error or if the day is not present in the index.
 //
@@ -248,10 +309,11 @@ func LoadGNCFile(baseDir, sym string, t ofiTask, buf *[]byte) bool {
 	idxPath := filepath.Join(dir, "index.quantdev")
 	dataPath := filepath.Join(dir, "data.quantdev")
 
-	offset, length := findBlobOffset(idxPath, t.Day)
-	if length == 0 {
+	entry, ok := findBlobEntry(idxPath, t.Day)
+	if !ok || entry.Length == 0 {
 		return false
 	}
+	offset, length := entry.Offset, entry.Length
 
 	// Safety check: prevent panic if index is corrupted and length is massive.
 	// 512MB is a reasonable upper bound for a single day's blob.
@@ -276,14 +338,29 @@ func LoadGNCFile(baseDir, sym string, t ofiTask, buf *[]byte) bool {
 	if _, err := io.ReadFull(f, *buf); err != nil {
 		return false
 	}
+	if VerifyBlobChecksums && blobChecksum(*buf) != entry.Checksum {
+		return false
+	}
 	return true
 }
 
+// blobChecksum is the value stored in the index row's Checksum field:
+// the first 8 bytes of the blob's SHA-256, little-endian.
+func blobChecksum(raw []byte) uint64 {
+	sum := sha256.Sum256(raw)
+	return binary.LittleEndian.Uint64(sum[:8])
+}
+
 // InflateGNC decodes a TBV1 blob into DayColumns by mapping the TradeBlock
 // and copying just the SoA slices we care about (time, price, qty).
+// With SafeDecode set, or when the blob isn't 8-byte aligned in memory, it
+// uses InflateGNCSafe instead of reinterpreting the bytes.
 //
 // Signature is kept as (int, error) for compatibility with the previous code.
 func InflateGNC(rawBlob []byte, cols *DayColumns) (int, error) {
+	if SafeDecode || len(rawBlob) == 0 || uintptr(unsafe.Pointer(&rawBlob[0]))%8 != 0 {
+		return InflateGNCSafe(rawBlob, cols)
+	}
 	cols.Reset()
 
 	tb, err := mapTradeBlock(rawBlob)
@@ -298,9 +375,42 @@ func InflateGNC(rawBlob []byte, cols *DayColumns) (int, error) {
 	return cols.Count, nil
 }
 
+// InflateGNCSafe is InflateGNC without unsafe: every value is read with
+// binary.LittleEndian, so a misaligned blob still decodes and a truncated or
+// corrupt one fails parseTBHeader's bounds checks with an error instead of
+// reading past the buffer. About 1.6x slower than the mapped path.
+func InflateGNCSafe(raw []byte, cols *DayColumns) (int, error) {
+	cols.Reset()
+
+	h, err := parseTBHeader(raw, uint64(len(raw)))
+	if err != nil {
+		return 0, err
+	}
+	n := int(h.Rows)
+	cols.resize(n)
+
+	le := binary.LittleEndian
+	u64 := func(off uint32, i int) uint64 { return le.Uint64(raw[int(off)+8*i:]) }
+	for i := 0; i < n; i++ {
+		cols.Times[i] = int64(u64(h.OffTime, i))
+		cols.Prices[i] = math.Float64frombits(u64(h.OffPrice, i))
+		cols.Qtys[i] = math.Float64frombits(u64(h.OffQty, i))
+		cols.Matches[i] = int32(u64(h.OffLast, i) - u64(h.OffFirst, i) + 1)
+		// Buyer is maker => the seller hit the bid.
+		cols.Sides[i] = 1
+		if u64(h.OffBits, i/64)&(1<<(i%64)) != 0 {
+			cols.Sides[i] = -1
+		}
+	}
+
+	cols.Count = n
+	return n, nil
+}
+
 // --- Discovery helpers over the TBV1 index tree ---
 
-// discoverSymbols yields all symbols (top-level dirs) under BaseDir.
+// discoverSymbols yields all symbols (top-level dirs) under BaseDir that
+// pass SymbolFilter.
 func discoverSymbols() iter.Seq[string] {
 	return func(yield func(string) bool) {
 		entries, _ := os.ReadDir(BaseDir)
@@ -309,7 +419,7 @@ func discoverSymbols() iter.Seq[string] {
 				continue
 			}
 			name := e.Name()
-			if len(name) == 0 || name[0] == '.' || name == "features" {
+			if len(name) == 0 || name[0] == '.' || name == "features" || !symbolSelected(name) {
 				continue
 			}
 			if !yield(name) {
@@ -319,6 +429,112 @@ func discoverSymbols() iter.Seq[string] {
 	}
 }
 
+// symbolSelected reports whether sym is SymbolOverride, when that is set,
+// and matches one of SymbolFilter's comma-separated globs; an empty filter
+// selects every symbol.
+func symbolSelected(sym string) bool {
+	if SymbolOverride != "" && sym != SymbolOverride {
+		return false
+	}
+	if SymbolFilter == "" {
+		return true
+	}
+	for _, pat := range strings.Split(SymbolFilter, ",") {
+		if ok, _ := filepath.Match(strings.TrimSpace(pat), sym); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSymbolFlags rejects a SymbolFilter with a malformed glob, which
+// would otherwise just select nothing, and a SymbolOverride that isn't a
+// symbol under BaseDir passing the filter.
+func checkSymbolFlags() error {
+	if SymbolFilter != "" {
+		for _, pat := range strings.Split(SymbolFilter, ",") {
+			if _, err := filepath.Match(strings.TrimSpace(pat), ""); err != nil {
+				return fmt.Errorf("bad -symbols pattern %q: %w", pat, err)
+			}
+		}
+	}
+	if SymbolOverride == "" {
+		return nil
+	}
+	for range discoverSymbols() {
+		return nil // symbolSelected passes SymbolOverride alone
+	}
+	if SymbolFilter != "" {
+		return fmt.Errorf("-symbol %s: no such symbol under %s matching -symbols %s", SymbolOverride, BaseDir, SymbolFilter)
+	}
+	return fmt.Errorf("-symbol %s: no such symbol under %s", SymbolOverride, BaseDir)
+}
+
+// discoverMonthDirs yields every YYYY/MM directory for a symbol, whether or
+// not it has a readable index.
+func discoverMonthDirs(sym string) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		root := filepath.Join(BaseDir, sym)
+		years, err := os.ReadDir(root)
+		if err != nil {
+			return
+		}
+		for _, y := range years {
+			if !y.IsDir() || len(y.Name()) != 4 {
+				continue
+			}
+			if _, err := strconv.Atoi(y.Name()); err != nil {
+				continue
+			}
+			months, err := os.ReadDir(filepath.Join(root, y.Name()))
+			if err != nil {
+				continue
+			}
+			for _, m := range months {
+				if !m.IsDir() || len(m.Name()) != 2 {
+					continue
+				}
+				if _, err := strconv.Atoi(m.Name()); err != nil {
+					continue
+				}
+				if !yield(filepath.Join(root, y.Name(), m.Name())) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// inSample reports whether day t belongs to the SampleFrac subset. The choice
+// hashes (SampleSeed, date) only, so a seed picks the same calendar days for
+// every symbol and every run. The seed is mixed on its own before the date
+// joins it: with a plain seed ^ date, seeds 0 and 1 would just swap
+// neighbouring days' fates.
+func (t ofiTask) inSample() bool {
+	if SampleFrac >= 1 {
+		return true
+	}
+	h := splitmix64(splitmix64(SampleSeed) ^ uint64(t.Year*10000+t.Month*100+t.Day))
+	return float64(h>>11)/(1<<53) < SampleFrac
+}
+
+// splitmix64 is the SplitMix64 step: a fast bijective 64-bit mixer.
+func splitmix64(h uint64) uint64 {
+	h += 0x9e3779b97f4a7c15
+	h = (h ^ (h >> 30)) * 0xbf58476d1ce4e5b9
+	h = (h ^ (h >> 27)) * 0x94d049bb133111eb
+	return h ^ (h >> 31)
+}
+
+// inDateRange reports whether day t lies within [DayFrom, DayTo]; a zero
+// bound is open.
+func (t ofiTask) inDateRange() bool {
+	if DayFrom != (ofiTask{}) && t.before(DayFrom) {
+		return false
+	}
+	return DayTo == (ofiTask{}) || !DayTo.before(t)
+}
+
 // discoverTasks yields all (year, month, day) tasks for a symbol.
 // Reads 26-byte index rows: Day[2] + Offset[8] + Length[8] + Checksum[8].
 func discoverTasks(sym string) iter.Seq[ofiTask] {
@@ -377,32 +593,42 @@ func discoverTasks(sym string) iter.Seq[ofiTask] {
 	}
 }
 
-// findBlobOffset scans a single index.quantdev for a given day.
-func findBlobOffset(idxPath string, day int) (uint64, uint64) {
+// indexEntry is one decoded 26-byte index.quantdev row.
+type indexEntry struct {
+	Offset   uint64
+	Length   uint64
+	Checksum uint64
+}
+
+// findBlobEntry scans a single index.quantdev for a given day and returns the
+// full row (offset, length, checksum). ok is false if the index is missing,
+// malformed, or has no row for that day.
+func findBlobEntry(idxPath string, day int) (e indexEntry, ok bool) {
 	f, err := os.Open(idxPath)
 	if err != nil {
-		return 0, 0
+		return e, false
 	}
 	defer f.Close()
 
 	var hdr [16]byte
 	if _, err := io.ReadFull(f, hdr[:]); err != nil || string(hdr[0:4]) != IdxMagic {
-		return 0, 0
+		return e, false
 	}
 	count := binary.LittleEndian.Uint64(hdr[8:16])
 
 	var row [26]byte
 	for i := uint64(0); i < count; i++ {
 		if _, err := io.ReadFull(f, row[:]); err != nil {
-			return 0, 0
+			return e, false
 		}
 		if int(binary.LittleEndian.Uint16(row[0:2])) == day {
-			offset := binary.LittleEndian.Uint64(row[2:10])
-			length := binary.LittleEndian.Uint64(row[10:18])
-			return offset, length
+			e.Offset = binary.LittleEndian.Uint64(row[2:10])
+			e.Length = binary.LittleEndian.Uint64(row[10:18])
+			e.Checksum = binary.LittleEndian.Uint64(row[18:26])
+			return e, true
 		}
 	}
-	return 0, 0
+	return e, false
 }
 
 func sprintfYear(y int) string  { return strconv.Itoa(y) }
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/binary"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// sampleDays returns which of the n days from 2020-01-01 are in the
+// SampleFrac subset under seed.
+func sampleDays(t *testing.T, seed uint64, frac float64, n int) []bool {
+	t.Helper()
+	prevSeed, prevFrac := SampleSeed, SampleFrac
+	t.Cleanup(func() { SampleSeed, SampleFrac = prevSeed, prevFrac })
+	SampleSeed, SampleFrac = seed, frac
+
+	out := make([]bool, n)
+	d := ofiTask{2020, 1, 1}
+	for i := range out {
+		out[i] = d.inSample()
+		d = d.next()
+	}
+	return out
+}
+
+func TestInSampleDeterministicFraction(t *testing.T) {
+	const n = 4000
+	a := sampleDays(t, 7, 0.3, n)
+	b := sampleDays(t, 7, 0.3, n)
+	picked := 0
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("day %d differs between identical runs", i)
+		}
+		if a[i] {
+			picked++
+		}
+	}
+	// Binomial(4000, 0.3): sd ≈ 29.
+	if got := float64(picked) / n; math.Abs(got-0.3) > 0.03 {
+		t.Fatalf("picked fraction %.3f, want ≈ 0.3", got)
+	}
+	for _, in := range sampleDays(t, 7, 1, 100) {
+		if !in {
+			t.Fatal("SampleFrac 1 dropped a day")
+		}
+	}
+}
+
+// TestInSampleNearbySeedsIndependent checks that adjacent seeds pick
+// unrelated subsets: at frac 0.5 they should agree on about half the days,
+// not on all or none of them.
+func TestInSampleNearbySeedsIndependent(t *testing.T) {
+	const n = 4000
+	for seed := uint64(0); seed < 8; seed++ {
+		a := sampleDays(t, seed, 0.5, n)
+		b := sampleDays(t, seed+1, 0.5, n)
+		agree := 0
+		for i := range a {
+			if a[i] == b[i] {
+				agree++
+			}
+		}
+		if got := float64(agree) / n; math.Abs(got-0.5) > 0.04 {
+			t.Fatalf("seeds %d and %d agree on %.3f of days, want ≈ 0.5", seed, seed+1, got)
+		}
+	}
+}
+
+// checkDecoded compares cols with the synthDay it was encoded from.
+func checkDecoded(t *testing.T, d synthDay, cols *DayColumns) {
+	t.Helper()
+	n := len(d.Times)
+	if cols.Count != n {
+		t.Fatalf("decoded %d rows, want %d", cols.Count, n)
+	}
+	if !slices.Equal(cols.Times[:n], d.Times) || !slices.Equal(cols.Prices[:n], d.Prices) ||
+		!slices.Equal(cols.Qtys[:n], d.Qtys) || !slices.Equal(cols.Sides[:n], d.Sides) {
+		t.Fatal("decoded columns differ from the encoded day")
+	}
+	for i := range n {
+		// encodeTBV1 writes first = 2i, last = 2i + i%3.
+		if want := int32(i%3 + 1); cols.Matches[i] != want {
+			t.Fatalf("row %d: %d matches, want %d", i, cols.Matches[i], want)
+		}
+	}
+}
+
+// TestInflateGNCSafeMisaligned decodes a blob starting one byte past an
+// 8-byte boundary, where the unsafe path can't be used.
+func TestInflateGNCSafeMisaligned(t *testing.T) {
+	d := randomDay(ofiTask{2024, 1, 2}, 1000, 40000, rand.New(rand.NewSource(1)))
+	blob := encodeTBV1(d)
+	// The allocator aligns a slice this size to 8 bytes, so buf[1:] isn't.
+	buf := make([]byte, len(blob)+1)
+	shifted := buf[1:]
+	copy(shifted, blob)
+
+	cols := &DayColumns{}
+	if _, err := InflateGNCSafe(shifted, cols); err != nil {
+		t.Fatal(err)
+	}
+	checkDecoded(t, d, cols)
+
+	// InflateGNC falls back to the safe decoder for it.
+	cols = &DayColumns{}
+	if _, err := InflateGNC(shifted, cols); err != nil {
+		t.Fatal(err)
+	}
+	checkDecoded(t, d, cols)
+}
+
+// TestInflateGNCSafeTruncated cuts a blob at several points and corrupts
+// its row count: each must fail with an error, not panic or decode.
+func TestInflateGNCSafeTruncated(t *testing.T) {
+	blob := encodeTBV1(randomDay(ofiTask{2024, 1, 2}, 1000, 40000, rand.New(rand.NewSource(1))))
+	for _, cut := range []int{0, 3, TBHdrSize - 1, TBHdrSize, len(blob) / 2, len(blob) - 1} {
+		cols := &DayColumns{}
+		if n, err := InflateGNCSafe(blob[:cut], cols); err == nil {
+			t.Fatalf("blob cut to %d of %d bytes decoded %d rows", cut, len(blob), n)
+		}
+	}
+
+	bad := slices.Clone(blob)
+	binary.LittleEndian.PutUint64(bad[8:], 1<<40)
+	if _, err := InflateGNCSafe(bad, &DayColumns{}); err == nil {
+		t.Fatal("corrupt row count decoded")
+	}
+}
+
+// TestSymbolFlags checks that -symbol narrows discovery to one symbol and
+// is validated against BaseDir and -symbols.
+func TestSymbolFlags(t *testing.T) {
+	root := t.TempDir()
+	withBaseDir(t, root)
+	for _, sym := range []string{"BTCUSDT", "ETHUSDT"} {
+		if err := os.Mkdir(filepath.Join(root, sym), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func(sym, filter string) { SymbolOverride, SymbolFilter = sym, filter }(SymbolOverride, SymbolFilter)
+
+	for _, tc := range []struct {
+		sym, filter string
+		want        []string // nil: rejected
+	}{
+		{"", "", []string{"BTCUSDT", "ETHUSDT"}},
+		{"ETHUSDT", "", []string{"ETHUSDT"}},
+		{"ETHUSDT", "ETH*,SOL*", []string{"ETHUSDT"}},
+		{"ETHUSDT", "BTC*", nil},
+		{"SOLUSDT", "", nil},
+	} {
+		SymbolOverride, SymbolFilter = tc.sym, tc.filter
+		err := checkSymbolFlags()
+		if tc.want == nil {
+			if err == nil {
+				t.Errorf("-symbol %q -symbols %q accepted", tc.sym, tc.filter)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("-symbol %q -symbols %q: %v", tc.sym, tc.filter, err)
+		}
+		if got := sortedSymbols(); !slices.Equal(got, tc.want) {
+			t.Errorf("-symbol %q -symbols %q: symbols %v, want %v", tc.sym, tc.filter, got, tc.want)
+		}
+		if tc.sym != "" && Symbol() != tc.sym {
+			t.Errorf("Symbol() = %s, want %s", Symbol(), tc.sym)
+		}
+	}
+}
+
+// TestVerifyBlobChecksums flips one byte of a stored blob: with
+// VerifyBlobChecksums the day is rejected, without it it still loads.
+func TestVerifyBlobChecksums(t *testing.T) {
+	root := t.TempDir()
+	task := ofiTask{2024, 1, 2}
+	writeSynthMonth(t, root, "BTCUSDT", 2024, 1, map[int]synthDay{
+		2: randomDay(task, 500, 40000, rand.New(rand.NewSource(1))),
+	})
+	defer func(prev bool) { VerifyBlobChecksums = prev }(VerifyBlobChecksums)
+
+	var buf []byte
+	VerifyBlobChecksums = true
+	if !LoadGNCFile(root, "BTCUSDT", task, &buf) {
+		t.Fatal("intact blob rejected")
+	}
+
+	// A byte inside the price column: the blob still decodes, so only the
+	// checksum can tell.
+	path := filepath.Join(root, "BTCUSDT", "2024", "01", "data.quantdev")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[TBHdrSize+500*8+3] ^= 0x10
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if LoadGNCFile(root, "BTCUSDT", task, &buf) {
+		t.Error("corrupted blob loaded with VerifyBlobChecksums")
+	}
+	VerifyBlobChecksums = false
+	if !LoadGNCFile(root, "BTCUSDT", task, &buf) {
+		t.Fatal("corrupted blob rejected without VerifyBlobChecksums")
+	}
+	var cols DayColumns
+	if _, err := InflateGNC(buf, &cols); err != nil {
+		t.Fatalf("corrupted blob doesn't decode: %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Job is one entry of a jobs file: a single-symbol OOS report over a date
+// range, e.g.
+//
+//	{"symbol": "BTCUSDT", "from": "2024-01-01", "to": "2024-03-31",
+//	 "horizons": ["5m", "15m"], "output": "btc_q1.txt"}
+//
+// From and To are inclusive UTC days; either may be empty for an open end.
+// Horizons are Go durations and default to HorizonLabels. Output defaults to
+// Job_<SYMBOL>_<from>_<to>.txt.
+//
+// A .csv jobs file has the header symbol,from,to,horizons,output with the
+// horizons separated by ';' in their cell.
+type Job struct {
+	Symbol   string   `json:"symbol"`
+	From     string   `json:"from,omitempty"`
+	To       string   `json:"to,omitempty"`
+	Horizons []string `json:"horizons,omitempty"`
+	Output   string   `json:"output,omitempty"`
+
+	from, to ofiTask
+	delays   []int64
+}
+
+// LoadJobs parses and validates a jobs file (JSON array, or CSV by
+// extension). Every job is checked up front, so a typo in the last entry
+// fails before the first one runs.
+func LoadJobs(path string) ([]Job, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var jobs []Job
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		jobs, err = parseJobsCSV(string(raw))
+	} else {
+		err = json.Unmarshal(raw, &jobs)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(jobs) == 0 {
+		return nil, fmt.Errorf("%s: no jobs", path)
+	}
+
+	known := make(map[string]bool)
+	for _, sym := range sortedSymbols() {
+		known[sym] = true
+	}
+	outputs := make(map[string]int)
+	for i := range jobs {
+		if err := jobs[i].validate(known); err != nil {
+			return nil, fmt.Errorf("job %d: %w", i+1, err)
+		}
+		if prev, dup := outputs[jobs[i].Output]; dup {
+			return nil, fmt.Errorf("job %d: output %s already written by job %d", i+1, jobs[i].Output, prev)
+		}
+		outputs[jobs[i].Output] = i + 1
+	}
+	return jobs, nil
+}
+
+func parseJobsCSV(text string) ([]Job, error) {
+	rows, err := csv.NewReader(strings.NewReader(text)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	want := []string{"symbol", "from", "to", "horizons", "output"}
+	if len(rows) == 0 || strings.Join(rows[0], ",") != strings.Join(want, ",") {
+		return nil, fmt.Errorf("CSV header must be %s", strings.Join(want, ","))
+	}
+	var jobs []Job
+	for _, r := range rows[1:] {
+		j := Job{Symbol: r[0], From: r[1], To: r[2], Output: r[4]}
+		for _, h := range strings.Split(r[3], ";") {
+			if h = strings.TrimSpace(h); h != "" {
+				j.Horizons = append(j.Horizons, h)
+			}
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, nil
+}
+
+// validate checks j against the symbols under BaseDir and fills in its
+// parsed days, horizon delays and default output.
+func (j *Job) validate(known map[string]bool) error {
+	if !known[j.Symbol] {
+		return fmt.Errorf("unknown symbol %q", j.Symbol)
+	}
+	var err error
+	if j.from, err = parseDay(j.From); err != nil {
+		return err
+	}
+	if j.to, err = parseDay(j.To); err != nil {
+		return err
+	}
+	if j.From != "" && j.To != "" && j.to.before(j.from) {
+		return fmt.Errorf("to %s is before from %s", j.To, j.From)
+	}
+
+	if len(j.Horizons) == 0 {
+		j.Horizons = HorizonLabels
+	}
+	j.delays = make([]int64, len(j.Horizons))
+	for i, h := range j.Horizons {
+		d, err := time.ParseDuration(h)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("bad horizon %q", h)
+		}
+		j.delays[i] = d.Milliseconds()
+	}
+
+	if j.Output == "" {
+		j.Output = fmt.Sprintf("Job_%s_%s_%s.txt", j.Symbol, orOpen(j.From), orOpen(j.To))
+	}
+	return nil
+}
+
+// parseDay parses YYYY-MM-DD; "" is the zero (open) day.
+func parseDay(s string) (ofiTask, error) {
+	if s == "" {
+		return ofiTask{}, nil
+	}
+	d, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return ofiTask{}, fmt.Errorf("bad date %q (want YYYY-MM-DD)", s)
+	}
+	return ofiTask{Year: d.Year(), Month: int(d.Month()), Day: d.Day()}, nil
+}
+
+func orOpen(day string) string {
+	if day == "" {
+		return "open"
+	}
+	return day
+}
+
+// RunJobs runs each job as its own RunTestForSymbol report, with DayFrom,
+// DayTo and the horizons set for the job and restored afterwards. Under
+// -format json each job writes its own JSON next to its output.
+func RunJobs(jobs []Job) {
+	startAll := time.Now()
+	labels, delays := HorizonLabels, HorizonDelays
+	from, to := DayFrom, DayTo
+	defer func() {
+		HorizonLabels, HorizonDelays = labels, delays
+		DayFrom, DayTo = from, to
+	}()
+
+	fmt.Printf(">>> JOBS: %d <<<\n", len(jobs))
+	for i, j := range jobs {
+		fmt.Printf("=== Job %d/%d: %s %s..%s horizons %v -> %s ===\n",
+			i+1, len(jobs), j.Symbol, orOpen(j.From), orOpen(j.To), j.Horizons, j.Output)
+		HorizonLabels, HorizonDelays = j.Horizons, j.delays
+		DayFrom, DayTo = j.from, j.to
+
+		var js *JSONReport
+		if ReportFormat == "json" {
+			js = newJSONReport()
+		}
+		RunTestForSymbol(j.Symbol, GetContinuousModels, j.Output, nil, js)
+		writeJSONReport(js, strings.TrimSuffix(j.Output, filepath.Ext(j.Output))+".json")
+	}
+	fmt.Printf("Jobs completed in %s\n", time.Since(startAll))
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestRunJobsPerJobSettings runs a two-job file and checks that each report
+// covers its own symbol, date range and horizons, and that the globals the
+// jobs override are restored afterwards.
+func TestRunJobsPerJobSettings(t *testing.T) {
+	root := t.TempDir()
+	withBaseDir(t, root)
+	rng := rand.New(rand.NewSource(1))
+	for sym, p0 := range map[string]float64{"BTCUSDT": 40000, "ETHUSDT": 2500} {
+		days := make(map[int]synthDay)
+		for d := 1; d <= 5; d++ {
+			days[d] = randomDay(ofiTask{2024, 1, d}, 4000, p0, rng)
+		}
+		writeSynthMonth(t, root, sym, 2024, 1, days)
+	}
+
+	defer func(prev string) { ReportFormat = prev }(ReportFormat)
+	ReportFormat = "json"
+	labels, delays := []string{"15m"}, []int64{900_000}
+	defer func(l []string, d []int64) { HorizonLabels, HorizonDelays = l, d }(HorizonLabels, HorizonDelays)
+	HorizonLabels, HorizonDelays = labels, delays
+	defer func(f, to ofiTask) { DayFrom, DayTo = f, to }(DayFrom, DayTo)
+	DayFrom, DayTo = ofiTask{}, ofiTask{}
+
+	out := t.TempDir()
+	jobsFile := filepath.Join(out, "jobs.json")
+	raw := `[
+	 {"symbol": "BTCUSDT", "from": "2024-01-02", "to": "2024-01-03", "horizons": ["1m", "5m"], "output": "` + filepath.ToSlash(filepath.Join(out, "btc.txt")) + `"},
+	 {"symbol": "ETHUSDT", "from": "2024-01-03", "horizons": ["2m"], "output": "` + filepath.ToSlash(filepath.Join(out, "eth.txt")) + `"}
+	]`
+	if err := os.WriteFile(jobsFile, []byte(raw), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	jobs, err := LoadJobs(jobsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	RunJobs(jobs)
+
+	if &HorizonLabels[0] != &labels[0] || &HorizonDelays[0] != &delays[0] {
+		t.Errorf("horizons not restored: %v %v", HorizonLabels, HorizonDelays)
+	}
+	if DayFrom != (ofiTask{}) || DayTo != (ofiTask{}) {
+		t.Errorf("day range not restored: %v..%v", DayFrom, DayTo)
+	}
+
+	for _, tc := range []struct {
+		json     string
+		sym      string
+		from, to ofiTask
+		horizons []string
+	}{
+		{"btc.json", "BTCUSDT", ofiTask{2024, 1, 2}, ofiTask{2024, 1, 3}, []string{"1m", "5m"}},
+		{"eth.json", "ETHUSDT", ofiTask{2024, 1, 3}, ofiTask{}, []string{"2m"}},
+	} {
+		data, err := os.ReadFile(filepath.Join(out, tc.json))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got JSONReport
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Symbols) != 1 || got.Symbols[tc.sym] == nil {
+			t.Fatalf("%s: symbols %v, want only %s", tc.json, keys(got.Symbols), tc.sym)
+		}
+
+		// The same report run directly over the job's range and horizons
+		// must see the same samples; the full range must see more.
+		want := runCounts(t, tc.sym, tc.from, tc.to, tc.horizons)
+		all := runCounts(t, tc.sym, ofiTask{}, ofiTask{}, tc.horizons)
+		for model, cells := range got.Symbols[tc.sym] {
+			if hs := keys(cells); !slices.Equal(hs, sortedCopy(tc.horizons)) {
+				t.Fatalf("%s %s: horizons %v, want %v", tc.json, model, hs, tc.horizons)
+			}
+			for h, c := range cells {
+				n := c.IS.N + c.OOS.TestCount
+				if n != want[model+"/"+h] {
+					t.Errorf("%s %s %s: %d samples, want %d", tc.json, model, h, n, want[model+"/"+h])
+				}
+				if n >= all[model+"/"+h] {
+					t.Errorf("%s %s %s: %d samples, not fewer than the full range's %d", tc.json, model, h, n, all[model+"/"+h])
+				}
+			}
+		}
+	}
+}
+
+// runCounts runs sym's report over from..to at the given horizons and
+// returns each model/horizon cell's train plus test sample count.
+func runCounts(t *testing.T, sym string, from, to ofiTask, horizons []string) map[string]int {
+	t.Helper()
+	defer func(l []string, d []int64) { HorizonLabels, HorizonDelays = l, d }(HorizonLabels, HorizonDelays)
+	defer func(f, to ofiTask) { DayFrom, DayTo = f, to }(DayFrom, DayTo)
+	j := Job{Symbol: sym, Horizons: horizons}
+	if err := j.validate(map[string]bool{sym: true}); err != nil {
+		t.Fatal(err)
+	}
+	HorizonLabels, HorizonDelays = j.Horizons, j.delays
+	DayFrom, DayTo = from, to
+
+	js := newJSONReport()
+	RunTestForSymbol(sym, GetContinuousModels, filepath.Join(t.TempDir(), "ref.txt"), nil, js)
+	counts := make(map[string]int)
+	for model, cells := range js.Symbols[sym] {
+		for h, c := range cells {
+			counts[model+"/"+h] = c.IS.N + c.OOS.TestCount
+		}
+	}
+	return counts
+}
+
+func keys[V any](m map[string]V) []string {
+	ks := make([]string, 0, len(m))
+	for k := range m {
+		ks = append(ks, k)
+	}
+	slices.Sort(ks)
+	return ks
+}
+
+func sortedCopy(s []string) []string {
+	s = slices.Clone(s)
+	slices.Sort(s)
+	return s
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"reflect"
+	"time"
+)
+
+// JSONReport is the -format=json output of one run: every core-table cell
+// keyed by symbol, then model, then horizon label. Keys are maps, which
+// encoding/json writes sorted, so runs diff cleanly.
+type JSONReport struct {
+	RunTS   string                                        `json:"run_ts"`
+	Symbols map[string]map[string]map[string]*HorizonJSON `json:"symbols"`
+}
+
+// HorizonJSON is one (model, horizon) cell split into its train (IS) and
+// test (OOS) segments, with the rolling-window and regime breakdowns of the
+// test segment nested under it.
+type HorizonJSON struct {
+	IS         ISStatsJSON     `json:"is"`
+	OOS        ReportStats     `json:"oos"`
+	Rolling    []WindowMetrics `json:"rolling,omitempty"`
+	VolRegimes []RegimeMetrics `json:"vol_regimes,omitempty"`
+	TODRegimes []RegimeMetrics `json:"tod_regimes,omitempty"`
+}
+
+// ISStatsJSON holds the train-segment figures. Only the Sharpe is computed
+// in-sample; it is what the best-horizon section selects on.
+type ISStatsJSON struct {
+	N      int     `json:"n"`
+	Sharpe float64 `json:"sharpe"`
+}
+
+func newJSONReport() *JSONReport {
+	return &JSONReport{
+		RunTS:   time.Now().UTC().Format(time.RFC3339),
+		Symbols: make(map[string]map[string]map[string]*HorizonJSON),
+	}
+}
+
+// cell returns the (sym, model, horizon) entry, creating it if needed.
+func (r *JSONReport) cell(sym, model, horizon string) *HorizonJSON {
+	models := r.Symbols[sym]
+	if models == nil {
+		models = make(map[string]map[string]*HorizonJSON)
+		r.Symbols[sym] = models
+	}
+	if models[model] == nil {
+		models[model] = make(map[string]*HorizonJSON)
+	}
+	c := models[model][horizon]
+	if c == nil {
+		c = &HorizonJSON{}
+		models[model][horizon] = c
+	}
+	return c
+}
+
+// Stats records one core-table cell. A nil *JSONReport discards it, as it
+// does for Window and Regime.
+func (r *JSONReport) Stats(sym, model, horizon string, s ReportStats) {
+	if r == nil {
+		return
+	}
+	finiteFloats(reflect.ValueOf(&s).Elem())
+	c := r.cell(sym, model, horizon)
+	c.IS = ISStatsJSON{N: s.TrainCount, Sharpe: s.ISSharpe}
+	c.OOS = s
+}
+
+// Window appends a rolling-window row to the cell.
+func (r *JSONReport) Window(sym, model, horizon string, wm WindowMetrics) {
+	if r == nil {
+		return
+	}
+	finiteFloats(reflect.ValueOf(&wm).Elem())
+	c := r.cell(sym, model, horizon)
+	c.Rolling = append(c.Rolling, wm)
+}
+
+// Regime appends a regime row to the cell; kind is "vol" or "tod".
+func (r *JSONReport) Regime(sym, model, horizon, kind string, rm RegimeMetrics) {
+	if r == nil {
+		return
+	}
+	finiteFloats(reflect.ValueOf(&rm).Elem())
+	c := r.cell(sym, model, horizon)
+	if kind == "vol" {
+		c.VolRegimes = append(c.VolRegimes, rm)
+	} else {
+		c.TODRegimes = append(c.TODRegimes, rm)
+	}
+}
+
+// finiteFloats zeroes NaN/Inf float fields (and slice elements) of struct v,
+// which encoding/json refuses to encode. The slice is copied first so the
+// caller's stats are left alone.
+func finiteFloats(v reflect.Value) {
+	clean := func(f reflect.Value) {
+		if x := f.Float(); math.IsNaN(x) || math.IsInf(x, 0) {
+			f.SetFloat(0)
+		}
+	}
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		switch {
+		case f.Kind() == reflect.Float64:
+			clean(f)
+		case f.Kind() == reflect.Slice && f.Type().Elem().Kind() == reflect.Float64:
+			c := reflect.MakeSlice(f.Type(), f.Len(), f.Len())
+			reflect.Copy(c, f)
+			for j := 0; j < c.Len(); j++ {
+				clean(c.Index(j))
+			}
+			f.Set(c)
+		}
+	}
+}
+
+// WriteFile writes the report as indented JSON. It goes to path+".tmp"
+// first and is renamed into place, so a run interrupted mid-write leaves
+// the previous report intact rather than a truncated one.
+func (r *JSONReport) WriteFile(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
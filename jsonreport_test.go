@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestJSONReportWriteFileReplaces checks that a rewrite replaces the report
+// whole and leaves no temp file behind.
+func TestJSONReportWriteFileReplaces(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	r := newJSONReport()
+	r.Stats("BTCUSDT", "Hawkes_OFI", "60s", ReportStats{TestCount: 10})
+	if err := r.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	r.Stats("ETHUSDT", "Hawkes_OFI", "60s", ReportStats{TestCount: 20})
+	if err := r.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got JSONReport
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Symbols) != 2 || got.Symbols["ETHUSDT"]["Hawkes_OFI"]["60s"].OOS.TestCount != 20 {
+		t.Fatalf("report = %+v", got.Symbols)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Fatalf("temp file left behind: %v", err)
+	}
+}
+
+// TestJSONReportWriteFileKeepsOld checks that a failed write leaves the
+// previous report untouched.
+func TestJSONReportWriteFileKeepsOld(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := newJSONReport().WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	old, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A directory in the temp file's place makes the write fail.
+	if err := os.Mkdir(path+".tmp", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	r := newJSONReport()
+	r.Stats("BTCUSDT", "Hawkes_OFI", "60s", ReportStats{TestCount: 10})
+	if err := r.WriteFile(path); err == nil {
+		t.Fatal("WriteFile succeeded over a directory")
+	}
+	now, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(now) != string(old) {
+		t.Fatal("failed write changed the report")
+	}
+}
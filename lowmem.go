@@ -0,0 +1,214 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+// Moments accumulates the online statistics of one (feature, return) stream
+// that the moment-based report metrics need: co-moments for Pearson (with
+// Welford updates, so large feature offsets cost no precision), sign
+// agreement for the hit rate, and the mean and variance of sign(signal) *
+// return for the Sharpe. Accumulators built from disjoint samples combine
+// with Merge, in any order.
+type Moments struct {
+	N              int
+	meanX, meanY   float64
+	m2X, m2Y, coXY float64
+	Hits, Trials   int // sign(signal) == sign(return) among non-zero pairs
+	pnlMean, pnlM2 float64
+	pnlN           int
+}
+
+// Add folds in one sample.
+func (m *Moments) Add(x, y float64) {
+	m.N++
+	n := float64(m.N)
+	dx := x - m.meanX
+	m.meanX += dx / n
+	dy := y - m.meanY
+	m.meanY += dy / n
+	m.m2X += dx * (x - m.meanX)
+	m.m2Y += dy * (y - m.meanY)
+	m.coXY += dx * (y - m.meanY)
+
+	if x == 0 || y == 0 {
+		return
+	}
+	m.Trials++
+	pnl := y
+	if x < 0 {
+		pnl = -y
+	}
+	if pnl > 0 {
+		m.Hits++
+	}
+	m.pnlN++
+	d := pnl - m.pnlMean
+	m.pnlMean += d / float64(m.pnlN)
+	m.pnlM2 += d * (pnl - m.pnlMean)
+}
+
+// Merge folds o into m (Chan et al.'s pairwise update).
+func (m *Moments) Merge(o Moments) {
+	if o.N == 0 {
+		return
+	}
+	if m.N == 0 {
+		*m = o
+		return
+	}
+	na, nb := float64(m.N), float64(o.N)
+	n := na + nb
+	dx, dy := o.meanX-m.meanX, o.meanY-m.meanY
+	m.m2X += o.m2X + dx*dx*na*nb/n
+	m.m2Y += o.m2Y + dy*dy*na*nb/n
+	m.coXY += o.coXY + dx*dy*na*nb/n
+	m.meanX += dx * nb / n
+	m.meanY += dy * nb / n
+	m.N += o.N
+
+	m.Hits += o.Hits
+	m.Trials += o.Trials
+	if o.pnlN > 0 {
+		pa, pb := float64(m.pnlN), float64(o.pnlN)
+		d := o.pnlMean - m.pnlMean
+		m.pnlM2 += o.pnlM2 + d*d*pa*pb/(pa+pb)
+		m.pnlMean += d * pb / (pa + pb)
+		m.pnlN += o.pnlN
+	}
+}
+
+// Pearson is the correlation of the accumulated pairs.
+func (m Moments) Pearson() float64 {
+	if m.m2X <= 0 || m.m2Y <= 0 {
+		return 0
+	}
+	return m.coXY / math.Sqrt(m.m2X*m.m2Y)
+}
+
+// Sharpe is the per-trade Sharpe of the sign(signal) strategy, as in
+// StrategyRiskStats (vol targeting rescales trades, not their Sharpe).
+func (m Moments) Sharpe() float64 {
+	if m.pnlN == 0 || m.pnlM2 <= 0 {
+		return 0
+	}
+	return m.pnlMean / math.Sqrt(m.pnlM2/float64(m.pnlN))
+}
+
+// CellMoments is one (model, horizon) cell of a -low-mem run, split at a
+// day boundary instead of at a sample count (see lowMemSplit). It also sums
+// the gaps between consecutive samples of a day, for the realized spacing.
+type CellMoments struct {
+	Train, Test Moments
+
+	lastT  float64
+	gapSum float64
+	gapN   int
+}
+
+// Merge folds o into c.
+func (c *CellMoments) Merge(o CellMoments) {
+	c.Train.Merge(o.Train)
+	c.Test.Merge(o.Test)
+	c.gapSum += o.gapSum
+	c.gapN += o.gapN
+}
+
+// spacingMs is the mean within-day gap between samples; the median
+// sampleSpacingMs takes would need the samples. 0 when unknown.
+func (c CellMoments) spacingMs() float64 {
+	if c.gapN == 0 {
+		return 0
+	}
+	return c.gapSum / float64(c.gapN)
+}
+
+// add files a sample under train or test. Train samples whose label reaches
+// splitMs are purged (PurgeSplit), and test samples within the embargo of
+// splitMs are skipped, mirroring sortedTrainTestSplit in time rather than
+// samples.
+func (c *CellMoments) add(t, x, y float64, splitMs, horizonMs int64) {
+	// A worker streams each day's samples in order; a gap across days (or
+	// back to an earlier one) isn't a sampling step.
+	if c.lastT > 0 && t > c.lastT && int64(t)/dayMillis == int64(c.lastT)/dayMillis {
+		c.gapSum += t - c.lastT
+		c.gapN++
+	}
+	c.lastT = t
+
+	switch {
+	case t < float64(splitMs):
+		if PurgeSplit && t+float64(horizonMs) >= float64(splitMs) {
+			return
+		}
+		c.Train.Add(x, y)
+	case t >= float64(splitMs+lowMemEmbargoMs(horizonMs)):
+		c.Test.Add(x, y)
+	}
+}
+
+// lowMemEmbargoMs converts EmbargoSamples to time. A negative embargo, one
+// label overlap, is a horizon whatever the spacing; an explicit sample
+// count is taken at the time clock's nominal step, since the embargo has to
+// apply while samples stream in, before their spacing is known.
+func lowMemEmbargoMs(horizonMs int64) int64 {
+	if EmbargoSamples < 0 {
+		return horizonMs
+	}
+	return int64(EmbargoSamples) * SamplingRateSec * 1000
+}
+
+// lowMemSplit is the train/test cut of a -low-mem run: the UTC start of
+// the first test day, with the earliest trainFrac of tasks (chronological)
+// as train. Samples can't be counted before they are streamed, so the cut
+// falls on a day boundary.
+func lowMemSplit(tasks []ofiTask, trainFrac float64) int64 {
+	k := min(max(int(trainFrac*float64(len(tasks))), 1), len(tasks)-1)
+	t := tasks[k]
+	return time.Date(t.Year, time.Month(t.Month), t.Day, 0, 0, 0, 0, time.UTC).UnixMilli()
+}
+
+// FinalizeMoments turns a cell's moments into the report's moment-based
+// columns: counts, PearsonIC and its t-stats, hit rate, Sharpe (IS and
+// OOS) and SharpeAnn. Without per-sample data there is no HAC variance, so
+// ICTStatHAC is the classical t-stat on EffectiveN instead of TestCount;
+// the trade skew and kurtosis assume normality (0 and 3) for the DSR. All
+// quantile, MI, log-loss, drawdown, cost and bootstrap columns stay 0.
+func FinalizeMoments(c CellMoments, horizonMs int64) ReportStats {
+	stats := ReportStats{
+		TrainCount:    c.Train.N,
+		TestCount:     c.Test.N,
+		EffectiveN:    float64(c.Test.N) / labelOverlap(horizonMs, c.spacingMs()),
+		TradeKurtosis: 3,
+	}
+	if stats.EffectiveN < MinEffectiveSamples {
+		stats.Suppressed = true
+		return stats
+	}
+
+	ic := c.Test.Pearson()
+	stats.PearsonIC = ic
+	tStat := func(n float64) float64 {
+		if n <= 2 || math.Abs(ic) >= 1 {
+			return 0
+		}
+		return ic * math.Sqrt((n-2)/(1-ic*ic))
+	}
+	stats.ICTStat = tStat(float64(c.Test.N))
+	stats.ICTStatHAC = tStat(stats.EffectiveN)
+	stats.ICPValue = twoSidedP(stats.ICTStatHAC)
+
+	if c.Test.Trials > 0 {
+		stats.HitRate = float64(c.Test.Hits) / float64(c.Test.Trials)
+		stats.HitRateZ = (stats.HitRate - 0.5) / math.Sqrt(0.25/float64(c.Test.Trials))
+	}
+	stats.Sharpe = c.Test.Sharpe()
+	spacing := c.spacingMs()
+	if spacing <= 0 {
+		spacing = SamplingRateSec * 1000
+	}
+	stats.SharpeAnn = stats.Sharpe * math.Sqrt(annualFactor(spacing/1000))
+	stats.ISSharpe = c.Train.Sharpe()
+	return stats
+}
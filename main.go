@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"runtime/debug"
@@ -10,19 +11,183 @@ func main() {
 	// Slightly laxer GC; this is CPU-heavy research code.
 	debug.SetGCPercent(200)
 
-	if len(os.Args) < 2 {
-		fmt.Println("Usage: go run . [test|probe]")
+	// Global flags go before the subcommand; defaults are the config.go
+	// values, so a bare subcommand behaves as it always has.
+	flag.StringVar(&BaseDir, "base", BaseDir, "data root containing one directory per symbol")
+	flag.StringVar(&SymbolOverride, "symbol", SymbolOverride, "only use this symbol, for every command (default: all; smoke takes the first preferred one)")
+	flag.StringVar(&SymbolFilter, "symbols", SymbolFilter, "only use symbols matching these comma-separated globs, e.g. BTCUSDT,ETH*")
+	flag.IntVar(&CPUThreads, "threads", CPUThreads, "worker goroutines")
+	flag.BoolVar(&VerifyBlobChecksums, "verify-checksums", VerifyBlobChecksums, "re-hash every blob on read and skip days whose checksum doesn't match")
+	flag.BoolVar(&SafeDecode, "safe-decode", SafeDecode, "decode every blob with bounds-checked reads instead of unsafe column views")
+	flag.BoolVar(&UseMmap, "mmap", UseMmap, "serve day blobs from memory-mapped data files (falls back to reads)")
+	flag.Parse()
+	if CPUThreads < 1 {
+		fmt.Printf("-threads must be at least 1, got %d\n", CPUThreads)
+		os.Exit(1)
+	}
+	if err := checkSymbolFlags(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	args := flag.Args()
+	if len(args) < 1 {
+		fmt.Println("Usage: go run . [-base DIR] [-symbols GLOBS] [-symbol SYM] [-threads N] [-verify-checksums] [-safe-decode] [-mmap] [test [-db results.db]|sweep -model TYPE [-taus 1,2,5]|jobs FILE|probe [-deep]|reindex [-dry-run]|compact [-dry-run]|smoke]")
 		return
 	}
 
-	switch os.Args[1] {
+	if err := checkModelsConfig(); err != nil {
+		fmt.Printf("Invalid models config: %v\n", err)
+		os.Exit(1)
+	}
+	switch args[0] {
 	case "test":
 		// Full OOS research run (writes Continuous_Algo_Report_OOS.txt).
+		fs := flag.NewFlagSet("test", flag.ExitOnError)
+		fs.StringVar(&ResultsDBPath, "db", ResultsDBPath, "also write report rows to this SQLite database")
+		fs.BoolVar(&LowMemory, "low-mem", LowMemory, "keep streaming moments instead of samples (core IC/hit rate/Sharpe only)")
+		addSampleFlags(fs)
+		fs.Parse(args[1:])
+		checkSampleFlags(fs)
 		RunTest()
+	case "sweep":
+		// One pass over all symbols with one model type at several taus.
+		fs := flag.NewFlagSet("sweep", flag.ExitOnError)
+		typ := fs.String("model", "", "model type to sweep (see ModelSpec)")
+		list := fs.String("taus", "", "comma-separated taus in seconds (default 1,2,5,15,30,60,300)")
+		fs.Float64Var(&SweepMinIC, "min-ic", SweepMinIC, "prune candidates whose early IS |IC| is below this (0 = no screening)")
+		addSampleFlags(fs)
+		fs.Parse(args[1:])
+		checkSampleFlags(fs)
+		taus := DefaultSweepTaus
+		if *list != "" {
+			var err error
+			if taus, err = parseTaus(*list); err != nil {
+				fmt.Printf("[sweep] %v\n", err)
+				os.Exit(1)
+			}
+		}
+		if err := RunTauSweep(*typ, taus); err != nil {
+			fmt.Printf("[sweep] %v\n", err)
+			os.Exit(1)
+		}
+	case "jobs":
+		// One report per entry of a JSON/CSV jobs file (see Job).
+		fs := flag.NewFlagSet("jobs", flag.ExitOnError)
+		fs.BoolVar(&LowMemory, "low-mem", LowMemory, "keep streaming moments instead of samples (core IC/hit rate/Sharpe only)")
+		addSampleFlags(fs)
+		fs.Parse(args[1:])
+		checkSampleFlags(fs)
+		if fs.NArg() != 1 {
+			fmt.Println("Usage: go run . jobs [flags] FILE")
+			os.Exit(1)
+		}
+		jobs, err := LoadJobs(fs.Arg(0))
+		if err != nil {
+			fmt.Printf("[jobs] %v\n", err)
+			os.Exit(1)
+		}
+		RunJobs(jobs)
 	case "probe":
 		// Structural sanity check of data under BaseDir.
-		RunProbe()
+		fs := flag.NewFlagSet("probe", flag.ExitOnError)
+		deep := fs.Bool("deep", false, "decode every day and check timestamp order and prices (slow)")
+		fs.Parse(args[1:])
+		RunProbe(*deep)
+	case "reindex":
+		// Rebuild index.quantdev files from data.quantdev.
+		fs := flag.NewFlagSet("reindex", flag.ExitOnError)
+		dryRun := fs.Bool("dry-run", false, "print the index changes without writing them")
+		fs.Parse(args[1:])
+		RunReindex(*dryRun)
+	case "compact":
+		// Drop duplicate/stale index rows and orphaned blobs.
+		fs := flag.NewFlagSet("compact", flag.ExitOnError)
+		dryRun := fs.Bool("dry-run", false, "report what would be compacted without writing")
+		fs.Parse(args[1:])
+		RunCompact(*dryRun)
+	case "smoke":
+		// One-day end-to-end pipeline check; non-zero exit on failure.
+		if _, err := RunSmoke(); err != nil {
+			fmt.Printf("[smoke] FAIL: %v\n", err)
+			os.Exit(1)
+		}
 	default:
-		fmt.Println("Unknown command. Use 'test' or 'probe'")
+		fmt.Println("Unknown command. Use 'test', 'sweep', 'jobs', 'probe', 'reindex', 'compact' or 'smoke'")
+	}
+}
+
+// addSampleFlags registers the day-subsetting and output flags shared by
+// test, sweep and jobs.
+func addSampleFlags(fs *flag.FlagSet) {
+	fs.BoolFunc("csv", "same as -format csv", func(string) error {
+		ReportFormat = "csv"
+		return nil
+	})
+	fs.StringVar(&SamplingMode, "sampling", SamplingMode, "sampling clock: time, trades or volume")
+	fs.IntVar(&SampleTrades, "sample-trades", SampleTrades, "trades per sample on the trades clock (0 = size from the previous day)")
+	fs.Float64Var(&SampleVolume, "sample-volume", SampleVolume, "traded quantity per sample on the volume clock (0 = size from the previous day)")
+	fs.BoolVar(&AlignSamplingGrid, "align-grid", AlignSamplingGrid, "snap the time clock's sampling grid to UTC multiples of the sampling rate")
+	fs.BoolVar(&DumpParquet, "dump-parquet", DumpParquet, "also write sampled features and labels as Parquet")
+	fs.StringVar(&ReturnMode, "returns", ReturnMode, "label prices: last or micromid")
+	fs.StringVar(&ReportFormat, "format", ReportFormat, "report output: text, csv or json")
+	fs.BoolVar(&CrossDayLabels, "cross-day", CrossDayLabels, "label samples near the day's close from the next day's trades")
+	fs.StringVar(&PriceBreakMode, "price-breaks", PriceBreakMode, "with -cross-day, labels spanning a price break: split (drop them) or adjust")
+	fs.BoolVar(&PurgeSplit, "purge", PurgeSplit, "drop train samples whose label reaches the test period of each OOS cut")
+	fs.IntVar(&EmbargoSamples, "embargo", EmbargoSamples, "test samples skipped after each OOS cut (-1 = one horizon's worth)")
+	fs.BoolVar(&MIBiasCorrection, "mi-bias-correction", MIBiasCorrection, "apply the Miller-Madow correction to mutual information")
+	fs.BoolVar(&RankNormMetrics, "rank-norm", RankNormMetrics, "also report MI and delta log-loss on the rank-normalized signal")
+	fs.BoolVar(&BootstrapIntervals, "bootstrap", BootstrapIntervals, "add stationary-bootstrap 95% intervals on IC and Sharpe (slow)")
+	fs.IntVar(&BootstrapResamples, "bootstrap-resamples", BootstrapResamples, "resamples per -bootstrap interval")
+	fs.Float64Var(&VolTarget, "vol-target", VolTarget, "scale the sign(signal) strategy to this per-sample return vol (0 = unscaled)")
+	fs.BoolVar(&GARCHVolGain, "garch-vol-gain", GARCHVolGain, "scale Kalman_Adapt's process noise by a GARCH(1,1) variance ratio")
+	fs.BoolVar(&VolRegimeGARCH, "vol-regime-garch", VolRegimeGARCH, "assign volatility regimes by GARCH(1,1) instead of EWMA vol")
+	fs.BoolVar(&AdditiveDrawdown, "additive-drawdown", AdditiveDrawdown, "report max drawdown on summed rather than compounded strategy returns")
+	fs.BoolVar(&AdaptiveClamp, "adaptive-clamp", AdaptiveClamp, "clip each model output to its running 1st/99th percentiles")
+	fs.BoolVar(&TripleBarrier, "triple-barrier", TripleBarrier, "label with the triple barrier instead of fixed-horizon returns")
+	fs.Float64Var(&BarrierK, "barrier-k", BarrierK, "triple-barrier width in trailing sigmas")
+	fs.Float64Var(&SampleFrac, "sample-frac", SampleFrac, "process only this fraction of days (deterministic per -seed)")
+	fs.Uint64Var(&SampleSeed, "seed", SampleSeed, "seed selecting the -sample-frac day subset")
+}
+
+// checkSampleFlags validates the flags registered by addSampleFlags on fs.
+func checkSampleFlags(fs *flag.FlagSet) {
+	if ReportFormat != "text" && ReportFormat != "csv" && ReportFormat != "json" {
+		fmt.Printf("Unknown -format %q (want text, csv or json)\n", ReportFormat)
+		os.Exit(1)
+	}
+	if ReturnMode != "last" && ReturnMode != "micromid" {
+		fmt.Printf("Unknown -returns %q (want last or micromid)\n", ReturnMode)
+		os.Exit(1)
+	}
+	if SamplingMode != "time" && SamplingMode != "trades" && SamplingMode != "volume" {
+		fmt.Printf("Unknown -sampling %q (want time, trades or volume)\n", SamplingMode)
+		os.Exit(1)
+	}
+	if PriceBreakMode != "split" && PriceBreakMode != "adjust" {
+		fmt.Printf("Unknown -price-breaks %q (want split or adjust)\n", PriceBreakMode)
+		os.Exit(1)
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "price-breaks" && !CrossDayLabels {
+			fmt.Println("-price-breaks only applies to cross-day labels; add -cross-day")
+			os.Exit(1)
+		}
+	})
+	if SampleFrac <= 0 || SampleFrac > 1 {
+		fmt.Printf("-sample-frac must be in (0, 1], got %g\n", SampleFrac)
+		os.Exit(1)
+	}
+	if BootstrapIntervals && BootstrapResamples < 1 {
+		fmt.Printf("-bootstrap-resamples must be at least 1, got %d\n", BootstrapResamples)
+		os.Exit(1)
+	}
+	if VolTarget < 0 {
+		fmt.Printf("-vol-target must not be negative, got %g\n", VolTarget)
+		os.Exit(1)
+	}
+	if TripleBarrier && BarrierK <= 0 {
+		fmt.Printf("-barrier-k must be positive, got %g\n", BarrierK)
+		os.Exit(1)
 	}
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"math"
+	"sync"
 )
 
 // ContinuousModel defines a physics object that updates on dt/price/volume.
@@ -11,6 +12,33 @@ type ContinuousModel interface {
 	Update(dt float64, p, v float64) float64
 }
 
+// Tick is one trade as RunStream sees it: the Update arguments plus the
+// aggressor side (+1 buy, -1 sell, 0 unknown; see DayColumns.Sides) and the
+// number of fills aggregated into it (0 unknown).
+type Tick struct {
+	DT, P, V float64
+	Side     int8
+	Matches  int32
+}
+
+// TickModel is a ContinuousModel that wants the whole Tick. RunStream calls
+// UpdateTick instead of Update for it, so models that only need (dt, p, v)
+// keep implementing just Update. A TickModel's Update should behave like
+// UpdateTick with Side and Matches unknown.
+type TickModel interface {
+	ContinuousModel
+	UpdateTick(t Tick) float64
+}
+
+// updateTick feeds t to m through UpdateTick when m is a TickModel and
+// through Update otherwise.
+func updateTick(m ContinuousModel, t Tick) float64 {
+	if tm, ok := m.(TickModel); ok {
+		return tm.UpdateTick(t)
+	}
+	return m.Update(t.DT, t.P, t.V)
+}
+
 // ============================================================================
 // 1. Baseline Hawkes_Intensity (keep as-is; this is your proven baseline)
 // ============================================================================
@@ -48,6 +76,10 @@ type ModelHawkesOFI struct {
 	beta    float64 // decay rate
 	lastP   float64
 	init    bool
+
+	// Signed variant: classify by the aggressor side from UpdateTick,
+	// falling back to the tick rule only when the side is unknown.
+	signed bool
 }
 
 func NewHawkesOFI() *ModelHawkesOFI {
@@ -55,13 +87,30 @@ func NewHawkesOFI() *ModelHawkesOFI {
 	return &ModelHawkesOFI{beta: 0.002}
 }
 
-func (m *ModelHawkesOFI) Name() string { return "Hawkes_OFI" }
+// NewHawkesOFISigned is Hawkes_OFI fed by the buyer-maker side, so
+// equal-price trades (most trades at tick resolution) count toward the side
+// that actually initiated them instead of only decaying.
+func NewHawkesOFISigned() *ModelHawkesOFI {
+	return &ModelHawkesOFI{beta: 0.002, signed: true}
+}
+
+func (m *ModelHawkesOFI) Name() string {
+	if m.signed {
+		return "Hawkes_OFI_Signed"
+	}
+	return "Hawkes_OFI"
+}
 
 func (m *ModelHawkesOFI) Reset() {
 	m.buyInt, m.sellInt, m.lastP, m.init = 0, 0, 0, false
 }
 
 func (m *ModelHawkesOFI) Update(dt float64, p, v float64) float64 {
+	return m.UpdateTick(Tick{DT: dt, P: p, V: v})
+}
+
+func (m *ModelHawkesOFI) UpdateTick(t Tick) float64 {
+	dt, p, v := t.DT, t.P, t.V
 	if !m.init {
 		m.lastP = p
 		m.init = true
@@ -76,10 +125,15 @@ func (m *ModelHawkesOFI) Update(dt float64, p, v float64) float64 {
 
 	impact := math.Log1p(v)
 
+	switch {
+	case m.signed && t.Side > 0:
+		m.buyInt += impact
+	case m.signed && t.Side < 0:
+		m.sellInt += impact
 	// Tick rule on trades (no quotes available).
-	if p > m.lastP {
+	case p > m.lastP:
 		m.buyInt += impact
-	} else if p < m.lastP {
+	case p < m.lastP:
 		m.sellInt += impact
 		// p == lastP is treated as neutral; only decay applies.
 	}
@@ -188,14 +242,485 @@ func (m *ModelHilbert) Update(dt float64, p, v float64) float64 {
 }
 
 // ============================================================================
-// 5. Model registry
+// 5. VWAP_Dev: price level vs. a decaying volume-weighted average price
+// ============================================================================
+
+type ModelVWAPDev struct {
+	sumPQ   float64 // decayed sum of p*q
+	sumQ    float64 // decayed sum of q
+	elapsed float64 // seconds since Reset
+	tau     float64 // decay time constant, seconds
+	squash  float64 // output = tanh(squash * relative deviation)
+}
+
+func NewVWAPDev() *ModelVWAPDev {
+	// tau=900s -> VWAP anchored on roughly the last 15 minutes of flow.
+	// squash=100 -> a 1% deviation maps to tanh(1) ≈ 0.76.
+	return &ModelVWAPDev{tau: 900, squash: 100}
+}
+
+func (m *ModelVWAPDev) Name() string { return "VWAP_Dev" }
+
+func (m *ModelVWAPDev) Reset() { m.sumPQ, m.sumQ, m.elapsed = 0, 0, 0 }
+
+func (m *ModelVWAPDev) Update(dt float64, p, v float64) float64 {
+	if dt > 0 {
+		decay := math.Exp(-dt / m.tau)
+		m.sumPQ *= decay
+		m.sumQ *= decay
+		m.elapsed += dt
+	}
+	m.sumPQ += p * v
+	m.sumQ += v
+
+	// Warmup: the VWAP isn't anchored on anything until ~tau of flow is in.
+	// The volume floor guards the division when the decayed sum underflows.
+	const minQ = 1e-12
+	if m.elapsed < m.tau || m.sumQ < minQ {
+		return 0
+	}
+	vwap := m.sumPQ / m.sumQ
+	if vwap <= 0 {
+		return 0
+	}
+	// Positive when trading rich vs. recent VWAP (mean-reversion candidate).
+	return math.Tanh(m.squash * (p - vwap) / vwap)
+}
+
+// ============================================================================
+// 6. Norm_OFI: signed flow over its own scale, comparable across symbols
+// ============================================================================
+
+type ModelNormOFI struct {
+	signed float64 // decayed sum of sign*q
+	scale  float64 // decayed sum of |sign*q|
+	tau    float64 // decay time constant, seconds
+	lastP  float64
+	init   bool
+}
+
+func NewNormOFI() *ModelNormOFI {
+	// tau=300s: same ballpark as Hawkes_OFI's ~350s half-life.
+	return &ModelNormOFI{tau: 300}
+}
+
+func (m *ModelNormOFI) Name() string { return "Norm_OFI" }
+
+func (m *ModelNormOFI) Reset() {
+	m.signed, m.scale, m.lastP, m.init = 0, 0, 0, false
+}
+
+func (m *ModelNormOFI) Update(dt float64, p, v float64) float64 {
+	if !m.init {
+		m.lastP = p
+		m.init = true
+		return 0
+	}
+
+	if dt > 0 {
+		decay := math.Exp(-dt / m.tau)
+		m.signed *= decay
+		m.scale *= decay
+	}
+
+	// Tick rule, same convention as Hawkes_OFI (p == lastP is neutral).
+	var flow float64
+	if p > m.lastP {
+		flow = v
+	} else if p < m.lastP {
+		flow = -v
+	}
+	m.lastP = p
+
+	m.signed += flow
+	m.scale += math.Abs(flow)
+
+	// Both sums share the same decay, so the ratio is bounded in [-1, 1]
+	// regardless of the symbol's typical trade size.
+	const eps = 1e-12
+	return m.signed / (m.scale + eps)
+}
+
+// ============================================================================
+// 7. Kalman_Vel / Kalman_Adapt: constant-velocity Kalman filter on log price
+// ============================================================================
+
+type ModelKalmanVel struct {
+	x, v          float64 // state: filtered log price, velocity (log-return / s)
+	p00, p01, p11 float64 // state covariance (symmetric)
+	q             float64 // process noise: velocity diffusion per second
+	r             float64 // measurement noise: variance of a trade print vs. state
+	init          bool
+
+	// Vol adaptation (volTau > 0): q is scaled by the ratio of short-run
+	// (volTau) to long-run (8*volTau) realized variance, so the gain on
+	// velocity rises when the market speeds up. r stays fixed: print noise
+	// is set by the tick/spread, not by volatility. With GARCHVolGain the
+	// ratio is GARCH(1,1) conditional over long-run variance instead.
+	volTau  float64
+	sSq, sT float64 // short-run decayed sum of dz^2 and of dt
+	lSq, lT float64 // long-run ditto
+	elapsed float64
+	lastZ   float64
+	garch   *GARCH11
+	name    string
+}
+
+func NewKalmanVel() *ModelKalmanVel {
+	// r=1e-8 -> ~1bp of print noise (bid/ask bounce).
+	// q=1e-12 -> velocity wanders ~1e-6/s per sqrt(s); smooth over minutes.
+	return &ModelKalmanVel{q: 1e-12, r: 1e-8, name: "Kalman_Vel"}
+}
+
+// NewKalmanAdaptive is Kalman_Vel with vol-adaptive process noise.
+func NewKalmanAdaptive() *ModelKalmanVel {
+	// volTau=300s short window vs. 40min baseline.
+	m := NewKalmanVel()
+	m.volTau, m.name = 300, "Kalman_Adapt"
+	if GARCHVolGain {
+		m.garch = NewGARCH11(GARCHAlpha, GARCHBeta, GARCHLearnRate)
+	}
+	return m
+}
+
+func (m *ModelKalmanVel) Name() string { return m.name }
+
+func (m *ModelKalmanVel) Reset() {
+	m.x, m.v, m.init = 0, 0, false
+	m.p00, m.p01, m.p11 = 0, 0, 0
+	m.sSq, m.sT, m.lSq, m.lT, m.elapsed, m.lastZ = 0, 0, 0, 0, 0, 0
+	if m.garch != nil {
+		m.garch.Reset()
+	}
+}
+
+// volGain returns the process-noise multiplier: short/long realized variance
+// rate (or the GARCH variance ratio), clamped to [0.1, 10], and 1 until the
+// short window has filled.
+func (m *ModelKalmanVel) volGain(dt, z float64) float64 {
+	if m.volTau <= 0 {
+		return 1
+	}
+	if dt > 0 {
+		ds := math.Exp(-dt / m.volTau)
+		dl := math.Exp(-dt / (8 * m.volTau))
+		m.sSq, m.sT = m.sSq*ds, m.sT*ds+dt
+		m.lSq, m.lT = m.lSq*dl, m.lT*dl+dt
+		m.elapsed += dt
+	}
+	dz := z - m.lastZ
+	m.sSq += dz * dz
+	m.lSq += dz * dz
+	m.lastZ = z
+	if m.garch != nil {
+		m.garch.Update(dz, dt)
+	}
+
+	var g float64
+	switch {
+	case m.elapsed < m.volTau:
+		return 1
+	case m.garch != nil:
+		g = m.garch.Ratio()
+	case m.sT <= 0 || m.lSq <= 0:
+		return 1
+	default:
+		g = (m.sSq / m.sT) / (m.lSq / m.lT)
+	}
+	return math.Max(0.1, math.Min(10, g))
+}
+
+func (m *ModelKalmanVel) Update(dt float64, p, v float64) float64 {
+	if p <= 0 {
+		return m.v
+	}
+	z := math.Log(p)
+	if !m.init {
+		// Position known to measurement precision, velocity unknown.
+		m.x, m.v, m.init = z, 0, true
+		m.p00, m.p01, m.p11 = m.r, 0, 1e-6
+		m.lastZ = z
+		return 0
+	}
+	q := m.q * m.volGain(dt, z)
+
+	// Predict: F = [[1, dt], [0, 1]], Q = q * [[dt^3/3, dt^2/2], [dt^2/2, dt]].
+	if dt > 0 {
+		m.x += m.v * dt
+		dt2 := dt * dt
+		p00 := m.p00 + 2*dt*m.p01 + dt2*m.p11 + q*dt2*dt/3
+		p01 := m.p01 + dt*m.p11 + q*dt2/2
+		p11 := m.p11 + q*dt
+		m.p00, m.p01, m.p11 = p00, p01, p11
+	}
+
+	// Update with H = [1, 0].
+	s := m.p00 + m.r
+	if s <= 0 {
+		return 0
+	}
+	k0 := m.p00 / s
+	k1 := m.p01 / s
+	innov := z - m.x
+	m.x += k0 * innov
+	m.v += k1 * innov
+	m.p00, m.p01, m.p11 = (1-k0)*m.p00, (1-k0)*m.p01, m.p11-k1*m.p01
+
+	if math.IsNaN(m.v) || math.IsInf(m.v, 0) {
+		m.Reset()
+		return 0
+	}
+	return m.v
+}
+
+// ============================================================================
+// 8. Kyle_Lambda: decayed regression slope of price change on signed flow
 // ============================================================================
 
+type ModelKyleLambda struct {
+	sw, sx, sy, sxx, sxy float64 // decayed weight and moments of (flow, dp)
+	tau                  float64 // decay time constant, seconds
+	lastP                float64
+	init                 bool
+}
+
+func NewKyleLambda() *ModelKyleLambda {
+	// tau=600s: impact estimated over roughly the last 10 minutes.
+	return &ModelKyleLambda{tau: 600}
+}
+
+func (m *ModelKyleLambda) Name() string { return "Kyle_Lambda" }
+
+func (m *ModelKyleLambda) Reset() {
+	m.sw, m.sx, m.sy, m.sxx, m.sxy = 0, 0, 0, 0, 0
+	m.lastP, m.init = 0, false
+}
+
+func (m *ModelKyleLambda) Update(dt float64, p, v float64) float64 {
+	if !m.init || m.lastP <= 0 || p <= 0 {
+		m.lastP = p
+		m.init = true
+		return 0
+	}
+
+	if dt > 0 {
+		decay := math.Exp(-dt / m.tau)
+		m.sw *= decay
+		m.sx *= decay
+		m.sy *= decay
+		m.sxx *= decay
+		m.sxy *= decay
+	}
+
+	// Signed flow via the tick rule (p == lastP is neutral).
+	var flow float64
+	if p > m.lastP {
+		flow = v
+	} else if p < m.lastP {
+		flow = -v
+	}
+	dp := math.Log(p / m.lastP)
+	m.lastP = p
+
+	m.sw++
+	m.sx += flow
+	m.sy += dp
+	m.sxx += flow * flow
+	m.sxy += flow * dp
+
+	// Warmup: need a handful of effective observations for a slope.
+	const minObs = 20
+	const eps = 1e-18
+	if m.sw < minObs {
+		return 0
+	}
+	mx := m.sx / m.sw
+	varX := m.sxx/m.sw - mx*mx
+	if varX < eps {
+		return 0
+	}
+	cov := m.sxy/m.sw - mx*m.sy/m.sw
+	return cov / varX
+}
+
+// ============================================================================
+// 9. Size_Entropy: how evenly recent volume spreads across trade sizes
+// ============================================================================
+
+type ModelSizeEntropy struct {
+	hist   []float64 // decayed volume per log2-size bucket
+	sumW   float64   // decayed trade count (for the reference size)
+	sumLog float64   // decayed sum of log2(q)
+	tau    float64   // decay time constant, seconds
+}
+
+// NewSizeEntropy returns a model with the given number of octave-wide size
+// buckets, centred on the (decayed) geometric-mean trade size so the same
+// bucket count works for BTC-sized and DOGE-sized quantities.
+func NewSizeEntropy(buckets int) *ModelSizeEntropy {
+	if buckets < 2 {
+		buckets = 2
+	}
+	// tau=300s; 16 octaves covers sizes from 1/256x to 256x the typical print.
+	return &ModelSizeEntropy{hist: make([]float64, buckets), tau: 300}
+}
+
+func (m *ModelSizeEntropy) Name() string { return "Size_Entropy" }
+
+func (m *ModelSizeEntropy) Reset() {
+	clear(m.hist)
+	m.sumW, m.sumLog = 0, 0
+}
+
+func (m *ModelSizeEntropy) Update(dt float64, p, v float64) float64 {
+	if dt > 0 {
+		decay := math.Exp(-dt / m.tau)
+		for i := range m.hist {
+			m.hist[i] *= decay
+		}
+		m.sumW *= decay
+		m.sumLog *= decay
+	}
+	if v <= 0 {
+		return m.entropy()
+	}
+
+	lq := math.Log2(v)
+	m.sumW++
+	m.sumLog += lq
+
+	nb := len(m.hist)
+	b := int(math.Floor(lq-m.sumLog/m.sumW)) + nb/2
+	if b < 0 {
+		b = 0
+	} else if b >= nb {
+		b = nb - 1
+	}
+	m.hist[b] += v
+
+	return m.entropy()
+}
+
+// entropy returns the Shannon entropy (bits) of the normalized histogram:
+// 0 when all volume sits in one bucket, log2(buckets) when spread evenly.
+func (m *ModelSizeEntropy) entropy() float64 {
+	var total float64
+	for _, h := range m.hist {
+		total += h
+	}
+	if total <= 0 {
+		return 0
+	}
+	var e float64
+	for _, h := range m.hist {
+		if h > 0 {
+			q := h / total
+			e -= q * math.Log2(q)
+		}
+	}
+	return e
+}
+
+// ============================================================================
+// 10. OU_Revert: Ornstein-Uhlenbeck deviation from a slow equilibrium
+// ============================================================================
+
+type ModelOU struct {
+	mu      float64 // equilibrium log price (EWMA at rate theta)
+	lastX   float64 // previous log price
+	sumSq   float64 // decayed sum of squared log-price changes
+	sumT    float64 // decayed elapsed time matching sumSq
+	elapsed float64 // seconds since Reset
+	init    bool
+	theta   float64 // reversion speed, 1/s
+	tau     float64 // variance decay time constant, seconds
+}
+
+func NewOU() *ModelOU {
+	// theta=1/600 -> equilibrium tracks ~10 minutes of price.
+	// tau=900s -> diffusion variance from the last ~15 minutes.
+	return &ModelOU{theta: 1.0 / 600, tau: 900}
+}
+
+func (m *ModelOU) Name() string { return "OU_Revert" }
+
+func (m *ModelOU) Reset() {
+	m.mu, m.lastX, m.sumSq, m.sumT, m.elapsed = 0, 0, 0, 0, 0
+	m.init = false
+}
+
+func (m *ModelOU) Update(dt float64, p, v float64) float64 {
+	if p <= 0 {
+		return 0
+	}
+	x := math.Log(p)
+	if !m.init {
+		m.mu, m.lastX = x, x
+		m.init = true
+		return 0
+	}
+
+	if dt > 0 {
+		m.mu += (1 - math.Exp(-m.theta*dt)) * (x - m.mu)
+		decay := math.Exp(-dt / m.tau)
+		m.sumSq *= decay
+		m.sumT = m.sumT*decay + dt
+		m.elapsed += dt
+	}
+	dx := x - m.lastX
+	m.sumSq += dx * dx
+	m.lastX = x
+
+	if m.elapsed < m.tau || m.sumT <= 0 || m.sumSq <= 0 {
+		return 0
+	}
+	// Diffusion rate sigma^2 per second; an OU process with reversion speed
+	// theta has stationary std sigma/sqrt(2*theta) around its mean.
+	sd := math.Sqrt(m.sumSq / m.sumT / (2 * m.theta))
+	// Positive when price sits below equilibrium (snap-back up expected).
+	return (m.mu - x) / sd
+}
+
+// ============================================================================
+// 11. Model registry
+// ============================================================================
+
+// modelSpecs holds ModelsConfigPath as parsed on the first
+// GetContinuousModels call. Each symbol and each worker asks for a fresh
+// set, so the file is read once per run rather than on every call.
+var modelSpecs struct {
+	once  sync.Once
+	specs []ModelSpec
+	err   error
+}
+
+// GetContinuousModels returns a fresh set of models: the ones described in
+// ModelsConfigPath if that file exists and is valid, else the built-in set.
 func GetContinuousModels() []ContinuousModel {
+	modelSpecs.once.Do(func() {
+		modelSpecs.specs, modelSpecs.err = readModelSpecs(ModelsConfigPath)
+	})
+	if modelSpecs.err == nil {
+		if models, err := modelsFromSpecs(ModelsConfigPath, modelSpecs.specs); err == nil {
+			return models
+		}
+	}
+	return defaultContinuousModels()
+}
+
+func defaultContinuousModels() []ContinuousModel {
 	return []ContinuousModel{
 		NewHawkesIntensity(), // baseline, proven positive
 		NewHawkesOFI(),       // your new OFI-based variant
+		NewHawkesOFISigned(), // same, classified by aggressor side
 		NewSignature(),       // sign-corrected signature
 		NewHilbert(),         // robust Hilbert_Phase
+		NewVWAPDev(),         // level anchor, mean-reversion counterpart
+		NewNormOFI(),         // scale-free OFI for cross-symbol comparison
+		NewKalmanVel(),       // principled velocity smoother benchmark
+		NewKalmanAdaptive(),  // same, gain follows realized vol
+		NewKyleLambda(),      // price impact per unit of signed flow
+		NewSizeEntropy(16),   // lumpy (whale) vs. evenly spread flow
+		NewOU(),              // normalized distance from slow equilibrium
 	}
 }
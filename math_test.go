@@ -0,0 +1,41 @@
+package main
+
+import "testing"
+
+// TestTickRuleNeutralOnUnchangedPrice feeds the signed-flow models a run of
+// trades at one price with the aggressor side unknown, so each falls back
+// to the tick rule. Every trade is neutral: no flow accumulates and the
+// models output 0. Hawkes_OFI_Signed still counts the same trades once
+// their side is known.
+func TestTickRuleNeutralOnUnchangedPrice(t *testing.T) {
+	run := func(m ContinuousModel, side int8) float64 {
+		m.Reset()
+		var out float64
+		for i := range 200 {
+			out = updateTick(m, Tick{DT: 0.5, P: 40000, V: 0.1 + float64(i%7), Side: side})
+		}
+		return out
+	}
+
+	hawkes := NewHawkesOFISigned()
+	norm := NewNormOFI()
+	kyle := NewKyleLambda()
+	for _, m := range []ContinuousModel{hawkes, norm, kyle} {
+		if out := run(m, 0); out != 0 {
+			t.Errorf("%s: output %g on unchanged prices, want 0", m.Name(), out)
+		}
+	}
+	if hawkes.buyInt != 0 || hawkes.sellInt != 0 {
+		t.Errorf("Hawkes_OFI_Signed: buy %g, sell %g intensity, want none", hawkes.buyInt, hawkes.sellInt)
+	}
+	if norm.signed != 0 || norm.scale != 0 {
+		t.Errorf("Norm_OFI: signed %g, scale %g, want no flow", norm.signed, norm.scale)
+	}
+	if kyle.sx != 0 || kyle.sxx != 0 {
+		t.Errorf("Kyle_Lambda: flow moments %g, %g, want none", kyle.sx, kyle.sxx)
+	}
+
+	if out := run(hawkes, 1); out <= 0 {
+		t.Errorf("Hawkes_OFI_Signed: output %g on buyer-initiated trades, want > 0", out)
+	}
+}
@@ -1,68 +1,197 @@
 package main
 
 import (
+	"cmp"
 	"math"
+	"math/rand/v2"
+	"slices"
 	"sort"
 )
 
-// Consolidated OOS statistics for a single (model, horizon) pair.
+// Consolidated OOS statistics for a single (model, horizon) pair. JSON tags
+// follow the CSV column names; the train-segment fields are reported under
+// HorizonJSON.IS instead.
 type ReportStats struct {
-	TrainCount int
-	TestCount  int
+	TrainCount int `json:"-"`
+	TestCount  int `json:"test_n"`
+
+	// Effective test sample size after discounting overlapping labels
+	// (TestCount / overlap). Suppressed is set when it falls below
+	// MinEffectiveSamples, in which case no metrics are computed.
+	EffectiveN float64 `json:"effective_n"`
+	Suppressed bool    `json:"suppressed"`
 
 	// Correlation / IC (OOS, test-only)
-	PearsonIC  float64
-	SpearmanIC float64
+	PearsonIC  float64 `json:"pearson_ic"`
+	SpearmanIC float64 `json:"spearman_ic"`
+	KendallTau float64 `json:"kendall_tau"` // tau-b, robust to heavy tails
+	DistCorr   float64 `json:"dcor"`        // distance correlation, catches nonlinear dependence
+
+	// t-statistics for PearsonIC: ICTStat assumes independent samples;
+	// ICTStatHAC uses a Newey-West long-run variance with ICHACLag lags, which
+	// accounts for overlapping labels.
+	ICTStat    float64 `json:"ic_tstat"`
+	ICTStatHAC float64 `json:"ic_tstat_hac"`
+
+	// Two-sided p-value of ICTStatHAC (normal approximation), and its
+	// Benjamini-Hochberg adjustment across the report's (model, horizon)
+	// cells; ICQValue is filled in by the report, not AnalyzeFullSuiteOOS,
+	// and is 1 for the combined rows (Gated_Ensemble, Ridge_Combo), which
+	// are outside that family.
+	ICPValue float64 `json:"ic_p"`
+	ICQValue float64 `json:"ic_q"`
+
+	// Per-UTC-day Pearson ICs on the test segment: their mean, and t-stats
+	// of that mean assuming independent days (DailyICTStat) and with a
+	// Newey-West correction over DailyICLag lags (DailyICTStatNW).
+	DailyICDays    int     `json:"daily_ic_days"`
+	DailyICMean    float64 `json:"daily_ic_mean"`
+	DailyICTStat   float64 `json:"daily_ic_tstat"`
+	DailyICTStatNW float64 `json:"daily_ic_tstat_nw"`
+
+	// 95% stationary-bootstrap intervals on the test segment; all zero
+	// unless BootstrapIntervals is set.
+	PearsonICLow  float64 `json:"pearson_ic_low"`
+	PearsonICHigh float64 `json:"pearson_ic_high"`
+	SharpeLow     float64 `json:"sharpe_low"`
+	SharpeHigh    float64 `json:"sharpe_high"`
 
 	// Directional accuracy (OOS)
-	HitRate  float64 // fraction of non-zero returns where sign(signal) == sign(return)
-	HitRateZ float64 // z-score vs 50% baseline (binomial approximation)
-
-	// Conditional return curve (deciles, OOS)
-	DecileMean         []float64 // length 10, in raw return units
-	TopDecileRetBps    float64
-	BottomDecileRetBps float64
-	SpreadBps          float64 // TopDecile - BottomDecile (bps)
+	HitRate  float64 `json:"hit_rate"`   // fraction of non-zero returns where sign(signal) == sign(return)
+	HitRateZ float64 `json:"hit_rate_z"` // z-score vs 50% baseline (binomial approximation)
+
+	// Conditional return curve (CurveBuckets signal quantiles, OOS; deciles
+	// by default). The "decile" names predate the configurable bucket count.
+	DecileMean         []float64 `json:"decile_mean"` // length CurveBuckets, in raw return units
+	DecileCount        []int     `json:"decile_count"`
+	DecileStdErr       []float64 `json:"decile_stderr"` // raw return units
+	ThinBuckets        int       `json:"thin_buckets"`  // buckets with fewer than MinBucketCount points
+	TopDecileRetBps    float64   `json:"top_decile_bps"`
+	BottomDecileRetBps float64   `json:"bottom_decile_bps"`
+	SpreadBps          float64   `json:"spread_bps"` // TopDecile - BottomDecile (bps)
 
 	// Information theoretic (OOS)
-	MutualInfo   float64 // bits
-	NormalizedMI float64 // MI / H(Y)
+	MutualInfo   float64 `json:"mi"`  // bits
+	NormalizedMI float64 `json:"nmi"` // MI / H(Y)
 
-	// Probabilistic forecast quality (train on train, evaluate on test)
-	BaselineLogLoss float64
-	SignalLogLoss   float64
-	DeltaLogLoss    float64 // Baseline - Signal; >0 is better
+	// Same, with the signal rank-transformed to a uniform [0,1] marginal
+	// first (see RankNormMetrics).
+	MutualInfoRank   float64 `json:"mi_rank"`
+	NormalizedMIRank float64 `json:"nmi_rank"`
 
-	// Economic / risk metrics for sign(signal) strategy (OOS)
-	Sharpe       float64
-	MaxDrawdown  float64
-	AvgTrade     float64
-	AvgWin       float64
-	AvgLoss      float64
-	WinLossRatio float64
+	// Probabilistic forecast quality (train on train, evaluate on test)
+	BaselineLogLoss float64 `json:"baseline_logloss"`
+	SignalLogLoss   float64 `json:"signal_logloss"`
+	DeltaLogLoss    float64 `json:"delta_logloss"` // Baseline - Signal; >0 is better
+
+	// ΔLogLoss with the logistic fit on the train ECDF of the signal.
+	DeltaLogLossRank float64 `json:"delta_logloss_rank"`
+
+	// Economic / risk metrics for sign(signal) strategy (OOS).
+	// With VolTarget set, trade-level figures are in vol-targeted units
+	// (VolScale = VolTarget / std(returns)); Sharpe is scale-invariant.
+	//
+	// Sharpe is per observation; GrossSharpe and NetSharpe are per curve
+	// trade, before and after costs, so their gap is the cost. SharpeAnn
+	// scales Sharpe by sqrt(observations per year), assuming one trade per
+	// realized sample spacing (see sampleSpacingMs) over a 365-day (24/7)
+	// year and independent per-trade returns; with horizons longer than the
+	// sampling step the labels overlap and the annualized figure overstates
+	// what a non-overlapping book would earn.
+	VolScale     float64 `json:"vol_scale"`
+	ISSharpe     float64 `json:"-"` // same strategy on the train segment, for selection only
+	Sharpe       float64 `json:"sharpe"`
+	SharpeAnn    float64 `json:"sharpe_ann"`
+	GrossSharpe  float64 `json:"gross_sharpe"` // NetSharpe's trades before costs
+	NetSharpe    float64 `json:"net_sharpe"`   // after CostBps per position flip
+	Flips        int     `json:"flips"`        // sign changes between consecutive curve trades
+	Turnover     float64 `json:"turnover"`     // Flips / curve trades
+	MaxDrawdown  float64 `json:"max_drawdown"`
+	AvgTrade     float64 `json:"avg_trade"`
+	AvgWin       float64 `json:"avg_win"`
+	AvgLoss      float64 `json:"avg_loss"`
+	WinLossRatio float64 `json:"win_loss_ratio"`
+
+	// Time under water, in non-overlapping trades: the longest run below the
+	// prior equity peak and the mean run length (see StrategyRiskStats).
+	MaxDDDuration int     `json:"max_dd_duration"`
+	AvgUnderwater float64 `json:"avg_underwater"`
+
+	// Shape of the per-trade returns, for the Deflated Sharpe.
+	TradeSkew     float64 `json:"trade_skew"`
+	TradeKurtosis float64 `json:"trade_kurtosis"` // raw (normal = 3)
+
+	// DSR is the probability that the true Sharpe beats the best of the
+	// run's model×horizon trials, given the variance of their Sharpes (see
+	// DeflatedSharpe). Set by the caller, which sees every trial.
+	DSR float64 `json:"dsr"`
 }
 
 // OOS rolling-window metrics on the test segment.
 type WindowMetrics struct {
-	StartTime float64
-	EndTime   float64
-	Count     int
-
-	PearsonIC  float64
-	SpearmanIC float64
-	HitRate    float64
-	Sharpe     float64
+	StartTime float64 `json:"start_time"`
+	EndTime   float64 `json:"end_time"`
+	Count     int     `json:"test_n"`
+
+	PearsonIC  float64 `json:"pearson_ic"`
+	SpearmanIC float64 `json:"spearman_ic"`
+	HitRate    float64 `json:"hit_rate"`
+	Sharpe     float64 `json:"sharpe"`
 }
 
 // OOS regime metrics (volatility or time-of-day on test segment).
 type RegimeMetrics struct {
-	Name  string
-	Count int
+	Name  string `json:"regime"`
+	Count int    `json:"test_n"`
+
+	PearsonIC  float64 `json:"pearson_ic"`
+	SpearmanIC float64 `json:"spearman_ic"`
+	HitRate    float64 `json:"hit_rate"`
+	Sharpe     float64 `json:"sharpe"`
+}
+
+// OOS IC across several train/test boundaries (see BoundarySweepOOS).
+type BoundaryMetrics struct {
+	ICs []float64 // test-segment Pearson IC per boundary, in input order
 
-	PearsonIC  float64
-	SpearmanIC float64
-	HitRate    float64
-	Sharpe     float64
+	Mean      float64
+	Std       float64
+	Min       float64
+	Max       float64
+	SignAgree float64 // fraction of boundaries whose IC has the sign of Mean
+}
+
+// Verdict classifies the sweep: "ROBUST" when the IC has the same sign at
+// every boundary, "CUT-DEPENDENT" when it flips, and "-" with fewer than two
+// boundaries.
+func (b BoundaryMetrics) Verdict() string {
+	switch {
+	case len(b.ICs) < 2:
+		return "-"
+	case b.SignAgree == 1:
+		return "ROBUST"
+	default:
+		return "CUT-DEPENDENT"
+	}
+}
+
+// Walk-forward fold dispersion (see WalkForwardOOS).
+type WalkForwardSummary struct {
+	Folds int
+
+	MeanIC, StdIC                     float64
+	MeanDeltaLogLoss, StdDeltaLogLoss float64
+	MeanSharpe, StdSharpe             float64
+}
+
+// OOS event study around the largest forward moves in the test segment.
+type BigMoveMetrics struct {
+	Count int // non-overlapping big moves examined
+
+	HitRate  float64 // fraction where sign(pre-move signal) == sign(move)
+	HitRateZ float64 // z-score vs 50% baseline
+	MeanZ    float64 // mean pre-move signal z-score, signed by move direction
+	Elevated float64 // fraction with |z| > 1 before the move
 }
 
 // internal helper for chronological train/test split
@@ -76,35 +205,69 @@ type trainTestSplit struct {
 }
 
 // AnalyzeFullSuiteOOS computes all core metrics OOS, with a single chronological
-// train/test split for a given (model, horizon) signal.
-func AnalyzeFullSuiteOOS(times, feats, returns []float64, trainFrac float64) ReportStats {
-	s := splitTrainTest(times, feats, returns, trainFrac)
+// train/test split for a given (model, horizon) signal. horizonMs is the
+// label horizon, used to discount overlapping samples. Like every *OOS
+// metric here it expects samples sorted by time (streamTasks merges them
+// that way; unsorted ones give an empty result) and leaves its inputs
+// untouched.
+func AnalyzeFullSuiteOOS(times, feats, returns []float64, trainFrac float64, horizonMs int64) ReportStats {
+	return AnalyzeFullSuiteOOSCached(times, feats, returns, trainFrac, horizonMs, nil)
+}
+
+// AnalyzeFullSuiteOOSCached is AnalyzeFullSuiteOOS with the feature's ranks
+// for the Spearman IC taken from fr, so one model's cells across horizons
+// rank their (identical) feature once.
+func AnalyzeFullSuiteOOSCached(times, feats, returns []float64, trainFrac float64, horizonMs int64, fr *FeatureRanks) ReportStats {
+	spacing := sampleSpacingMs(times)
+	overlap := labelOverlap(horizonMs, spacing)
+	s := purgedSplit(times, feats, returns, trainFrac, horizonMs)
 	trainN := len(s.TrainF)
 	testN := len(s.TestF)
 
 	stats := ReportStats{
 		TrainCount: trainN,
 		TestCount:  testN,
-		DecileMean: make([]float64, 10),
+		EffectiveN: float64(testN) / overlap,
+		DecileMean: make([]float64, max(CurveBuckets, 1)),
 	}
-	if testN < 30 {
-		// Too little test data to say anything meaningful.
+	if stats.EffectiveN < MinEffectiveSamples {
+		// Too little independent test data to say anything meaningful.
+		stats.Suppressed = true
 		return stats
 	}
 
 	// 1. ICs (test-only)
 	stats.PearsonIC = Pearson(s.TestF, s.TestR)
-	stats.SpearmanIC = Spearman(s.TestF, s.TestR)
+	stats.SpearmanIC = Pearson(fr.ranks(feats, len(feats)-testN), rankify(s.TestR))
+	stats.KendallTau = KendallTau(s.TestF, s.TestR)
+	stats.DistCorr = DistanceCorrelation(s.TestF, s.TestR)
+	lag := ICHACLag
+	if lag <= 0 {
+		lag = int(math.Ceil(overlap))
+	}
+	stats.ICTStat, stats.ICTStatHAC = ICTStats(s.TestF, s.TestR, lag)
+	stats.ICPValue = twoSidedP(stats.ICTStatHAC)
+
+	daily := DailyICs(s.TestT, s.TestF, s.TestR)
+	dayLag := DailyICLag
+	if dayLag <= 0 {
+		dayLag = int(math.Ceil(float64(horizonMs) / dayMillis))
+	}
+	stats.DailyICDays = len(daily)
+	stats.DailyICMean, stats.DailyICTStat = meanTStat(daily)
+	stats.DailyICTStatNW = NeweyWestTStat(daily, dayLag)
 
 	// 2. Hit rate vs 50% baseline (test-only)
 	stats.HitRate, stats.HitRateZ = HitRateStats(s.TestF, s.TestR)
 
-	// 3. Conditional return curve (deciles, test-only)
-	stats.DecileMean, stats.BottomDecileRetBps, stats.TopDecileRetBps, stats.SpreadBps =
-		DecileCurve(s.TestF, s.TestR)
+	// 3. Conditional return curve (CurveBuckets quantiles, test-only)
+	curve := ConditionalCurve(s.TestF, s.TestR, CurveBuckets)
+	stats.DecileMean, stats.DecileCount, stats.DecileStdErr = curve.Means, curve.Counts, curve.StdErrs
+	stats.ThinBuckets = curve.Thin(MinBucketCount)
+	stats.BottomDecileRetBps, stats.TopDecileRetBps, stats.SpreadBps = curve.BottomBps, curve.TopBps, curve.SpreadBps
 
 	// 4. Mutual information + NMI (test-only)
-	stats.MutualInfo, stats.NormalizedMI = CalcMutualInfo(s.TestF, s.TestR, 10)
+	stats.MutualInfo, stats.NormalizedMI = CalcMutualInfo(s.TestF, s.TestR, MIBins)
 
 	// 5. Δ Log-loss vs baseline:
 	//    - baseline LL uses test labels only
@@ -112,17 +275,83 @@ func AnalyzeFullSuiteOOS(times, feats, returns []float64, trainFrac float64) Rep
 	stats.BaselineLogLoss, stats.SignalLogLoss, stats.DeltaLogLoss =
 		LogLossImprovementTrainTest(s.TrainF, s.TrainR, s.TestF, s.TestR)
 
+	// 5b. Same MI / logistic with the marginal shape of the signal removed.
+	if RankNormMetrics {
+		trainU, testU := uniformizeTrainTest(s.TrainF, s.TestF)
+		stats.MutualInfoRank, stats.NormalizedMIRank = CalcMutualInfo(testU, s.TestR, MIBins)
+		_, _, stats.DeltaLogLossRank = LogLossImprovementTrainTest(trainU, s.TrainR, testU, s.TestR)
+	}
+
 	// 6. Sharpe + basic risk profile (test-only)
-	stats.Sharpe, stats.MaxDrawdown, stats.AvgTrade, stats.AvgWin, stats.AvgLoss, stats.WinLossRatio =
-		StrategyRiskStats(s.TestF, s.TestR)
+	stats.VolScale = volTargetScale(s.TestR)
+	rs := StrategyRiskStats(s.TestT, s.TestF, s.TestR, horizonMs)
+	stats.Sharpe, stats.MaxDrawdown, stats.MaxDDDuration, stats.AvgUnderwater = rs.Sharpe, rs.MaxDrawdown, rs.MaxDDDuration, rs.AvgUnderwater
+	stats.AvgTrade, stats.AvgWin, stats.AvgLoss, stats.WinLossRatio = rs.AvgTrade, rs.AvgWin, rs.AvgLoss, rs.WinLossRatio
+	stats.TradeSkew, stats.TradeKurtosis = rs.Skew, rs.Kurtosis
+	stats.SharpeAnn = stats.Sharpe * math.Sqrt(annualFactor(spacing/1000))
+	stats.ISSharpe = signSharpe(s.TrainF, s.TrainR)
+	stats.GrossSharpe, stats.NetSharpe, stats.Flips, stats.Turnover = NetStrategyStats(s.TestT, s.TestF, s.TestR, horizonMs, CostBps)
+
+	// 7. Bootstrap CIs for IC and Sharpe (test-only)
+	if BootstrapIntervals {
+		meanBlock := BootstrapMeanBlock
+		if meanBlock <= 0 {
+			meanBlock = overlap
+		}
+		stats.PearsonICLow, stats.PearsonICHigh, stats.SharpeLow, stats.SharpeHigh =
+			BootstrapCI(s.TestF, s.TestR, BootstrapResamples, meanBlock)
+	}
 
 	return stats
 }
 
+// labelOverlap is how many consecutive samples share (part of) one label
+// window: horizon / sample spacing, at least 1. spacingMs is the realized
+// spacing (see sampleSpacingMs); 0 means SamplingRateSec, the time clock's
+// nominal step.
+func labelOverlap(horizonMs int64, spacingMs float64) float64 {
+	if spacingMs <= 0 {
+		spacingMs = SamplingRateSec * 1000
+	}
+	o := float64(horizonMs) / spacingMs
+	if o < 1 {
+		return 1
+	}
+	return o
+}
+
+// splitGuards returns the purge and embargo of a cut through samples at
+// times whose labels span horizonMs: the purge is one horizon when
+// PurgeSplit is on, and a negative EmbargoSamples becomes the label overlap
+// at the realized sample spacing.
+func splitGuards(times []float64, horizonMs int64) (purgeMs int64, embargoN int) {
+	if PurgeSplit {
+		purgeMs = horizonMs
+	}
+	embargoN = EmbargoSamples
+	if embargoN < 0 {
+		embargoN = int(labelOverlap(horizonMs, sampleSpacingMs(times)))
+	}
+	return purgeMs, embargoN
+}
+
+// purgedSplit is sortedTrainTestSplit with splitGuards' purge and embargo
+// for labels of horizonMs; every *OOS metric cuts its samples this way.
+func purgedSplit(times, feats, returns []float64, trainFrac float64, horizonMs int64) trainTestSplit {
+	purgeMs, embargoN := splitGuards(times, horizonMs)
+	return sortedTrainTestSplit(times, feats, returns, trainFrac, purgeMs, embargoN)
+}
+
+// purgedTrainN is the length of purgedSplit's train segment, for fits that
+// draw their train samples from several series aligned with times.
+func purgedTrainN(times, returns []float64, trainFrac float64, horizonMs int64) int {
+	return len(purgedSplit(times, returns, returns, trainFrac, horizonMs).TrainR)
+}
+
 // RollingWindowMetricsOOS computes OOS metrics over multiple contiguous time
-// windows on the test segment (after the same train/test split).
-func RollingWindowMetricsOOS(times, feats, returns []float64, trainFrac float64, windows int) []WindowMetrics {
-	s := splitTrainTest(times, feats, returns, trainFrac)
+// windows on the test segment (after the same purged train/test split).
+func RollingWindowMetricsOOS(times, feats, returns []float64, trainFrac float64, horizonMs int64, windows int) []WindowMetrics {
+	s := purgedSplit(times, feats, returns, trainFrac, horizonMs)
 	n := len(s.TestF)
 	if n < 60 || windows <= 0 {
 		return nil
@@ -155,7 +384,6 @@ func RollingWindowMetricsOOS(times, feats, returns []float64, trainFrac float64,
 		tEnd := s.TestT[end-1]
 
 		hit, _ := HitRateStats(sig, ret)
-		sh, _, _, _, _, _ := StrategyRiskStats(sig, ret)
 
 		out = append(out, WindowMetrics{
 			StartTime:  tStart,
@@ -164,32 +392,43 @@ func RollingWindowMetricsOOS(times, feats, returns []float64, trainFrac float64,
 			PearsonIC:  Pearson(sig, ret),
 			SpearmanIC: Spearman(sig, ret),
 			HitRate:    hit,
-			Sharpe:     sh,
+			Sharpe:     StrategyRiskStats(s.TestT[start:end], sig, ret, horizonMs).Sharpe,
 		})
 	}
 	return out
 }
 
 // VolRegimeMetricsOOS computes OOS metrics across volatility regimes
-// (low/medium/high), based on |return| within the test segment.
-func VolRegimeMetricsOOS(times, feats, returns []float64, trainFrac float64) []RegimeMetrics {
-	s := splitTrainTest(times, feats, returns, trainFrac)
+// (low/medium/high). A sample's regime is its backward realized vol (see
+// BackwardVol, span volWindow samples, from labels of horizonMs that have
+// closed by then; see RegimeVol for the GARCH alternative), so it is known
+// at decision time and doesn't peek at the sample's own label. Tercile cut
+// points come from the train segment.
+func VolRegimeMetricsOOS(times, feats, returns []float64, trainFrac float64, horizonMs int64, volWindow int) []RegimeMetrics {
+	s := purgedSplit(times, feats, returns, trainFrac, horizonMs)
 	n := len(s.TestR)
 	if n < 60 {
 		return nil
 	}
 
-	vols := make([]float64, n)
-	for i := 0; i < n; i++ {
-		vols[i] = math.Abs(s.TestR[i])
-	}
+	// The test segment is the tail of the (time-sorted) series.
+	rv := RegimeVol(times, returns, horizonMs, volWindow)
+	testStart := len(times) - n
+	vols := rv[testStart:]
 
-	sorted := make([]float64, n)
-	copy(sorted, vols)
+	var sorted []float64
+	for _, v := range rv[:testStart] {
+		if v > 0 {
+			sorted = append(sorted, v)
+		}
+	}
+	if len(sorted) < 60 {
+		return nil
+	}
 	sort.Float64s(sorted)
 
-	q1 := sorted[n/3]
-	q2 := sorted[2*n/3]
+	q1 := sorted[len(sorted)/3]
+	q2 := sorted[2*len(sorted)/3]
 
 	var idxLow, idxMed, idxHigh []int
 	for i := 0; i < n; i++ {
@@ -208,21 +447,22 @@ func VolRegimeMetricsOOS(times, feats, returns []float64, trainFrac float64) []R
 		if len(idxs) < 20 {
 			return RegimeMetrics{Name: name, Count: len(idxs)}
 		}
+		ts := make([]float64, len(idxs))
 		sig := make([]float64, len(idxs))
 		ret := make([]float64, len(idxs))
 		for j, i := range idxs {
+			ts[j] = s.TestT[i]
 			sig[j] = s.TestF[i]
 			ret[j] = s.TestR[i]
 		}
 		hit, _ := HitRateStats(sig, ret)
-		sh, _, _, _, _, _ := StrategyRiskStats(sig, ret)
 		return RegimeMetrics{
 			Name:       name,
 			Count:      len(idxs),
 			PearsonIC:  Pearson(sig, ret),
 			SpearmanIC: Spearman(sig, ret),
 			HitRate:    hit,
-			Sharpe:     sh,
+			Sharpe:     StrategyRiskStats(ts, sig, ret, horizonMs).Sharpe,
 		}
 	}
 
@@ -234,15 +474,15 @@ func VolRegimeMetricsOOS(times, feats, returns []float64, trainFrac float64) []R
 }
 
 // TimeOfDayRegimeMetricsOOS computes OOS metrics across time-of-day regimes
-// (early / mid / late) on the test segment, using ms-of-day from timestamps.
-func TimeOfDayRegimeMetricsOOS(times, feats, returns []float64, trainFrac float64) []RegimeMetrics {
-	s := splitTrainTest(times, feats, returns, trainFrac)
+// (early / mid / late) on the purged test segment, using ms-of-day from
+// timestamps.
+func TimeOfDayRegimeMetricsOOS(times, feats, returns []float64, trainFrac float64, horizonMs int64) []RegimeMetrics {
+	s := purgedSplit(times, feats, returns, trainFrac, horizonMs)
 	n := len(s.TestT)
 	if n < 60 {
 		return nil
 	}
 
-	const dayMillis = 24 * 60 * 60 * 1000.0
 	third := dayMillis / 3.0
 
 	var earlyIdx, midIdx, lateIdx []int
@@ -262,21 +502,22 @@ func TimeOfDayRegimeMetricsOOS(times, feats, returns []float64, trainFrac float6
 		if len(idxs) < 20 {
 			return RegimeMetrics{Name: name, Count: len(idxs)}
 		}
+		ts := make([]float64, len(idxs))
 		sig := make([]float64, len(idxs))
 		ret := make([]float64, len(idxs))
 		for j, i := range idxs {
+			ts[j] = s.TestT[i]
 			sig[j] = s.TestF[i]
 			ret[j] = s.TestR[i]
 		}
 		hit, _ := HitRateStats(sig, ret)
-		sh, _, _, _, _, _ := StrategyRiskStats(sig, ret)
 		return RegimeMetrics{
 			Name:       name,
 			Count:      len(idxs),
 			PearsonIC:  Pearson(sig, ret),
 			SpearmanIC: Spearman(sig, ret),
 			HitRate:    hit,
-			Sharpe:     sh,
+			Sharpe:     StrategyRiskStats(ts, sig, ret, horizonMs).Sharpe,
 		}
 	}
 
@@ -287,36 +528,556 @@ func TimeOfDayRegimeMetricsOOS(times, feats, returns []float64, trainFrac float6
 	}
 }
 
-// ---------------------- shared train/test split ----------------------
+// WalkForwardOOS splits the time-sorted samples into folds+1 equal blocks and,
+// for fold k, trains on blocks 0..k (expanding window) and tests on block
+// k+1. Each fold's cut is purged and embargoed like the core split (see
+// splitGuards) for labels of horizonMs. The logistic behind DeltaLogLoss is
+// refit every fold, warm-started from the previous fold's fit. Per-fold
+// stats fill TrainCount, TestCount, PearsonIC, SpearmanIC, HitRate, the
+// log-loss fields and Sharpe.
+func WalkForwardOOS(times, feats, returns []float64, folds int, horizonMs int64) ([]ReportStats, WalkForwardSummary) {
+	n := len(feats)
+	if folds <= 0 || n != len(returns) || n != len(times) || n/(folds+1) < 20 || !slices.IsSorted(times) {
+		return nil, WalkForwardSummary{}
+	}
+	block := n / (folds + 1)
+	purgeMs, embargoN := splitGuards(times, horizonMs)
+	out := make([]ReportStats, 0, folds)
+	var a, b float64
+	for k := 0; k < folds; k++ {
+		cut := (k + 1) * block
+		testEnd := cut + block
+		if k == folds-1 {
+			testEnd = n
+		}
+		trainEnd, testStart := cut, min(cut+max(embargoN, 0), testEnd)
+		for purgeMs > 0 && trainEnd > 0 && times[trainEnd-1]+float64(purgeMs) >= times[cut] {
+			trainEnd--
+		}
+		if trainEnd < 20 || testEnd-testStart < 20 {
+			continue
+		}
+		trF, trR := feats[:trainEnd], returns[:trainEnd]
+		teF, teR := feats[testStart:testEnd], returns[testStart:testEnd]
+
+		st := ReportStats{
+			TrainCount: len(trF),
+			TestCount:  len(teF),
+			PearsonIC:  Pearson(teF, teR),
+			SpearmanIC: Spearman(teF, teR),
+			Sharpe:     signSharpe(teF, teR),
+		}
+		st.HitRate, _ = HitRateStats(teF, teR)
+		st.BaselineLogLoss, st.SignalLogLoss, st.DeltaLogLoss, a, b =
+			logLossImprovementFrom(trF, trR, teF, teR, a, b)
+		out = append(out, st)
+	}
+
+	sum := WalkForwardSummary{Folds: len(out)}
+	ics := make([]float64, len(out))
+	dlls := make([]float64, len(out))
+	shs := make([]float64, len(out))
+	for i, st := range out {
+		ics[i], dlls[i], shs[i] = st.PearsonIC, st.DeltaLogLoss, st.Sharpe
+	}
+	sum.MeanIC, sum.StdIC = meanStd(ics)
+	sum.MeanDeltaLogLoss, sum.StdDeltaLogLoss = meanStd(dlls)
+	sum.MeanSharpe, sum.StdSharpe = meanStd(shs)
+	return out, sum
+}
+
+// meanStd returns the mean and population standard deviation of x.
+func meanStd(x []float64) (mean, std float64) {
+	if len(x) == 0 {
+		return 0, 0
+	}
+	for _, v := range x {
+		mean += v
+	}
+	mean /= float64(len(x))
+	for _, v := range x {
+		std += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(std / float64(len(x)))
+}
+
+// BoundarySweepOOS repeats the chronological split at each train fraction in
+// fracs and reports the spread of the test-segment Pearson IC. A signal whose
+// IC keeps its sign and size across boundaries doesn't owe its result to a
+// lucky cut point.
+func BoundarySweepOOS(times, feats, returns []float64, fracs []float64, horizonMs int64) BoundaryMetrics {
+	var out BoundaryMetrics
+	if len(fracs) == 0 {
+		return out
+	}
+	out.ICs = make([]float64, len(fracs))
+	out.Min, out.Max = math.Inf(1), math.Inf(-1)
+	for i, f := range fracs {
+		s := purgedSplit(times, feats, returns, f, horizonMs)
+		ic := Pearson(s.TestF, s.TestR)
+		out.ICs[i] = ic
+		out.Mean += ic
+		out.Min = math.Min(out.Min, ic)
+		out.Max = math.Max(out.Max, ic)
+	}
+	k := float64(len(fracs))
+	out.Mean /= k
+
+	var ss float64
+	var agree int
+	for _, ic := range out.ICs {
+		ss += (ic - out.Mean) * (ic - out.Mean)
+		if ic*out.Mean > 0 {
+			agree++
+		}
+	}
+	out.Std = math.Sqrt(ss / k)
+	out.SignAgree = float64(agree) / k
+	return out
+}
+
+// BigMoveMetricsOOS picks the topN largest |forward return| samples in the
+// test segment, at least horizonMs apart so one move isn't counted once per
+// overlapping sample, and asks whether the signal was already pointing the
+// right way in the horizonMs leading up to each. The signal is z-scored with
+// train-segment mean/std so "elevated" is judged against in-sample scale.
+func BigMoveMetricsOOS(times, feats, returns []float64, trainFrac float64, horizonMs int64, topN int) BigMoveMetrics {
+	s := purgedSplit(times, feats, returns, trainFrac, horizonMs)
+	n := len(s.TestR)
+	if n < 60 || topN <= 0 || len(s.TrainF) < 2 {
+		return BigMoveMetrics{}
+	}
+
+	var mean, m2 float64
+	for _, f := range s.TrainF {
+		mean += f
+	}
+	mean /= float64(len(s.TrainF))
+	for _, f := range s.TrainF {
+		d := f - mean
+		m2 += d * d
+	}
+	std := math.Sqrt(m2 / float64(len(s.TrainF)))
+	if std == 0 {
+		return BigMoveMetrics{}
+	}
+
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return math.Abs(s.TestR[order[a]]) > math.Abs(s.TestR[order[b]])
+	})
+
+	gap := float64(horizonMs)
+	var picked []int
+	for _, i := range order {
+		if len(picked) == topN || s.TestR[i] == 0 {
+			break
+		}
+		clash := false
+		for _, j := range picked {
+			if math.Abs(s.TestT[i]-s.TestT[j]) < gap {
+				clash = true
+				break
+			}
+		}
+		if !clash {
+			picked = append(picked, i)
+		}
+	}
+	if len(picked) == 0 {
+		return BigMoveMetrics{}
+	}
+
+	var hits, elevated int
+	var sumZ float64
+	for _, i := range picked {
+		// Average signal over (t - horizon, t]; TestT is sorted ascending.
+		t0 := s.TestT[i] - gap
+		var sum float64
+		var cnt int
+		for k := i; k >= 0 && s.TestT[k] > t0; k-- {
+			sum += s.TestF[k]
+			cnt++
+		}
+		z := (sum/float64(cnt) - mean) / std
+
+		dir := 1.0
+		if s.TestR[i] < 0 {
+			dir = -1
+		}
+		if z*dir > 0 {
+			hits++
+		}
+		if math.Abs(z) > 1 {
+			elevated++
+		}
+		sumZ += z * dir
+	}
+
+	m := len(picked)
+	out := BigMoveMetrics{
+		Count:    m,
+		HitRate:  float64(hits) / float64(m),
+		MeanZ:    sumZ / float64(m),
+		Elevated: float64(elevated) / float64(m),
+	}
+	out.HitRateZ = (out.HitRate - 0.5) / math.Sqrt(0.25/float64(m))
+	return out
+}
+
+// BackwardVol returns, per time-sorted sample, a trailing volatility that
+// uses only returns already realized at that sample: an EWMA (span samples)
+// of squared short-horizon labels from earlier samples whose window
+// [t, t+horizonMs] has closed. Samples before any label closes get 0.
+func BackwardVol(times, shortRets []float64, horizonMs int64, span int) []float64 {
+	n := len(times)
+	out := make([]float64, n)
+	alpha := 2 / (float64(span) + 1)
+	var ewma float64
+	var seen bool
+	j := 0
+	for i := 0; i < n; i++ {
+		for j < i && times[j]+float64(horizonMs) <= times[i] {
+			r2 := shortRets[j] * shortRets[j]
+			if !seen {
+				ewma, seen = r2, true
+			} else {
+				ewma += alpha * (r2 - ewma)
+			}
+			j++
+		}
+		out[i] = math.Sqrt(ewma)
+	}
+	return out
+}
+
+// BackwardGARCHVol is BackwardVol with a GARCH(1,1) (see GARCH11) in place
+// of the EWMA: each label is fed to it as its window closes, as a return
+// over horizonMs, so the vol it gives a sample again uses only returns
+// realized by then. Samples before any label closes get 0.
+func BackwardGARCHVol(times, shortRets []float64, horizonMs int64) []float64 {
+	n := len(times)
+	out := make([]float64, n)
+	g := NewGARCH11(GARCHAlpha, GARCHBeta, GARCHLearnRate)
+	dt := float64(max(horizonMs, 1))
+	j := 0
+	for i := 0; i < n; i++ {
+		for j < i && times[j]+float64(horizonMs) <= times[i] {
+			g.Update(shortRets[j], dt)
+			j++
+		}
+		out[i] = g.Sigma()
+	}
+	return out
+}
+
+// RegimeVol is the backward vol that buckets samples into volatility
+// regimes: BackwardGARCHVol with VolRegimeGARCH, else BackwardVol over span
+// samples.
+func RegimeVol(times, shortRets []float64, horizonMs int64, span int) []float64 {
+	if VolRegimeGARCH {
+		return BackwardGARCHVol(times, shortRets, horizonMs)
+	}
+	return BackwardVol(times, shortRets, horizonMs, span)
+}
+
+// GatedEnsemble is a second-stage signal that, at each sample, trusts the
+// model with the best train-segment |IC| within the sample's vol regime.
+// Picks and Signs are indexed VolLow, VolMed, VolHigh (Picks -1 when the
+// regime had too few train samples).
+type GatedEnsemble struct {
+	Picks [3]int
+	Signs [3]float64
+	Stats ReportStats
+}
+
+// GatedEnsembleOOS fits a GatedEnsemble on the train segment and evaluates
+// it OOS via AnalyzeFullSuiteOOS. feats[m] is model m's signal and regime a
+// backward-looking vol per sample (see RegimeVol), all aligned with the
+// time-sorted times/returns. Regime terciles, per-regime picks, signs and
+// the z-scoring of each model are all taken from the train segment of
+// purgedSplit.
+func GatedEnsembleOOS(times []float64, feats [][]float64, returns, regime []float64, trainFrac float64, horizonMs int64) GatedEnsemble {
+	out := GatedEnsemble{Picks: [3]int{-1, -1, -1}}
+	n := len(times)
+	trainN := purgedTrainN(times, returns, trainFrac, horizonMs)
+	if len(feats) == 0 || trainN < 60 {
+		return out
+	}
+
+	sorted := append([]float64(nil), regime[:trainN]...)
+	sort.Float64s(sorted)
+	q1, q2 := sorted[trainN/3], sorted[2*trainN/3]
+	regimeOf := func(v float64) int {
+		switch {
+		case v <= q1:
+			return 0
+		case v <= q2:
+			return 1
+		}
+		return 2
+	}
+
+	stds := make([]float64, len(feats))
+	for m, f := range feats {
+		_, stds[m] = meanStd(f[:trainN])
+	}
+
+	var idx [3][]int
+	for i := 0; i < trainN; i++ {
+		r := regimeOf(regime[i])
+		idx[r] = append(idx[r], i)
+	}
+	for r := range idx {
+		if len(idx[r]) < 20 {
+			continue
+		}
+		ret := make([]float64, len(idx[r]))
+		sig := make([]float64, len(idx[r]))
+		for j, i := range idx[r] {
+			ret[j] = returns[i]
+		}
+		bestIC := 0.0
+		for m, f := range feats {
+			if stds[m] == 0 {
+				continue
+			}
+			for j, i := range idx[r] {
+				sig[j] = f[i]
+			}
+			if ic := Pearson(sig, ret); math.Abs(ic) > math.Abs(bestIC) {
+				bestIC = ic
+				out.Picks[r] = m
+				out.Signs[r] = math.Copysign(1, ic)
+			}
+		}
+	}
 
-type parallelSorter struct {
-	times, feats, rets []float64
+	ens := make([]float64, n)
+	for i := range ens {
+		r := regimeOf(regime[i])
+		if m := out.Picks[r]; m >= 0 {
+			ens[i] = out.Signs[r] * feats[m][i] / stds[m]
+		}
+	}
+	out.Stats = AnalyzeFullSuiteOOS(times, ens, returns, trainFrac, horizonMs)
+	return out
+}
+
+// RidgeCombo is a linear combination of model signals. Weights apply to
+// each signal z-scored on the train segment and predict returns in train
+// standard deviations, so they read like partial correlations.
+type RidgeCombo struct {
+	Weights []float64
+	Stats   ReportStats
 }
 
-func (p parallelSorter) Len() int { return len(p.times) }
+// RidgeCombineOOS fits ridge weights of feats (one series per model,
+// aligned with the time-sorted times/returns) against returns on the train
+// segment of purgedSplit, and evaluates the combined signal OOS via
+// AnalyzeFullSuiteOOS. Signals and returns are z-scored on the train
+// segment, so lambda penalizes the weights on a common scale: it is added
+// to the diagonal of the signals' train correlation matrix (0 is plain
+// least squares).
+func RidgeCombineOOS(times []float64, feats [][]float64, returns []float64, trainFrac, lambda float64, horizonMs int64) RidgeCombo {
+	k := len(feats)
+	n := len(times)
+	out := RidgeCombo{Weights: make([]float64, k)}
+	trainN := purgedTrainN(times, returns, trainFrac, horizonMs)
+	if k == 0 || trainN < 60 {
+		return out
+	}
+
+	means := make([]float64, k)
+	stds := make([]float64, k)
+	for m, f := range feats {
+		means[m], stds[m] = meanStd(f[:trainN])
+	}
+	retMean, retStd := meanStd(returns[:trainN])
+	if retStd == 0 {
+		return out
+	}
+	z := func(m, i int) float64 {
+		if stds[m] == 0 {
+			return 0
+		}
+		return (feats[m][i] - means[m]) / stds[m]
+	}
+
+	// Normal equations (X'X/n + λI) w = X'y/n on the train segment.
+	a := make([][]float64, k)
+	b := make([]float64, k)
+	for r := range a {
+		a[r] = make([]float64, k)
+	}
+	row := make([]float64, k)
+	for i := 0; i < trainN; i++ {
+		for m := range feats {
+			row[m] = z(m, i)
+		}
+		y := (returns[i] - retMean) / retStd
+		for r := range k {
+			b[r] += row[r] * y
+			for c := range r + 1 {
+				a[r][c] += row[r] * row[c]
+			}
+		}
+	}
+	for r := range k {
+		b[r] /= float64(trainN)
+		for c := range r + 1 {
+			a[r][c] /= float64(trainN)
+			a[c][r] = a[r][c]
+		}
+		a[r][r] += lambda
+		if stds[r] == 0 {
+			// Constant signal: pin its weight to 0.
+			a[r][r] = 1
+		}
+	}
+	w, ok := solveLinear(a, b)
+	if !ok {
+		return out
+	}
+	out.Weights = w
+
+	combo := make([]float64, n)
+	for i := range combo {
+		for m := range feats {
+			combo[i] += w[m] * z(m, i)
+		}
+	}
+	out.Stats = AnalyzeFullSuiteOOS(times, combo, returns, trainFrac, horizonMs)
+	return out
+}
 
-func (p parallelSorter) Swap(i, j int) {
-	p.times[i], p.times[j] = p.times[j], p.times[i]
-	p.feats[i], p.feats[j] = p.feats[j], p.feats[i]
-	p.rets[i], p.rets[j] = p.rets[j], p.rets[i]
+// solveLinear solves a x = b by Gaussian elimination with partial pivoting,
+// overwriting a and b. ok is false when a is (numerically) singular.
+func solveLinear(a [][]float64, b []float64) (x []float64, ok bool) {
+	n := len(b)
+	for col := 0; col < n; col++ {
+		piv := col
+		for r := col + 1; r < n; r++ {
+			if math.Abs(a[r][col]) > math.Abs(a[piv][col]) {
+				piv = r
+			}
+		}
+		if math.Abs(a[piv][col]) < 1e-12 {
+			return nil, false
+		}
+		a[col], a[piv] = a[piv], a[col]
+		b[col], b[piv] = b[piv], b[col]
+		for r := col + 1; r < n; r++ {
+			f := a[r][col] / a[col][col]
+			for c := col; c < n; c++ {
+				a[r][c] -= f * a[col][c]
+			}
+			b[r] -= f * b[col]
+		}
+	}
+	x = make([]float64, n)
+	for r := n - 1; r >= 0; r-- {
+		sum := b[r]
+		for c := r + 1; c < n; c++ {
+			sum -= a[r][c] * x[c]
+		}
+		x[r] = sum / a[r][r]
+	}
+	return x, true
 }
 
-func (p parallelSorter) Less(i, j int) bool {
-	return p.times[i] < p.times[j]
+// HoldingCurve is the alpha-decay curve of a sign(signal) entry: MeanBps[k]
+// is the average PnL (bps of log return) after holding for a time in
+// (k·StepMs, (k+1)·StepMs]. Best is the bucket with the highest mean, -1
+// when no bucket has data.
+type HoldingCurve struct {
+	StepMs  int64
+	MeanBps []float64
+	Counts  []int
+	Best    int
 }
 
-func splitTrainTest(times, feats, returns []float64, trainFrac float64) trainTestSplit {
+// HoldMs is the upper edge of bucket k, i.e. its holding time.
+func (h HoldingCurve) HoldMs(k int) int64 { return int64(k+1) * h.StepMs }
+
+// HoldingCurveOOS enters sign(feat) at every test-segment sample and marks
+// the position to each later sample price up to maxMs ahead, averaging the
+// PnL per StepMs bucket of holding time. prices are the samples' own
+// (label) prices, so the curve is as fine as the sampling grid. Inputs are
+// aligned per sample and time-sorted, like every *OOS metric's, and left
+// untouched; the test segment is purgedSplit's for labels of horizonMs, so
+// the curve covers the same samples as that horizon's core-table cell.
+// Pairs straddling a price break (see isPriceBreak) are skipped.
+func HoldingCurveOOS(times, prices, feats []float64, trainFrac float64, horizonMs, stepMs, maxMs int64) HoldingCurve {
+	n := len(times)
+	out := HoldingCurve{StepMs: stepMs, Best: -1}
+	if stepMs <= 0 || maxMs < stepMs || n < 2 || len(prices) != n || len(feats) != n {
+		return out
+	}
+	s := purgedSplit(times, feats, prices, trainFrac, horizonMs)
+	if len(s.TestF) == 0 {
+		return out
+	}
+
+	buckets := int((maxMs + stepMs - 1) / stepMs)
+	sums := make([]float64, buckets)
+	out.Counts = make([]int, buckets)
+	for i := n - len(s.TestF); i < n; i++ {
+		if feats[i] == 0 || prices[i] <= 0 {
+			continue
+		}
+		side := math.Copysign(1, feats[i])
+		for j := i + 1; j < n; j++ {
+			dt := times[j] - times[i]
+			if dt > float64(maxMs) {
+				break
+			}
+			if dt <= 0 || prices[j] <= 0 || isPriceBreak(prices[i], prices[j]) {
+				continue
+			}
+			k := int((dt - 1) / float64(stepMs))
+			sums[k] += side * math.Log(prices[j]/prices[i])
+			out.Counts[k]++
+		}
+	}
+
+	out.MeanBps = make([]float64, buckets)
+	for k, c := range out.Counts {
+		if c == 0 {
+			continue
+		}
+		out.MeanBps[k] = sums[k] / float64(c) * 1e4
+		if out.Best < 0 || out.MeanBps[k] > out.MeanBps[out.Best] {
+			out.Best = k
+		}
+	}
+	return out
+}
+
+// ---------------------- shared train/test split ----------------------
+
+// sortedTrainTestSplit cuts chronologically sorted samples at trainFrac; it
+// does not sort (or otherwise modify) them, so the slices it returns alias
+// the inputs and repeated calls on the same arrays are free and identical.
+// streamTasks sorts every column once at the merge, which is what lets the
+// metric functions share one ResultContainer. With purgeMs > 0, train
+// samples whose label window [t, t+purgeMs] reaches the first test
+// timestamp are dropped, so no train label shares data with the test
+// period; pass the label horizon. embargoN further skips that many test
+// samples right after the cut. Unsorted times give an empty split, and so
+// an empty result from every *OOS metric.
+func sortedTrainTestSplit(times, feats, returns []float64, trainFrac float64, purgeMs int64, embargoN int) trainTestSplit {
 	n := len(feats)
-	if n == 0 || n != len(returns) || n != len(times) {
+	if n == 0 || n != len(returns) || n != len(times) || !slices.IsSorted(times) {
 		return trainTestSplit{}
 	}
 	if trainFrac <= 0 || trainFrac >= 1 {
 		trainFrac = 0.7
 	}
 
-	// Sort all three slices chronologically by time in place.
-	sort.Sort(parallelSorter{times: times, feats: feats, rets: returns})
-
 	trainN := int(trainFrac * float64(n))
 	if trainN < 20 {
 		trainN = 20
@@ -332,13 +1093,27 @@ func splitTrainTest(times, feats, returns []float64, trainFrac float64) trainTes
 		return trainTestSplit{}
 	}
 
+	trainEnd, testStart := trainN, trainN
+	if purgeMs > 0 {
+		boundary := times[trainN]
+		for trainEnd > 0 && times[trainEnd-1]+float64(purgeMs) >= boundary {
+			trainEnd--
+		}
+	}
+	if embargoN > 0 {
+		testStart = min(trainN+embargoN, n)
+	}
+	if trainEnd == 0 || testStart == n {
+		return trainTestSplit{}
+	}
+
 	return trainTestSplit{
-		TrainF: feats[:trainN],
-		TrainR: returns[:trainN],
+		TrainF: feats[:trainEnd],
+		TrainR: returns[:trainEnd],
 
-		TestT: times[trainN:],
-		TestF: feats[trainN:],
-		TestR: returns[trainN:],
+		TestT: times[testStart:],
+		TestF: feats[testStart:],
+		TestR: returns[testStart:],
 	}
 }
 
@@ -350,27 +1125,177 @@ func Pearson(x, y []float64) float64 {
 	if n == 0 || n != len(y) {
 		return 0
 	}
-	var sx, sy, sxx, syy, sxy float64
+	// Two passes: means first, then centered sums. The one-pass form
+	// sxy - sx*sy/n cancels catastrophically when the means are large
+	// relative to the spread (e.g. price-level features). The centered
+	// sums are corrected by the rounding error left in the means (the
+	// residual sums dx, dy), and the norms are rooted separately so tiny
+	// or huge scales don't under- or overflow their product.
+	var mx, my float64
 	for i := 0; i < n; i++ {
-		xi := x[i]
-		yi := y[i]
-		sx += xi
-		sy += yi
-		sxx += xi * xi
-		syy += yi * yi
-		sxy += xi * yi
+		mx += x[i]
+		my += y[i]
 	}
 	nf := float64(n)
-	num := sxy - sx*sy/nf
-	denx := sxx - sx*sx/nf
-	deny := syy - sy*sy/nf
-	if denx <= 0 || deny <= 0 {
+	mx /= nf
+	my /= nf
+
+	var sx, sy, sxx, syy, sxy float64
+	for i := 0; i < n; i++ {
+		dx := x[i] - mx
+		dy := y[i] - my
+		sx += dx
+		sy += dy
+		sxx += dx * dx
+		syy += dy * dy
+		sxy += dx * dy
+	}
+	sxx -= sx * sx / nf
+	syy -= sy * sy / nf
+	sxy -= sx * sy / nf
+	if sxx <= 0 || syy <= 0 {
+		return 0
+	}
+	return max(-1, min(1, sxy/(math.Sqrt(sxx)*math.Sqrt(syy))))
+}
+
+// ICTStats returns the naive t-statistic of the Pearson IC between x and y,
+// IC*sqrt((n-2)/(1-IC^2)), and a Newey-West (HAC) version: the IC is the
+// mean of the products of the standardized series, and its standard error
+// comes from their Bartlett-weighted long-run variance over lag lags.
+func ICTStats(x, y []float64, lag int) (t, tHAC float64) {
+	n := len(x)
+	if n < 3 || n != len(y) {
+		return 0, 0
+	}
+	var mx, my float64
+	for i := 0; i < n; i++ {
+		mx += x[i]
+		my += y[i]
+	}
+	mx /= float64(n)
+	my /= float64(n)
+	var sxx, syy float64
+	for i := 0; i < n; i++ {
+		sxx += (x[i] - mx) * (x[i] - mx)
+		syy += (y[i] - my) * (y[i] - my)
+	}
+	if sxx <= 0 || syy <= 0 {
+		return 0, 0
+	}
+	sx := math.Sqrt(sxx / float64(n))
+	sy := math.Sqrt(syy / float64(n))
+
+	u := make([]float64, n)
+	var ic float64
+	for i := 0; i < n; i++ {
+		u[i] = (x[i] - mx) / sx * (y[i] - my) / sy
+		ic += u[i]
+	}
+	ic /= float64(n)
+
+	if ic*ic < 1 {
+		t = ic * math.Sqrt(float64(n-2)/(1-ic*ic))
+	}
+	if lrv := neweyWestLRV(u, lag); lrv > 0 {
+		tHAC = ic / math.Sqrt(lrv/float64(n))
+	}
+	return t, tHAC
+}
+
+const dayMillis = 24 * 60 * 60 * 1000.0
+
+// DailyICs splits time-sorted samples into UTC days and returns the Pearson
+// IC of each day with at least 20 samples.
+func DailyICs(times, feats, returns []float64) []float64 {
+	var out []float64
+	start := 0
+	for i := 1; i <= len(times); i++ {
+		if i < len(times) && math.Floor(times[i]/dayMillis) == math.Floor(times[start]/dayMillis) {
+			continue
+		}
+		if i-start >= 20 {
+			out = append(out, Pearson(feats[start:i], returns[start:i]))
+		}
+		start = i
+	}
+	return out
+}
+
+// meanTStat returns the mean of x and its t-statistic assuming independent
+// observations.
+func meanTStat(x []float64) (mean, t float64) {
+	n := len(x)
+	if n < 2 {
+		return 0, 0
+	}
+	for _, v := range x {
+		mean += v
+	}
+	mean /= float64(n)
+	var ss float64
+	for _, v := range x {
+		ss += (v - mean) * (v - mean)
+	}
+	sd := math.Sqrt(ss / float64(n-1))
+	if sd == 0 {
+		return mean, 0
+	}
+	return mean, mean / (sd / math.Sqrt(float64(n)))
+}
+
+// NeweyWestTStat is the t-statistic of the mean of series (e.g. daily ICs)
+// with a Newey-West standard error over lag lags, so serial correlation from
+// overlapping horizons doesn't inflate significance.
+func NeweyWestTStat(series []float64, lag int) float64 {
+	n := len(series)
+	if n < 2 {
 		return 0
 	}
-	return num / math.Sqrt(denx*deny)
+	var mean float64
+	for _, v := range series {
+		mean += v
+	}
+	mean /= float64(n)
+	lrv := neweyWestLRV(series, lag)
+	if lrv <= 0 {
+		return 0
+	}
+	return mean / math.Sqrt(lrv/float64(n))
+}
+
+// neweyWestLRV is the Newey-West long-run variance of u around its mean:
+// gamma_0 + 2*sum_{k=1..lag} (1 - k/(lag+1)) * gamma_k.
+func neweyWestLRV(u []float64, lag int) float64 {
+	n := len(u)
+	if n == 0 {
+		return 0
+	}
+	var mean float64
+	for _, v := range u {
+		mean += v
+	}
+	mean /= float64(n)
+
+	gamma := func(k int) float64 {
+		var g float64
+		for i := k; i < n; i++ {
+			g += (u[i] - mean) * (u[i-k] - mean)
+		}
+		return g / float64(n)
+	}
+	if lag >= n {
+		lag = n - 1
+	}
+	lrv := gamma(0)
+	for k := 1; k <= lag; k++ {
+		lrv += 2 * (1 - float64(k)/float64(lag+1)) * gamma(k)
+	}
+	return lrv
 }
 
-// Spearman rank correlation: Pearson over rank-transformed inputs.
+// Spearman rank correlation: Pearson over rank-transformed inputs. With
+// ties at their average rank this is the exact tie-corrected coefficient.
 func Spearman(x, y []float64) float64 {
 	n := len(x)
 	if n == 0 || n != len(y) {
@@ -382,36 +1307,339 @@ func Spearman(x, y []float64) float64 {
 	return Pearson(rx, ry)
 }
 
+// KendallTau returns Kendall's tau-b between x and y: concordant minus
+// discordant pairs, normalized with the tie corrections for both sides. It
+// uses Knight's O(n log n) algorithm: sort by (x, y), then count the
+// discordant pairs as the swaps of a merge sort on y.
+func KendallTau(x, y []float64) float64 {
+	n := len(x)
+	if n < 2 || n != len(y) {
+		return 0
+	}
+	type pair struct{ x, y float64 }
+	p := make([]pair, n)
+	for i := range p {
+		p[i] = pair{x[i], y[i]}
+	}
+	slices.SortFunc(p, func(a, b pair) int {
+		if c := cmp.Compare(a.x, b.x); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.y, b.y)
+	})
+
+	// tiedPairs counts pairs within runs of equal keys, given in order.
+	tiedPairs := func(same func(i int) bool) float64 {
+		var total, run float64
+		for i := 1; i < n; i++ {
+			if same(i) {
+				run++
+				total += run
+			} else {
+				run = 0
+			}
+		}
+		return total
+	}
+	tx := tiedPairs(func(i int) bool { return p[i].x == p[i-1].x })
+	txy := tiedPairs(func(i int) bool { return p[i].x == p[i-1].x && p[i].y == p[i-1].y })
+
+	ys := make([]float64, n)
+	for i := range p {
+		ys[i] = p[i].y
+	}
+	swaps := mergeCountSwaps(ys, make([]float64, n))
+	// ys is now sorted.
+	ty := tiedPairs(func(i int) bool { return ys[i] == ys[i-1] })
+
+	n0 := float64(n) * float64(n-1) / 2
+	den := (n0 - tx) * (n0 - ty)
+	if den <= 0 {
+		return 0
+	}
+	return (n0 - tx - ty + txy - 2*swaps) / math.Sqrt(den)
+}
+
+// mergeCountSwaps sorts a ascending (buf is scratch of the same length) and
+// returns the number of strictly inverted pairs, i.e. the adjacent swaps a
+// bubble sort would make.
+func mergeCountSwaps(a, buf []float64) float64 {
+	n := len(a)
+	if n < 2 {
+		return 0
+	}
+	mid := n / 2
+	swaps := mergeCountSwaps(a[:mid], buf[:mid]) + mergeCountSwaps(a[mid:], buf[mid:])
+	i, j, k := 0, mid, 0
+	for i < mid && j < n {
+		if a[j] < a[i] {
+			// a[j] jumps ahead of everything left in the first half.
+			buf[k] = a[j]
+			swaps += float64(mid - i)
+			j++
+		} else {
+			buf[k] = a[i]
+			i++
+		}
+		k++
+	}
+	k += copy(buf[k:], a[i:mid])
+	copy(buf[k:], a[j:])
+	copy(a, buf)
+	return swaps
+}
+
+// DistanceCorrelation returns Székely's distance correlation between x and
+// y: 0 iff they are independent (in the limit), 1 for a linear relation,
+// and unlike Pearson it also picks up U-shaped and threshold dependence.
+// This is the O(n²) V-statistic computed pairwise without storing the
+// distance matrices; inputs longer than DistCorrMaxSamples are thinned to
+// an evenly strided subsample of that size first.
+func DistanceCorrelation(x, y []float64) float64 {
+	n := len(x)
+	if n < 2 || n != len(y) {
+		return 0
+	}
+	if DistCorrMaxSamples > 1 && n > DistCorrMaxSamples {
+		xs, ys := make([]float64, DistCorrMaxSamples), make([]float64, DistCorrMaxSamples)
+		for i := range xs {
+			k := i * n / DistCorrMaxSamples
+			xs[i], ys[i] = x[k], y[k]
+		}
+		x, y, n = xs, ys, DistCorrMaxSamples
+	}
+
+	// With a_ij = |x_i - x_j|, row means a_i and grand mean a, the
+	// double-centered mean product is
+	//   (1/n²) Σ a_ij b_ij - (2/n) Σ a_i b_i + a·b,
+	// so one pass for the row sums and cross sums suffices.
+	rowA, rowB := make([]float64, n), make([]float64, n)
+	var sab, saa, sbb float64
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			a := math.Abs(x[i] - x[j])
+			b := math.Abs(y[i] - y[j])
+			rowA[i] += a
+			rowA[j] += a
+			rowB[i] += b
+			rowB[j] += b
+			sab += a * b
+			saa += a * a
+			sbb += b * b
+		}
+	}
+	nf := float64(n)
+	var ra, rb, rab, raa, rbb float64
+	for i := range rowA {
+		ai, bi := rowA[i]/nf, rowB[i]/nf
+		ra += ai
+		rb += bi
+		rab += ai * bi
+		raa += ai * ai
+		rbb += bi * bi
+	}
+	ma, mb := ra/nf, rb/nf
+	centered := func(sum, rowProd, m1, m2 float64) float64 {
+		return 2*sum/(nf*nf) - 2*rowProd/nf + m1*m2
+	}
+	dcov := centered(sab, rab, ma, mb)
+	dvarX := centered(saa, raa, ma, ma)
+	dvarY := centered(sbb, rbb, mb, mb)
+	if dvarX <= 0 || dvarY <= 0 || dcov <= 0 {
+		return 0
+	}
+	return math.Sqrt(dcov / math.Sqrt(dvarX*dvarY))
+}
+
+// FeatureRanks is the tail of one feature column from some index on,
+// sorted once, so its cells across horizons (the same samples, with test
+// segments starting at or after that index as the embargo grows) rank in
+// O(n) each instead of re-sorting. It is read-only once built and safe for
+// concurrent use; a nil *FeatureRanks ranks from scratch every time.
+type FeatureRanks struct {
+	from  int
+	tail  []float64 // full[from:]
+	order []int     // valueOrder(tail)
+}
+
+// NewFeatureRanks sorts full[from:], which must not change while the
+// result is in use.
+func NewFeatureRanks(full []float64, from int) *FeatureRanks {
+	tail := full[from:]
+	return &FeatureRanks{from: from, tail: tail, order: valueOrder(tail)}
+}
+
+// ranks returns the average ranks (1..n) of full[from:], from the stored
+// order when full is the column it was built on and from is no earlier.
+func (r *FeatureRanks) ranks(full []float64, from int) []float64 {
+	if r == nil || from < r.from || !slices.Equal(full[r.from:], r.tail) {
+		return rankify(full[from:])
+	}
+	return ranksFromOrder(r.tail, r.order, from-r.from)
+}
+
 // rankify converts values to average ranks (1..n). Ties get averaged ranks.
 func rankify(vals []float64) []float64 {
-	n := len(vals)
+	return ranksFromOrder(vals, valueOrder(vals), 0)
+}
+
+// valueOrder returns the indices of vals in ascending order of value.
+func valueOrder(vals []float64) []int {
 	type kv struct {
 		v float64
 		i int
 	}
-	tmp := make([]kv, n)
+	tmp := make([]kv, len(vals))
 	for i, v := range vals {
 		tmp[i] = kv{v: v, i: i}
 	}
-	sort.Slice(tmp, func(i, j int) bool { return tmp[i].v < tmp[j].v })
+	slices.SortFunc(tmp, func(a, b kv) int { return cmp.Compare(a.v, b.v) })
+
+	order := make([]int, len(vals))
+	for i, e := range tmp {
+		order[i] = e.i
+	}
+	return order
+}
 
-	ranks := make([]float64, n)
-	var i int
-	for i < n {
+// ranksFromOrder ranks vals[from:] given order = valueOrder(vals): entries
+// before from are skipped, ties share their average rank.
+func ranksFromOrder(vals []float64, order []int, from int) []float64 {
+	ranks := make([]float64, len(vals)-from)
+	assigned := 0
+	for i := 0; i < len(order); {
 		j := i + 1
-		for j < n && tmp[j].v == tmp[i].v {
+		for j < len(order) && vals[order[j]] == vals[order[i]] {
 			j++
 		}
-		// average rank of [i, j)
-		rank := 0.5*float64(i+j-1) + 1.0
-		for k := i; k < j; k++ {
-			ranks[tmp[k].i] = rank
+		// The tie group [i, j) takes ranks assigned+1 .. assigned+cnt.
+		cnt := 0
+		for _, k := range order[i:j] {
+			if k >= from {
+				cnt++
+			}
+		}
+		rank := float64(assigned) + 0.5*float64(cnt+1)
+		for _, k := range order[i:j] {
+			if k >= from {
+				ranks[k-from] = rank
+			}
 		}
+		assigned += cnt
 		i = j
 	}
 	return ranks
 }
 
+// uniformize maps values onto (0,1) by average rank: (rank-0.5)/n.
+// Any strictly monotone transform of vals gives the same output.
+func uniformize(vals []float64) []float64 {
+	u := rankify(vals)
+	nf := float64(len(vals))
+	for i := range u {
+		u[i] = (u[i] - 0.5) / nf
+	}
+	return u
+}
+
+// uniformizeTrainTest rank-normalizes train in-sample and maps test through
+// the train ECDF, so the test transform uses no test-period information.
+func uniformizeTrainTest(train, test []float64) (trainU, testU []float64) {
+	trainU = uniformize(train)
+
+	ref := make([]float64, len(train))
+	copy(ref, train)
+	sort.Float64s(ref)
+
+	testU = make([]float64, len(test))
+	nf := float64(len(ref))
+	if nf == 0 {
+		return trainU, testU
+	}
+	for i, x := range test {
+		lo := sort.SearchFloat64s(ref, x)
+		hi := lo
+		for hi < len(ref) && ref[hi] == x {
+			hi++
+		}
+		// Same convention as uniformize: ties share their average position.
+		testU[i] = float64(lo+hi) / (2 * nf)
+	}
+	return trainU, testU
+}
+
+// ---------------------- IC decay ----------------------
+
+// ICDecayRow is one model's IC term structure across horizons.
+type ICDecayRow struct {
+	Peak int // index of the largest |IC|; -1 if every IC is 0
+	// HalfLifeMs is the horizon at which IC falls to half its peak, 0 if it
+	// never does. Extrapolated marks one read off the fitted exponential
+	// rather than between two measured horizons.
+	HalfLifeMs   float64
+	Extrapolated bool
+}
+
+// ICDecay summarizes the ICs already measured at horizonsMs (ascending). The
+// half-life is interpolated exponentially between the first pair of
+// horizons after the peak that brackets half the peak IC (linearly if the
+// IC changes sign there). If no measured horizon gets that low, an
+// exponential |IC| = exp(a - λ·h) is fit by least squares to the peak and
+// later points, and the half-life is where that fit reaches half the peak;
+// a non-decaying fit leaves it 0.
+func ICDecay(horizonsMs []int64, ics []float64) ICDecayRow {
+	row := ICDecayRow{Peak: -1}
+	for i, ic := range ics {
+		if ic != 0 && (row.Peak < 0 || math.Abs(ic) > math.Abs(ics[row.Peak])) {
+			row.Peak = i
+		}
+	}
+	if row.Peak < 0 {
+		return row
+	}
+
+	// Signed toward the peak, so a sign flip counts as below half.
+	sgn := math.Copysign(1, ics[row.Peak])
+	half := math.Abs(ics[row.Peak]) / 2
+	for k := row.Peak + 1; k < len(ics); k++ {
+		v0, v := sgn*ics[k-1], sgn*ics[k]
+		if v > half {
+			continue
+		}
+		frac := (v0 - half) / (v0 - v)
+		if v > 0 {
+			frac = math.Log(v0/half) / math.Log(v0/v)
+		}
+		h0, h1 := float64(horizonsMs[k-1]), float64(horizonsMs[k])
+		row.HalfLifeMs = h0 + frac*(h1-h0)
+		return row
+	}
+
+	// No crossing: every later IC is above half the peak, so all are > 0.
+	n := float64(len(ics) - row.Peak)
+	if n < 2 {
+		return row
+	}
+	var sx, sy, sxx, sxy float64
+	for k := row.Peak; k < len(ics); k++ {
+		x, y := float64(horizonsMs[k]), math.Log(sgn*ics[k])
+		sx += x
+		sy += y
+		sxx += x * x
+		sxy += x * y
+	}
+	slope := (n*sxy - sx*sy) / (n*sxx - sx*sx)
+	if !(slope < 0) {
+		return row
+	}
+	intercept := (sy - slope*sx) / n
+	// The measured horizons are all above half, so the fit's crossing can't
+	// honestly come before the last of them.
+	row.HalfLifeMs = max((math.Log(half)-intercept)/slope, float64(horizonsMs[len(ics)-1]))
+	row.Extrapolated = true
+	return row
+}
+
 // ---------------------- Hit rate / sign accuracy ----------------------
 
 // HitRateStats computes:
@@ -452,16 +1680,40 @@ func HitRateStats(signal, ret []float64) (hitRate, z float64) {
 
 // ---------------------- Decile curve ----------------------
 
-// DecileCurve builds a conditional return curve by signal decile.
-// Returns:
-//
-//	decMeans[10]       - average raw return per decile
-//	bottomBps, topBps  - decile 0 and 9 in basis points
-//	spreadBps          - top - bottom in basis points
-func DecileCurve(signal, ret []float64) (decMeans []float64, bottomBps, topBps, spreadBps float64) {
+// CondCurve is a conditional return curve: the test returns bucketed by
+// equal-count signal quantiles, lowest signal first.
+type CondCurve struct {
+	Means   []float64 // average raw return per bucket
+	Counts  []int
+	StdErrs []float64 // standard error of each mean; 0 below two points
+	// Bottom and top bucket means and their difference, in basis points.
+	BottomBps, TopBps, SpreadBps float64
+}
+
+// Thin counts the buckets holding fewer than minCount points.
+func (c CondCurve) Thin(minCount int) int {
+	thin := 0
+	for _, n := range c.Counts {
+		if n < minCount {
+			thin++
+		}
+	}
+	return thin
+}
+
+// ConditionalCurve builds a conditional return curve over buckets equal-count
+// signal quantiles (buckets >= 1). With fewer points than buckets everything
+// lands in bucket 0 and the spread is 0.
+func ConditionalCurve(signal, ret []float64, buckets int) CondCurve {
+	buckets = max(buckets, 1)
+	c := CondCurve{
+		Means:   make([]float64, buckets),
+		Counts:  make([]int, buckets),
+		StdErrs: make([]float64, buckets),
+	}
 	n := len(signal)
 	if n == 0 || n != len(ret) {
-		return make([]float64, 10), 0, 0, 0
+		return c
 	}
 
 	type pair struct {
@@ -474,67 +1726,93 @@ func DecileCurve(signal, ret []float64) (decMeans []float64, bottomBps, topBps,
 	}
 	sort.Slice(data, func(i, j int) bool { return data[i].s < data[j].s })
 
-	decMeans = make([]float64, 10)
-	counts := make([]int, 10)
-	if n < 10 {
-		// not enough to split meaningfully
-		for i := range data {
-			decMeans[0] += data[i].r
-			counts[0]++
-		}
-		if counts[0] > 0 {
-			decMeans[0] /= float64(counts[0])
+	bucketOf := func(i int) int {
+		if n < buckets {
+			// not enough to split meaningfully
+			return 0
 		}
-		return decMeans, decMeans[0] * 1e4, decMeans[0] * 1e4, 0
+		return min(int(float64(i)/float64(n)*float64(buckets)), buckets-1)
 	}
-
-	for i := 0; i < n; i++ {
-		dec := int(float64(i) / float64(n) * 10.0)
-		if dec == 10 {
-			dec = 9
+	for i := range data {
+		b := bucketOf(i)
+		c.Means[b] += data[i].r
+		c.Counts[b]++
+	}
+	for b := range c.Means {
+		if c.Counts[b] > 0 {
+			c.Means[b] /= float64(c.Counts[b])
 		}
-		decMeans[dec] += data[i].r
-		counts[dec]++
 	}
-	for d := 0; d < 10; d++ {
-		if counts[d] > 0 {
-			decMeans[d] /= float64(counts[d])
+	sumSq := make([]float64, buckets)
+	for i := range data {
+		b := bucketOf(i)
+		d := data[i].r - c.Means[b]
+		sumSq[b] += d * d
+	}
+	for b, k := range c.Counts {
+		if k > 1 {
+			c.StdErrs[b] = math.Sqrt(sumSq[b] / float64(k-1) / float64(k))
 		}
 	}
-	bottom := decMeans[0]
-	top := decMeans[9]
-	bottomBps = bottom * 1e4
-	topBps = top * 1e4
-	spreadBps = (top - bottom) * 1e4
-	return decMeans, bottomBps, topBps, spreadBps
+
+	if n < buckets {
+		c.BottomBps, c.TopBps = c.Means[0]*1e4, c.Means[0]*1e4
+		return c
+	}
+	bottom, top := c.Means[0], c.Means[buckets-1]
+	c.BottomBps = bottom * 1e4
+	c.TopBps = top * 1e4
+	c.SpreadBps = (top - bottom) * 1e4
+	return c
+}
+
+// DecileCurve is ConditionalCurve with 10 buckets.
+// Returns:
+//
+//	decMeans[10]       - average raw return per decile
+//	bottomBps, topBps  - decile 0 and 9 in basis points
+//	spreadBps          - top - bottom in basis points
+func DecileCurve(signal, ret []float64) (decMeans []float64, bottomBps, topBps, spreadBps float64) {
+	c := ConditionalCurve(signal, ret, 10)
+	return c.Means, c.BottomBps, c.TopBps, c.SpreadBps
 }
 
 // ---------------------- Mutual information ----------------------
 
 // CalcMutualInfo estimates MI(signal, return) in bits using a simple
-// equal-frequency binning scheme. Bins must be >= 2.
+// equal-frequency binning scheme with bins per margin. Bins < 1 picks each
+// margin's count with autoBins; otherwise bins must be >= 2.
+//
+// With MIBiasCorrection the entropies get the Miller-Madow correction
+// (m-1)/2n, m = occupied bins, which removes the plug-in estimator's upward
+// bias; the corrected MI of independent inputs scatters around 0 and can
+// come out slightly negative.
 func CalcMutualInfo(signal, ret []float64, bins int) (miBits, nmi float64) {
 	n := len(signal)
-	if n == 0 || n != len(ret) || bins < 2 {
+	if n == 0 || n != len(ret) || bins == 1 {
 		return 0, 0
 	}
+	sBins, rBins := bins, bins
+	if bins < 1 {
+		sBins, rBins = autoBins(signal), autoBins(ret)
+	}
 
 	// Quantile-based bins for signal and returns separately.
-	sBin := quantileBins(signal, bins)
-	rBin := quantileBins(ret, bins)
+	sBin := quantileBins(signal, sBins)
+	rBin := quantileBins(ret, rBins)
 
 	// Joint and marginals.
-	joint := make([][]float64, bins)
+	joint := make([][]float64, sBins)
 	for i := range joint {
-		joint[i] = make([]float64, bins)
+		joint[i] = make([]float64, rBins)
 	}
-	margS := make([]float64, bins)
-	margR := make([]float64, bins)
+	margS := make([]float64, sBins)
+	margR := make([]float64, rBins)
 
 	for i := 0; i < n; i++ {
 		sb := sBin[i]
 		rb := rBin[i]
-		if sb < 0 || sb >= bins || rb < 0 || rb >= bins {
+		if sb < 0 || sb >= sBins || rb < 0 || rb >= rBins {
 			continue
 		}
 		joint[sb][rb]++
@@ -543,22 +1821,27 @@ func CalcMutualInfo(signal, ret []float64, bins int) (miBits, nmi float64) {
 	}
 
 	nf := float64(n)
-	for i := 0; i < bins; i++ {
+	for i := range margS {
 		margS[i] /= nf
-		margR[i] /= nf
-		for j := 0; j < bins; j++ {
+	}
+	for j := range margR {
+		margR[j] /= nf
+	}
+	for i := range joint {
+		for j := range joint[i] {
 			joint[i][j] /= nf
 		}
 	}
 
 	// Mutual information in bits.
 	var mi float64
-	for i := 0; i < bins; i++ {
-		for j := 0; j < bins; j++ {
-			p := joint[i][j]
+	occupied := 0
+	for i := range joint {
+		for j, p := range joint[i] {
 			if p <= 0 {
 				continue
 			}
+			occupied++
 			px := margS[i]
 			py := margR[j]
 			if px <= 0 || py <= 0 {
@@ -569,12 +1852,12 @@ func CalcMutualInfo(signal, ret []float64, bins int) (miBits, nmi float64) {
 	}
 
 	// Entropy of Y (returns).
-	var hy float64
-	for j := 0; j < bins; j++ {
-		p := margR[j]
-		if p > 0 {
-			hy -= p * math.Log2(p)
-		}
+	hy := entropyBits(margR)
+	if MIBiasCorrection {
+		// MI = H(X) + H(Y) - H(X,Y), so the corrections combine.
+		mS, mR := occupiedBins(margS), occupiedBins(margR)
+		mi += float64(mS+mR-occupied-1) / (2 * nf * math.Ln2)
+		hy += float64(mR-1) / (2 * nf * math.Ln2)
 	}
 	if hy > 0 {
 		nmi = mi / hy
@@ -582,6 +1865,47 @@ func CalcMutualInfo(signal, ret []float64, bins int) (miBits, nmi float64) {
 	return mi, nmi
 }
 
+func entropyBits(probs []float64) float64 {
+	var h float64
+	for _, p := range probs {
+		if p > 0 {
+			h -= p * math.Log2(p)
+		}
+	}
+	return h
+}
+
+func occupiedBins(probs []float64) int {
+	m := 0
+	for _, p := range probs {
+		if p > 0 {
+			m++
+		}
+	}
+	return m
+}
+
+// autoBins is the Freedman-Diaconis bin count for vals, (max-min) /
+// (2·IQR·n^(-1/3)), capped at sqrt(n/20) so the joint MI histogram keeps
+// about 20 expected points per cell (Miller-Madow under-corrects sparser
+// tables), and floored at 2.
+func autoBins(vals []float64) int {
+	n := len(vals)
+	limit := max(int(math.Sqrt(float64(n)/20)), 2)
+	if n < 4 {
+		return 2
+	}
+	sorted := slices.Clone(vals)
+	slices.Sort(sorted)
+	iqr := sorted[3*n/4] - sorted[n/4]
+	if iqr <= 0 {
+		return limit
+	}
+	width := 2 * iqr / math.Cbrt(float64(n))
+	fd := math.Ceil((sorted[n-1] - sorted[0]) / width)
+	return int(max(min(fd, float64(limit)), 2))
+}
+
 // quantileBins assigns each value to a [0,bins) bin with equal counts as much
 // as possible.
 func quantileBins(vals []float64, bins int) []int {
@@ -618,6 +1942,14 @@ func quantileBins(vals []float64, bins int) []int {
 //
 // and compares its log-loss on test vs a constant-probability baseline.
 func LogLossImprovementTrainTest(trainF, trainR, testF, testR []float64) (baseLL, signalLL, delta float64) {
+	baseLL, signalLL, delta, _, _ = logLossImprovementFrom(trainF, trainR, testF, testR, 0, 0)
+	return baseLL, signalLL, delta
+}
+
+// logLossImprovementFrom is LogLossImprovementTrainTest with the logistic
+// fit warm-started at (a0, b0); it also returns the fitted parameters so a
+// caller refitting on a growing window can chain them.
+func logLossImprovementFrom(trainF, trainR, testF, testR []float64, a0, b0 float64) (baseLL, signalLL, delta, a, b float64) {
 	// Convert returns to binary labels: y = 1 if r > 0 else 0.
 	toLabels := func(r []float64) []float64 {
 		y := make([]float64, len(r))
@@ -634,7 +1966,7 @@ func LogLossImprovementTrainTest(trainF, trainR, testF, testR []float64) (baseLL
 	yTest := toLabels(testR)
 
 	if len(yTrain) == 0 || len(yTest) == 0 {
-		return 0, 0, 0
+		return 0, 0, 0, a0, b0
 	}
 
 	// Baseline: constant probability = mean of test labels.
@@ -652,11 +1984,11 @@ func LogLossImprovementTrainTest(trainF, trainR, testF, testR []float64) (baseLL
 	baseLL = avgLogLossConst(yTest, p0)
 
 	// Fit 1D logistic regression on train.
-	a, b := fitLogistic1D(trainF, yTrain)
+	a, b, _ = fitLogistic1DFrom(trainF, yTrain, a0, b0)
 	signalLL = avgLogLossLogistic(testF, yTest, a, b)
 
 	delta = baseLL - signalLL
-	return baseLL, signalLL, delta
+	return baseLL, signalLL, delta, a, b
 }
 
 func avgLogLossConst(y []float64, p float64) float64 {
@@ -704,9 +2036,17 @@ func avgLogLossLogistic(f, y []float64, a, b float64) float64 {
 // fitLogistic1D does a crude Newton-Raphson fit for a 1D logistic model.
 // It is intentionally simple; we're not trying to be perfect here.
 func fitLogistic1D(f, y []float64) (a, b float64) {
+	a, b, _ = fitLogistic1DFrom(f, y, 0, 0)
+	return a, b
+}
+
+// fitLogistic1DFrom is fitLogistic1D started from (a0, b0) in the original
+// feature scale, e.g. the previous fold's solution in a walk-forward run.
+// It also returns the number of Newton iterations used.
+func fitLogistic1DFrom(f, y []float64, a0, b0 float64) (a, b float64, iters int) {
 	n := len(f)
 	if n == 0 || n != len(y) {
-		return 0, 0
+		return 0, 0, 0
 	}
 
 	// Standardize features to improve conditioning.
@@ -732,11 +2072,13 @@ func fitLogistic1D(f, y []float64) (a, b float64) {
 		fn[i] = (f[i] - meanF) / stdF
 	}
 
-	// Initialize params.
-	a, b = 0.0, 0.0
-	const iters = 25
+	// Initialize params in standardized space:
+	//   a0 + b0*f = (a0 + b0*meanF) + (b0*stdF) * fn
+	a, b = a0+b0*meanF, b0*stdF
+	const maxIters = 25
 
-	for iter := 0; iter < iters; iter++ {
+	for iters < maxIters {
+		iters++
 		var g0, g1, h00, h01, h11 float64
 		for i := 0; i < n; i++ {
 			z := a + b*fn[i]
@@ -773,46 +2115,134 @@ func fitLogistic1D(f, y []float64) (a, b float64) {
 	// so newA = a - b*meanF/stdF, newB = b/stdF.
 	newA := a - b*meanF/stdF
 	newB := b / stdF
-	return newA, newB
+	return newA, newB, iters
 }
 
 // ---------------------- Strategy risk / Sharpe ----------------------
 
+// volTargetScale returns the position multiplier that brings ret to a
+// per-sample volatility of VolTarget, or 1 when vol targeting is off.
+//
+// The scale is constant over the segment and uses the segment's own realized
+// vol (ex-post). That's fine for its purpose, which is putting drawdowns and
+// per-trade PnL of different symbols on a common footing, not for sizing.
+func volTargetScale(ret []float64) float64 {
+	if VolTarget <= 0 || len(ret) < 2 {
+		return 1
+	}
+	var mean, m2 float64
+	for _, r := range ret {
+		mean += r
+	}
+	mean /= float64(len(ret))
+	for _, r := range ret {
+		d := r - mean
+		m2 += d * d
+	}
+	std := math.Sqrt(m2 / float64(len(ret)))
+	if std <= 0 {
+		return 1
+	}
+	return VolTarget / std
+}
+
+// spacingProbe caps the gaps sampleSpacingMs takes the median of.
+const spacingProbe = 10000
+
+// sampleSpacingMs is the realized spacing of sorted sample times in ms: the
+// median gap between consecutive samples, over at most spacingProbe evenly
+// strided gaps. The trade and volume clocks have no fixed step, and the time
+// clock skips stretches without trades, so overlap is measured rather than
+// read off SamplingRateSec. The median ignores the overnight and missing-day
+// gaps. Returns 0 (SamplingRateSec, to labelOverlap) without a positive gap.
+func sampleSpacingMs(times []float64) float64 {
+	n := len(times) - 1
+	if n < 1 {
+		return 0
+	}
+	stride := max(n/spacingProbe, 1)
+	gaps := make([]float64, 0, n/stride+1)
+	for i := 0; i < n; i += stride {
+		gaps = append(gaps, times[i+1]-times[i])
+	}
+	slices.Sort(gaps)
+	if m := gaps[len(gaps)/2]; m > 0 {
+		return m
+	}
+	return 0
+}
+
+// annualFactor is the number of observations per 365-day year when samples
+// are spacingSec apart. Crypto trades around the clock, so no trading-day
+// calendar applies.
+func annualFactor(spacingSec float64) float64 {
+	if spacingSec <= 0 {
+		return 0
+	}
+	return 365 * 24 * 3600 / spacingSec
+}
+
+// RiskStats is the risk profile of a sign(signal) strategy, see
+// StrategyRiskStats.
+type RiskStats struct {
+	Sharpe        float64 // per trade
+	MaxDrawdown   float64 // fraction of the peak in [0,1] (summed returns under AdditiveDrawdown)
+	MaxDDDuration int     // longest run of curve trades below the prior peak
+	AvgUnderwater float64 // mean length of such runs
+
+	AvgTrade, AvgWin, AvgLoss float64
+	WinLossRatio              float64 // |AvgWin / AvgLoss|
+	Skew, Kurtosis            float64 // of the trades; Kurtosis is raw, 3 for a normal
+}
+
 // StrategyRiskStats computes returns of a naive sign(signal) strategy:
 //
-//	r_strat = sign(signal) * return
+//	r_strat = sign(signal) * return * volTargetScale(return)
+//
+// and then Sharpe, max drawdown, and simple trade stats over every trade
+// (see RiskStats).
 //
-// and then Sharpe, max drawdown, and simple trade stats.
-func StrategyRiskStats(signal, ret []float64) (sharpe, maxDD, avgTrade, avgWin, avgLoss, winLoss float64) {
+// Consecutive labels overlap (a 1h label every minute shares 59 minutes
+// with the next), so the equity curve holds one position at a time: a trade
+// joins it only once the previous one's label, horizonMs after its time,
+// has closed. The drawdown is the largest peak-to-trough loss of that
+// compounded curve prod(exp(r_strat)), as a fraction of the peak.
+//
+// The drawdown durations are counted in (non-overlapping) curve trades; a
+// run still open at the last trade counts at its length so far.
+func StrategyRiskStats(times, signal, ret []float64, horizonMs int64) RiskStats {
 	n := len(signal)
-	if n == 0 || n != len(ret) {
-		return 0, 0, 0, 0, 0, 0
+	if n == 0 || n != len(ret) || n != len(times) {
+		return RiskStats{}
 	}
+	scale := volTargetScale(ret)
 
-	var trades []float64
-	trades = trades[:0]
-
+	var trades, curve []float64
+	nextT := math.Inf(-1)
 	for i := 0; i < n; i++ {
 		s := signal[i]
 		r := ret[i]
 		if s == 0 || r == 0 {
 			continue
 		}
-		var sign float64
-		if s > 0 {
-			sign = 1
-		} else {
-			sign = -1
+		pos := scale
+		if s < 0 {
+			pos = -scale
+		}
+		trades = append(trades, pos*r)
+		if times[i] >= nextT {
+			curve = append(curve, pos*r)
+			nextT = times[i] + float64(horizonMs)
 		}
-		trades = append(trades, sign*r)
 	}
 
 	m := len(trades)
 	if m == 0 {
-		return 0, 0, 0, 0, 0, 0
+		return RiskStats{}
 	}
 
 	// Basic stats.
+	var sharpe, skew, kurt float64
 	var mean, m2 float64
 	for _, x := range trades {
 		mean += x
@@ -828,16 +2258,24 @@ func StrategyRiskStats(signal, ret []float64) (sharpe, maxDD, avgTrade, avgWin,
 		sharpe = mean / std
 	}
 
-	avgTrade = mean
+	// Standardized 3rd/4th moments (kurt is raw, 3 for a normal).
+	kurt = 3
+	if std > 0 {
+		var m3, m4 float64
+		for _, x := range trades {
+			d := (x - mean) / std
+			d2 := d * d
+			m3 += d2 * d
+			m4 += d2 * d2
+		}
+		skew = m3 / float64(m)
+		kurt = m4 / float64(m)
+	}
 
 	// Win/loss stats + max drawdown
 	var winSum, lossSum float64
 	var winCount, lossCount int
 
-	equity := 0.0
-	peak := 0.0
-	maxDrawdown := 0.0
-
 	for _, x := range trades {
 		if x > 0 {
 			winSum += x
@@ -846,27 +2284,260 @@ func StrategyRiskStats(signal, ret []float64) (sharpe, maxDD, avgTrade, avgWin,
 			lossSum += x
 			lossCount++
 		}
+	}
 
-		equity += x
-		if equity > peak {
-			peak = equity
+	// Compounded equity with drawdown as a fraction of the running peak;
+	// AdditiveDrawdown keeps the old summed-returns curve for comparison.
+	// Trades are log returns, so compounding is exp(x).
+	equity := 1.0
+	if AdditiveDrawdown {
+		equity = 0
+	}
+	peak := equity
+	maxDrawdown := 0.0
+	run, runs, runTotal, ddDur := 0, 0, 0, 0
+
+	for _, x := range curve {
+		var dd float64
+		if AdditiveDrawdown {
+			equity += x
+			peak = math.Max(peak, equity)
+			dd = equity - peak
+		} else {
+			equity *= math.Exp(x)
+			peak = math.Max(peak, equity)
+			dd = (equity - peak) / peak
 		}
-		dd := equity - peak
 		if dd < maxDrawdown {
 			maxDrawdown = dd
 		}
-	}
 
+		// A run starts at the first trade below the peak and ends at the
+		// trade that makes a new one.
+		if dd < 0 {
+			if run == 0 {
+				runs++
+			}
+			run++
+			runTotal++
+			ddDur = max(ddDur, run)
+		} else {
+			run = 0
+		}
+	}
+	rs := RiskStats{
+		Sharpe:        sharpe,
+		MaxDrawdown:   -maxDrawdown, // maxDrawdown is negative
+		MaxDDDuration: ddDur,
+		AvgTrade:      mean,
+		Skew:          skew,
+		Kurtosis:      kurt,
+	}
+	if runs > 0 {
+		rs.AvgUnderwater = float64(runTotal) / float64(runs)
+	}
 	if winCount > 0 {
-		avgWin = winSum / float64(winCount)
+		rs.AvgWin = winSum / float64(winCount)
 	}
 	if lossCount > 0 {
-		avgLoss = lossSum / float64(lossCount)
+		rs.AvgLoss = lossSum / float64(lossCount)
 	}
-	if avgLoss != 0 {
-		winLoss = math.Abs(avgWin / avgLoss)
+	if rs.AvgLoss != 0 {
+		rs.WinLossRatio = math.Abs(rs.AvgWin / rs.AvgLoss)
+	}
+	return rs
+}
+
+// NetStrategyStats runs the same sign(signal) strategy as StrategyRiskStats
+// over the same non-overlapping curve trades (a trade joins only once the
+// previous one's label, horizonMs after its time, has closed) and charges
+// costBps (in bps of notional) every time the position flips sign versus the
+// previous curve trade. Flips between overlapping trades would charge for
+// positions a one-at-a-time book never takes. Returns the gross and net
+// Sharpe per curve trade, both over the same trades so their gap is the
+// cost alone, the number of flips, and turnover as flips per curve trade.
+func NetStrategyStats(times, signal, ret []float64, horizonMs int64, costBps float64) (grossSharpe, netSharpe float64, flips int, turnover float64) {
+	n := len(signal)
+	if n == 0 || n != len(ret) || n != len(times) {
+		return 0, 0, 0, 0
 	}
+	scale := volTargetScale(ret)
+	cost := costBps * 1e-4 * scale
+
+	var grossSum, grossSumSq, sum, sumSq float64
+	var m int
+	var prev float64
+	nextT := math.Inf(-1)
+	for i := 0; i < n; i++ {
+		s := signal[i]
+		r := ret[i]
+		if s == 0 || r == 0 || times[i] < nextT {
+			continue
+		}
+		nextT = times[i] + float64(horizonMs)
+		pos := scale
+		if s < 0 {
+			pos = -scale
+		}
+		x := pos * r
+		grossSum += x
+		grossSumSq += x * x
+		if prev != 0 && pos != prev {
+			x -= cost
+			flips++
+		}
+		prev = pos
+
+		sum += x
+		sumSq += x * x
+		m++
+	}
+	if m == 0 {
+		return 0, 0, 0, 0
+	}
+
+	sharpe := func(sum, sumSq float64) float64 {
+		mean := sum / float64(m)
+		if variance := sumSq/float64(m) - mean*mean; variance > 0 {
+			return mean / math.Sqrt(variance)
+		}
+		return 0
+	}
+	return sharpe(grossSum, grossSumSq), sharpe(sum, sumSq), flips, float64(flips) / float64(m)
+}
+
+// BootstrapCI returns 95% percentile intervals for the Pearson IC and the
+// sign(signal) Sharpe using the stationary bootstrap of Politis & Romano:
+// resamples are built from blocks of geometric length with the given mean,
+// wrapping around the end, so serial correlation within a block survives.
+// The generator is seeded from n, so a report is reproducible.
+func BootstrapCI(signal, ret []float64, resamples int, meanBlock float64) (icLo, icHi, shLo, shHi float64) {
+	n := len(signal)
+	if n < 2 || n != len(ret) || resamples <= 0 {
+		return 0, 0, 0, 0
+	}
+	if meanBlock < 1 {
+		meanBlock = 1
+	}
+	pNew := 1 / meanBlock
+
+	rng := rand.New(rand.NewPCG(uint64(n), 0x9e3779b97f4a7c15))
+	bf := make([]float64, n)
+	br := make([]float64, n)
+	ics := make([]float64, resamples)
+	shs := make([]float64, resamples)
+
+	for b := 0; b < resamples; b++ {
+		j := rng.IntN(n)
+		for i := 0; i < n; i++ {
+			if i > 0 {
+				if rng.Float64() < pNew {
+					j = rng.IntN(n)
+				} else if j++; j == n {
+					j = 0
+				}
+			}
+			bf[i] = signal[j]
+			br[i] = ret[j]
+		}
+		ics[b] = Pearson(bf, br)
+		shs[b] = signSharpe(bf, br)
+	}
+
+	sort.Float64s(ics)
+	sort.Float64s(shs)
+	lo := int(0.025 * float64(resamples-1))
+	hi := int(math.Ceil(0.975 * float64(resamples-1)))
+	return ics[lo], ics[hi], shs[lo], shs[hi]
+}
+
+// signSharpe is the per-trade Sharpe of StrategyRiskStats without the rest of
+// the risk profile; vol scaling cancels out of the ratio.
+func signSharpe(signal, ret []float64) float64 {
+	var sum, sumSq float64
+	var m int
+	for i, s := range signal {
+		r := ret[i]
+		if s == 0 || r == 0 {
+			continue
+		}
+		x := r
+		if s < 0 {
+			x = -r
+		}
+		sum += x
+		sumSq += x * x
+		m++
+	}
+	if m == 0 {
+		return 0
+	}
+	mean := sum / float64(m)
+	variance := sumSq/float64(m) - mean*mean
+	if variance <= 0 {
+		return 0
+	}
+	return mean / math.Sqrt(variance)
+}
+
+// DeflatedSharpe is the Deflated Sharpe Ratio of Bailey & López de Prado
+// (2014): the probability that the true Sharpe exceeds sr0, the Sharpe the
+// best of nTrials skill-less strategies would show by luck given the
+// variance trialVar of Sharpes observed across those trials. skew and kurt
+// (raw) are the moments of the trade returns behind sharpe, and t the
+// number of independent observations.
+func DeflatedSharpe(sharpe, skew, kurt, t float64, nTrials int, trialVar float64) (dsr, sr0 float64) {
+	if nTrials > 1 && trialVar > 0 {
+		const eulerGamma = 0.5772156649015329
+		n := float64(nTrials)
+		sr0 = math.Sqrt(trialVar) * ((1-eulerGamma)*normInv(1-1/n) + eulerGamma*normInv(1-1/(n*math.E)))
+	}
+	if t <= 1 {
+		return 0, sr0
+	}
+	den := 1 - skew*sharpe + (kurt-1)/4*sharpe*sharpe
+	if den <= 0 {
+		return 0, sr0
+	}
+	return normCDF((sharpe - sr0) * math.Sqrt(t-1) / math.Sqrt(den)), sr0
+}
+
+// BenjaminiHochberg returns the Benjamini-Hochberg adjusted p-values
+// (q-values) of p: q_(i) = min over j >= i of p_(j)·m/j, in p's order.
+// Rejecting every hypothesis with q <= α controls the false discovery rate
+// at α.
+func BenjaminiHochberg(p []float64) []float64 {
+	m := len(p)
+	order := make([]int, m)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return p[order[a]] < p[order[b]] })
+
+	q := make([]float64, m)
+	running := 1.0
+	for k := m - 1; k >= 0; k-- {
+		running = math.Min(running, p[order[k]]*float64(m)/float64(k+1))
+		q[order[k]] = running
+	}
+	return q
+}
+
+// twoSidedP is the two-sided normal p-value of a t-stat.
+func twoSidedP(t float64) float64 {
+	return math.Erfc(math.Abs(t) / math.Sqrt2)
+}
+
+// FDRPass reports whether the cell's IC is significant at FDRLevel after
+// the report's Benjamini-Hochberg adjustment (ICQValue).
+func (s ReportStats) FDRPass() bool {
+	return s.TestCount > 0 && !s.Suppressed && s.ICQValue <= FDRLevel
+}
+
+func normCDF(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt2)
+}
 
-	// maxDrawdown is negative; return positive magnitude.
-	return sharpe, -maxDrawdown, avgTrade, avgWin, avgLoss, winLoss
+func normInv(p float64) float64 {
+	return math.Sqrt2 * math.Erfinv(2*p-1)
 }
@@ -0,0 +1,764 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestLabelOverlapUsesRealizedSpacing(t *testing.T) {
+	// Samples 15s apart, e.g. a trade clock on a busy symbol, with an
+	// overnight gap the median ignores.
+	var times []float64
+	for i := range 1000 {
+		times = append(times, float64(i*15_000))
+	}
+	times = append(times, times[len(times)-1]+8*3600_000)
+	for range 1000 {
+		times = append(times, times[len(times)-1]+15_000)
+	}
+
+	spacing := sampleSpacingMs(times)
+	if spacing != 15_000 {
+		t.Fatalf("spacing = %v, want 15000", spacing)
+	}
+	if got := labelOverlap(3600_000, spacing); got != 240 {
+		t.Fatalf("1h overlap at 15s = %v, want 240", got)
+	}
+	// No samples: the time clock's nominal step.
+	if got, want := labelOverlap(3600_000, sampleSpacingMs(nil)), 3600.0/SamplingRateSec; math.Abs(got-want) > 1e-12 {
+		t.Fatalf("fallback overlap = %v, want %v", got, want)
+	}
+}
+
+// TestSuppressesLowESSCells checks that a long horizon whose overlapping
+// labels leave too few independent test samples is suppressed, while a
+// short horizon on the same raw samples is reported.
+func TestSuppressesLowESSCells(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	const n = 2000 // 600 test samples, 60s apart
+	times := make([]float64, n)
+	feats := make([]float64, n)
+	rets := make([]float64, n)
+	for i := range n {
+		times[i] = float64(i * 60_000)
+		feats[i] = rng.NormFloat64()
+		rets[i] = 0.5*feats[i] + rng.NormFloat64()
+	}
+
+	short := AnalyzeFullSuiteOOS(times, feats, rets, 0.7, 60_000)
+	long := AnalyzeFullSuiteOOS(times, feats, rets, 0.7, 3600_000)
+	if short.TestCount != long.TestCount {
+		t.Fatalf("test counts differ: %d vs %d", short.TestCount, long.TestCount)
+	}
+	if short.Suppressed || short.EffectiveN < MinEffectiveSamples {
+		t.Fatalf("1m cell suppressed (effective n=%v)", short.EffectiveN)
+	}
+	if short.PearsonIC <= 0 {
+		t.Fatalf("1m cell IC = %v, want the planted positive IC", short.PearsonIC)
+	}
+	if !long.Suppressed || long.EffectiveN >= MinEffectiveSamples {
+		t.Fatalf("1h cell reported (effective n=%v)", long.EffectiveN)
+	}
+	if long.PearsonIC != 0 || long.Sharpe != 0 {
+		t.Fatalf("1h cell computed metrics: IC=%v Sharpe=%v", long.PearsonIC, long.Sharpe)
+	}
+}
+
+// TestSplitsPurgeTrainLabels checks that no train label, [t, t+horizon],
+// reaches the test period of the core split or of any walk-forward fold.
+func TestSplitsPurgeTrainLabels(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	const n = 5000
+	times := make([]float64, n)
+	feats := make([]float64, n)
+	rets := make([]float64, n)
+	for i := 1; i < n; i++ {
+		times[i] = times[i-1] + float64(1000+rng.Intn(9000))
+		feats[i] = rng.NormFloat64()
+		rets[i] = rng.NormFloat64()
+	}
+	const horizonMs = 120_000
+
+	// With the embargo off, the purge alone has to keep labels apart.
+	defer func(purge bool, embargo int) { PurgeSplit, EmbargoSamples = purge, embargo }(PurgeSplit, EmbargoSamples)
+	PurgeSplit = true
+	for _, embargo := range []int{-1, 0} {
+		EmbargoSamples = embargo
+		for _, frac := range []float64{0.5, 0.7, 0.9} {
+			s := purgedSplit(times, feats, rets, frac, horizonMs)
+			trainEnd, testStart := len(s.TrainF), n-len(s.TestF)
+			if trainEnd == 0 || len(s.TestF) == 0 {
+				t.Fatalf("embargo %d, frac %g: empty split", embargo, frac)
+			}
+			if last := times[trainEnd-1]; last+horizonMs >= s.TestT[0] {
+				t.Fatalf("embargo %d, frac %g: train label [%v, %v] reaches test start %v", embargo, frac, last, last+horizonMs, s.TestT[0])
+			}
+			if testStart <= trainEnd {
+				t.Fatalf("embargo %d, frac %g: test starts at %d, inside train (%d)", embargo, frac, testStart, trainEnd)
+			}
+		}
+
+		const folds = 4
+		stats, _ := WalkForwardOOS(times, feats, rets, folds, horizonMs)
+		if len(stats) != folds {
+			t.Fatalf("embargo %d: got %d folds, want %d", embargo, len(stats), folds)
+		}
+		block := n / (folds + 1)
+		for k, st := range stats {
+			testEnd := (k + 2) * block
+			if k == folds-1 {
+				testEnd = n
+			}
+			last, first := times[st.TrainCount-1], times[testEnd-st.TestCount]
+			if last+horizonMs >= first {
+				t.Fatalf("embargo %d, fold %d: train label [%v, %v] reaches test start %v", embargo, k, last, last+horizonMs, first)
+			}
+		}
+	}
+}
+
+// TestStrategyRiskStatsCurveSkipsOverlap builds 1h labels sampled every
+// minute: only the trades at 0, 60 and 120 minutes make the equity curve,
+// so the -0.5 labels in between (which compounded as if sequential would
+// ruin the account) don't touch the drawdown. The curve is still under
+// water at the last trade.
+func TestStrategyRiskStatsCurveSkipsOverlap(t *testing.T) {
+	defer func(vt float64, add bool) { VolTarget, AdditiveDrawdown = vt, add }(VolTarget, AdditiveDrawdown)
+	VolTarget = 0
+
+	const n = 180
+	times := make([]float64, n)
+	sig := make([]float64, n)
+	ret := make([]float64, n)
+	for i := range n {
+		times[i] = float64(i * 60_000)
+		sig[i] = 1
+		ret[i] = -0.5
+	}
+	ret[0], ret[60], ret[120] = 0.1, -0.2, 0.05
+
+	rs := StrategyRiskStats(times, sig, ret, 3600_000)
+	if want := 1 - math.Exp(-0.2); math.Abs(rs.MaxDrawdown-want) > 1e-12 {
+		t.Fatalf("compounded MaxDrawdown = %v, want %v", rs.MaxDrawdown, want)
+	}
+	if rs.MaxDDDuration != 2 || rs.AvgUnderwater != 2 {
+		t.Fatalf("MaxDDDuration = %d, AvgUnderwater = %v, want 2 and 2 (open run at the end)", rs.MaxDDDuration, rs.AvgUnderwater)
+	}
+
+	AdditiveDrawdown = true
+	if rs := StrategyRiskStats(times, sig, ret, 3600_000); math.Abs(rs.MaxDrawdown-0.2) > 1e-12 {
+		t.Fatalf("additive MaxDrawdown = %v, want 0.2", rs.MaxDrawdown)
+	}
+}
+
+// TestStrategyRiskStatsUnderwaterAtEnd has one drawdown that recovers and
+// one still open at the last trade; both count toward the averages.
+func TestStrategyRiskStatsUnderwaterAtEnd(t *testing.T) {
+	defer func(vt float64, add bool) { VolTarget, AdditiveDrawdown = vt, add }(VolTarget, AdditiveDrawdown)
+	VolTarget, AdditiveDrawdown = 0, false
+
+	ret := []float64{0.1, -0.1, 0.2, -0.05, -0.05, -0.05}
+	times := make([]float64, len(ret))
+	sig := make([]float64, len(ret))
+	for i := range ret {
+		times[i] = float64(i * 3600_000)
+		sig[i] = 1
+	}
+	rs := StrategyRiskStats(times, sig, ret, 3600_000)
+	if rs.MaxDDDuration != 3 || rs.AvgUnderwater != 2 {
+		t.Fatalf("MaxDDDuration = %d, AvgUnderwater = %v, want 3 and 2", rs.MaxDDDuration, rs.AvgUnderwater)
+	}
+	if want := 1 - math.Exp(-0.15); math.Abs(rs.MaxDrawdown-want) > 1e-12 {
+		t.Fatalf("MaxDrawdown = %v, want %v", rs.MaxDrawdown, want)
+	}
+	if math.Abs(rs.AvgWin-0.15) > 1e-12 || math.Abs(rs.AvgLoss+0.0625) > 1e-12 || math.Abs(rs.WinLossRatio-2.4) > 1e-12 {
+		t.Fatalf("AvgWin = %v, AvgLoss = %v, WinLossRatio = %v", rs.AvgWin, rs.AvgLoss, rs.WinLossRatio)
+	}
+}
+
+func TestFeatureRanksMatchRankify(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	vals := make([]float64, 1000)
+	for i := range vals {
+		vals[i] = float64(rng.Intn(50)) // plenty of ties
+	}
+	fr := NewFeatureRanks(vals, 300)
+	for _, from := range []int{0, 299, 300, 301, 999} {
+		got, want := fr.ranks(vals, from), rankify(vals[from:])
+		if !slices.Equal(got, want) {
+			t.Fatalf("from %d: ranks differ from rankify", from)
+		}
+	}
+	// A column other than the one it was built on ranks from scratch.
+	other := slices.Clone(vals)
+	other[500]++
+	other = append(other, 7)
+	if got, want := fr.ranks(other, 300), rankify(other[300:]); !slices.Equal(got, want) {
+		t.Fatal("ranks of a different column differ from rankify")
+	}
+}
+
+// BenchmarkSpearmanRanks ranks one feature's test segment for three
+// horizons whose embargoes start it at slightly different points, as a core
+// table row does: sorted afresh per horizon, and from one FeatureRanks.
+func BenchmarkSpearmanRanks(b *testing.B) {
+	const n = 77_000
+	rng := rand.New(rand.NewSource(1))
+	feats := make([]float64, n)
+	for i := range feats {
+		feats[i] = rng.NormFloat64()
+	}
+	froms := []int{n * 7 / 10, n*7/10 + 60, n*7/10 + 240}
+
+	b.Run("fresh", func(b *testing.B) {
+		for b.Loop() {
+			for _, from := range froms {
+				rankify(feats[from:])
+			}
+		}
+	})
+	b.Run("shared", func(b *testing.B) {
+		for b.Loop() {
+			fr := NewFeatureRanks(feats, froms[0])
+			for _, from := range froms {
+				fr.ranks(feats, from)
+			}
+		}
+	})
+}
+
+// bigPearson is Pearson in 256-bit floats: exact on float64 inputs for the
+// sizes used here, up to the final rounding.
+func bigPearson(x, y []float64) float64 {
+	const prec = 256
+	newF := func() *big.Float { return new(big.Float).SetPrec(prec) }
+	n := newF().SetInt64(int64(len(x)))
+	mx, my := newF(), newF()
+	for i := range x {
+		mx.Add(mx, newF().SetFloat64(x[i]))
+		my.Add(my, newF().SetFloat64(y[i]))
+	}
+	mx.Quo(mx, n)
+	my.Quo(my, n)
+	sxx, syy, sxy := newF(), newF(), newF()
+	for i := range x {
+		dx := newF().Sub(newF().SetFloat64(x[i]), mx)
+		dy := newF().Sub(newF().SetFloat64(y[i]), my)
+		sxx.Add(sxx, newF().Mul(dx, dx))
+		syy.Add(syy, newF().Mul(dy, dy))
+		sxy.Add(sxy, newF().Mul(dx, dy))
+	}
+	den := newF().Sqrt(newF().Mul(sxx, syy))
+	r, _ := newF().Quo(sxy, den).Float64()
+	return r
+}
+
+func TestPearsonMatchesHighPrecision(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	// series returns n points mean + spread*z with z standard normal, and
+	// a partner correlated rho with it.
+	series := func(n int, mx, sx, my, sy, rho float64) (x, y []float64) {
+		x, y = make([]float64, n), make([]float64, n)
+		for i := range n {
+			a, b := rng.NormFloat64(), rng.NormFloat64()
+			x[i] = mx + sx*a
+			y[i] = my + sy*(rho*a+math.Sqrt(1-rho*rho)*b)
+		}
+		return x, y
+	}
+	for _, tc := range []struct {
+		name                string
+		n                   int
+		mx, sx, my, sy, rho float64
+	}{
+		{"unit", 1000, 0, 1, 0, 1, 0.3},
+		{"price level", 5000, 65000, 1e-3, 0, 1e-4, 0.2},
+		{"huge means", 5000, 1e9, 1e-3, -1e10, 1e-2, 0.5},
+		{"near perfect", 2000, 1e6, 1e-2, 1e6, 1e-2, 0.999999},
+		{"near zero", 2000, 1e8, 1, 3e7, 1, 1e-4},
+		{"tiny scale", 1000, 1e-100, 1e-110, 1e-90, 1e-100, -0.7},
+	} {
+		x, y := series(tc.n, tc.mx, tc.sx, tc.my, tc.sy, tc.rho)
+		got, want := Pearson(x, y), bigPearson(x, y)
+		if math.Abs(got-want) > 1e-12 {
+			t.Errorf("%s: Pearson = %.15g, reference %.15g", tc.name, got, want)
+		}
+	}
+}
+
+// bruteKendallTau is tau-b straight from its definition, over every pair.
+func bruteKendallTau(x, y []float64) float64 {
+	var s, n0, tx, ty float64
+	for i := range x {
+		for j := i + 1; j < len(x); j++ {
+			dx, dy := cmp.Compare(x[i], x[j]), cmp.Compare(y[i], y[j])
+			s += float64(dx * dy)
+			n0++
+			if dx == 0 {
+				tx++
+			}
+			if dy == 0 {
+				ty++
+			}
+		}
+	}
+	if n0 == tx || n0 == ty {
+		return 0
+	}
+	return s / math.Sqrt((n0-tx)*(n0-ty))
+}
+
+func TestKendallTauMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	for trial := range 200 {
+		n := 2 + rng.Intn(300)
+		// Small value ranges on some trials make ties on either side.
+		xr, yr := 1+rng.Intn(2*n), 1+rng.Intn(2*n)
+		if trial%4 == 0 {
+			xr, yr = 1<<30, 1<<30
+		}
+		x, y := make([]float64, n), make([]float64, n)
+		for i := range n {
+			x[i] = float64(rng.Intn(xr))
+			y[i] = float64(rng.Intn(yr))
+			if trial%3 == 0 {
+				y[i] = x[i] + float64(rng.Intn(3)) // dependent
+			}
+		}
+		got, want := KendallTau(x, y), bruteKendallTau(x, y)
+		if math.Abs(got-want) > 1e-12 {
+			t.Fatalf("trial %d (n=%d): KendallTau = %v, brute force %v", trial, n, got, want)
+		}
+	}
+
+	for _, tc := range []struct {
+		name string
+		x, y []float64
+		want float64
+	}{
+		{"identical", []float64{1, 2, 3, 4}, []float64{1, 2, 3, 4}, 1},
+		{"reversed", []float64{1, 2, 3, 4}, []float64{4, 3, 2, 1}, -1},
+		{"x constant", []float64{5, 5, 5}, []float64{1, 2, 3}, 0},
+	} {
+		if got := KendallTau(tc.x, tc.y); math.Abs(got-tc.want) > 1e-12 {
+			t.Errorf("%s: KendallTau = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+// TestMutualInfoIndependentNearZero averages the MI of independent inputs:
+// the plug-in estimate is biased up by about (b-1)²/(2n ln 2) bits, the
+// Miller-Madow corrected one centers on 0. A dependent pair stays well
+// clear of both.
+func TestMutualInfoIndependentNearZero(t *testing.T) {
+	defer func(bias bool) { MIBiasCorrection = bias }(MIBiasCorrection)
+	const n, trials, bins = 20_000, 20, 10
+	rng := rand.New(rand.NewSource(11))
+	x, y := make([]float64, n), make([]float64, n)
+
+	var plugin, corrected float64
+	for range trials {
+		for i := range n {
+			x[i], y[i] = rng.NormFloat64(), rng.NormFloat64()
+		}
+		MIBiasCorrection = false
+		mi, _ := CalcMutualInfo(x, y, bins)
+		plugin += mi / trials
+		MIBiasCorrection = true
+		mi, _ = CalcMutualInfo(x, y, bins)
+		corrected += mi / trials
+	}
+	bias := float64((bins-1)*(bins-1)) / (2 * n * math.Ln2)
+	if math.Abs(plugin-bias) > bias/3 {
+		t.Errorf("plug-in MI = %.5f bits, want about %.5f", plugin, bias)
+	}
+	if math.Abs(corrected) > bias/5 {
+		t.Errorf("corrected MI = %.5f bits, want about 0", corrected)
+	}
+
+	for i := range n {
+		y[i] = x[i] + rng.NormFloat64()
+	}
+	if mi, _ := CalcMutualInfo(x, y, bins); mi < 20*bias {
+		t.Errorf("dependent MI = %.5f bits, want well above the bias %.5f", mi, bias)
+	}
+}
+
+// oosFixture is n sorted samples a minute apart with a weak signal.
+func oosFixture(n int, seed int64) (times, feats, rets []float64) {
+	rng := rand.New(rand.NewSource(seed))
+	times, feats, rets = make([]float64, n), make([]float64, n), make([]float64, n)
+	for i := range n {
+		times[i] = float64(i * 60_000)
+		feats[i] = rng.NormFloat64()
+		rets[i] = 0.05*feats[i] + rng.NormFloat64()
+	}
+	return times, feats, rets
+}
+
+// TestAnalyzeFullSuiteOOSRepeatable calls the metrics twice on the same
+// arrays, as the report does for one cell's sections: the results must be
+// identical and the inputs untouched.
+func TestAnalyzeFullSuiteOOSRepeatable(t *testing.T) {
+	defer func(prev bool) { BootstrapIntervals = prev }(BootstrapIntervals)
+	BootstrapIntervals = true
+	times, feats, rets := oosFixture(5000, 13)
+	t0, f0, r0 := slices.Clone(times), slices.Clone(feats), slices.Clone(rets)
+
+	a := AnalyzeFullSuiteOOS(times, feats, rets, 0.7, 60_000)
+	w := RollingWindowMetricsOOS(times, feats, rets, 0.7, 60_000, 4)
+	b := AnalyzeFullSuiteOOS(times, feats, rets, 0.7, 60_000)
+	if a.TestCount == 0 || a.Suppressed {
+		t.Fatalf("empty result: %+v", a)
+	}
+	// %v prints NaN fields alike, where == and DeepEqual wouldn't match them.
+	if fmt.Sprintf("%+v", a) != fmt.Sprintf("%+v", b) {
+		t.Fatalf("second call differs:\n%+v\n%+v", a, b)
+	}
+	if len(w) == 0 {
+		t.Fatal("no rolling windows")
+	}
+	if !slices.Equal(times, t0) || !slices.Equal(feats, f0) || !slices.Equal(rets, r0) {
+		t.Fatal("inputs modified")
+	}
+}
+
+func TestOOSMetricsRejectUnsorted(t *testing.T) {
+	times, feats, rets := oosFixture(5000, 17)
+	sizes := func() []int {
+		folds, _ := WalkForwardOOS(times, feats, rets, 4, 60_000)
+		return []int{
+			AnalyzeFullSuiteOOS(times, feats, rets, 0.7, 60_000).TestCount,
+			len(RollingWindowMetricsOOS(times, feats, rets, 0.7, 60_000, 4)),
+			len(folds),
+			RidgeCombineOOS(times, [][]float64{feats}, rets, 0.7, 1, 60_000).Stats.TestCount,
+		}
+	}
+	if got := sizes(); slices.Contains(got, 0) {
+		t.Fatalf("sorted input: sizes %v, want all non-zero", got)
+	}
+	times[100], times[101] = times[101], times[100]
+	if got := sizes(); !slices.Equal(got, []int{0, 0, 0, 0}) {
+		t.Fatalf("unsorted input: sizes %v, want all zero", got)
+	}
+}
+
+// TestRankNormMetricsMonotoneInvariant checks that the rank-normalized
+// metrics see only the signal's order: a strictly monotone transform of it
+// leaves them bit-identical.
+func TestRankNormMetricsMonotoneInvariant(t *testing.T) {
+	defer func(prev bool) { RankNormMetrics = prev }(RankNormMetrics)
+	RankNormMetrics = true
+	times, feats, rets := oosFixture(5000, 19)
+	base := AnalyzeFullSuiteOOS(times, feats, rets, 0.7, 60_000)
+	if base.TestCount == 0 || base.Suppressed || base.MutualInfoRank == 0 {
+		t.Fatalf("empty result: %+v", base)
+	}
+
+	for name, f := range map[string]func(float64) float64{
+		"exp":  math.Exp,
+		"cube": func(x float64) float64 { return x * x * x },
+	} {
+		tf := make([]float64, len(feats))
+		for i, x := range feats {
+			tf[i] = f(x)
+		}
+		got := AnalyzeFullSuiteOOS(times, tf, rets, 0.7, 60_000)
+		for _, c := range []struct {
+			field     string
+			got, want float64
+		}{
+			{"MutualInfoRank", got.MutualInfoRank, base.MutualInfoRank},
+			{"NormalizedMIRank", got.NormalizedMIRank, base.NormalizedMIRank},
+			{"DeltaLogLossRank", got.DeltaLogLossRank, base.DeltaLogLossRank},
+			{"SpearmanIC", got.SpearmanIC, base.SpearmanIC},
+			{"KendallTau", got.KendallTau, base.KendallTau},
+		} {
+			if c.got != c.want {
+				t.Errorf("%s: %s = %v, want %v", name, c.field, c.got, c.want)
+			}
+		}
+	}
+}
+
+// TestBigMoveMetricsFindsPlantedMoves plants ten large moves in the test
+// segment, each preceded by a horizon of signal leaning its way, on a
+// background of small returns and pure-noise signal. All ten must be found
+// and the pre-move signal must point the right way nearly every time.
+func TestBigMoveMetricsFindsPlantedMoves(t *testing.T) {
+	const n, horizon, moves = 4000, 10, 10
+	rng := rand.New(rand.NewSource(5))
+	times, feats, rets := make([]float64, n), make([]float64, n), make([]float64, n)
+	for i := range n {
+		times[i] = float64(i * 60_000)
+		feats[i] = rng.NormFloat64()
+		rets[i] = 0.01 * rng.NormFloat64()
+	}
+	for k := range moves {
+		i := 3000 + 100*k
+		dir := float64(1 - 2*(k%2))
+		rets[i] = 5 * dir
+		for j := i - horizon + 1; j <= i; j++ {
+			feats[j] = 2*dir + 0.5*rng.NormFloat64()
+		}
+	}
+
+	m := BigMoveMetricsOOS(times, feats, rets, 0.7, horizon*60_000, moves)
+	if m.Count != moves {
+		t.Fatalf("examined %d moves, want %d", m.Count, moves)
+	}
+	if m.HitRate < 0.9 || m.HitRateZ < 2 {
+		t.Fatalf("hit rate %.2f (z %.2f), want the planted signal to call the moves", m.HitRate, m.HitRateZ)
+	}
+	if m.MeanZ < 1 || m.Elevated < 0.9 {
+		t.Fatalf("mean z %.2f, elevated %.2f: pre-move signal should stand out", m.MeanZ, m.Elevated)
+	}
+}
+
+// TestVolTargetEqualizesSymbols runs the sign(signal) strategy on two
+// symbols whose returns differ in vol by 10x. Vol targeting must bring the
+// realized vol of both strategies to within a few percent of each other.
+func TestVolTargetEqualizesSymbols(t *testing.T) {
+	defer func(prev float64) { VolTarget = prev }(VolTarget)
+	rng := rand.New(rand.NewSource(9))
+	symbol := func(vol float64) (signal, ret []float64) {
+		signal, ret = make([]float64, 5000), make([]float64, 5000)
+		for i := range ret {
+			signal[i] = rng.NormFloat64()
+			ret[i] = vol * (0.1*signal[i] + rng.NormFloat64())
+		}
+		return signal, ret
+	}
+	strategyVol := func(signal, ret []float64) float64 {
+		scale := volTargetScale(ret)
+		pnl := make([]float64, len(ret))
+		for i, r := range ret {
+			pnl[i] = math.Copysign(scale, signal[i]) * r
+		}
+		_, std := meanStd(pnl)
+		return std
+	}
+	sigA, retA := symbol(0.001)
+	sigB, retB := symbol(0.01)
+
+	VolTarget = 0
+	if r := strategyVol(sigB, retB) / strategyVol(sigA, retA); r < 8 {
+		t.Fatalf("unscaled vol ratio %.2f, want the 10x gap", r)
+	}
+	VolTarget = 0.002
+	a, b := strategyVol(sigA, retA), strategyVol(sigB, retB)
+	if r := b / a; r < 0.97 || r > 1.03 {
+		t.Fatalf("vol-targeted ratio %.3f (%.5f vs %.5f), want ~1", r, b, a)
+	}
+	if math.Abs(a-VolTarget) > 0.05*VolTarget {
+		t.Fatalf("vol-targeted strategy vol %.5f, want ~%.3f", a, VolTarget)
+	}
+}
+
+// TestLogisticWarmStartConverges fits the next walk-forward fold from the
+// previous fold's (a, b) and from zero. The warm start must take strictly
+// fewer Newton iterations and land on the same solution.
+func TestLogisticWarmStartConverges(t *testing.T) {
+	rng := rand.New(rand.NewSource(21))
+	fold := func(mean float64) (f, y []float64) {
+		f, y = make([]float64, 4000), make([]float64, 4000)
+		for i := range f {
+			f[i] = mean + rng.NormFloat64()
+			if rng.Float64() < 1/(1+math.Exp(-(0.2+0.8*f[i]))) {
+				y[i] = 1
+			}
+		}
+		return f, y
+	}
+	prevF, prevY := fold(0)
+	nextF, nextY := fold(0.3)
+
+	a0, b0, _ := fitLogistic1DFrom(prevF, prevY, 0, 0)
+	aCold, bCold, cold := fitLogistic1DFrom(nextF, nextY, 0, 0)
+	aWarm, bWarm, warm := fitLogistic1DFrom(nextF, nextY, a0, b0)
+	if warm >= cold {
+		t.Fatalf("warm start took %d iterations, cold %d", warm, cold)
+	}
+	if math.Abs(aWarm-aCold) > 1e-5 || math.Abs(bWarm-bCold) > 1e-5 {
+		t.Fatalf("warm (%.7f, %.7f) vs cold (%.7f, %.7f)", aWarm, bWarm, aCold, bCold)
+	}
+}
+
+// TestNetStrategyStatsCountsCurveFlips alternates the signal every sample
+// under a two-sample label. The curve holds every other sample, all long, so
+// there is nothing to flip; with a one-sample label every trade flips.
+func TestNetStrategyStatsCountsCurveFlips(t *testing.T) {
+	defer func(prev float64) { VolTarget = prev }(VolTarget)
+	VolTarget = 0
+	const n = 100
+	times, signal, ret := make([]float64, n), make([]float64, n), make([]float64, n)
+	for i := range n {
+		times[i] = float64(i * 60_000)
+		signal[i] = float64(1 - 2*(i%2))
+		ret[i] = 0.001 * float64(1+i%3)
+	}
+
+	if _, _, flips, turnover := NetStrategyStats(times, signal, ret, 120_000, 4); flips != 0 || turnover != 0 {
+		t.Fatalf("overlapping labels: %d flips, turnover %.2f, want none", flips, turnover)
+	}
+	if _, _, flips, turnover := NetStrategyStats(times, signal, ret, 60_000, 4); flips != n-1 || turnover != float64(n-1)/n {
+		t.Fatalf("one-sample labels: %d flips, turnover %.2f, want %d", flips, turnover, n-1)
+	}
+}
+
+// TestNetStrategyStatsZeroCostIsGross checks that without costs the net
+// Sharpe is the gross one, so their gap under a cost is the cost alone.
+func TestNetStrategyStatsZeroCostIsGross(t *testing.T) {
+	defer func(prev float64) { VolTarget = prev }(VolTarget)
+	VolTarget = 0
+	rng := rand.New(rand.NewSource(1))
+	const n = 5000
+	times, signal, ret := make([]float64, n), make([]float64, n), make([]float64, n)
+	for i := range n {
+		times[i] = float64(i * 60_000)
+		signal[i] = rng.NormFloat64()
+		ret[i] = 0.0002*signal[i] + 0.001*rng.NormFloat64()
+	}
+
+	for _, horizonMs := range []int64{60_000, 300_000} {
+		gross, net, flips, _ := NetStrategyStats(times, signal, ret, horizonMs, 0)
+		if gross == 0 || flips == 0 || net != gross {
+			t.Fatalf("horizon %d, no cost: gross %v, net %v (%d flips); want equal", horizonMs, gross, net, flips)
+		}
+		costGross, costNet, _, _ := NetStrategyStats(times, signal, ret, horizonMs, 4)
+		if costGross != gross || costNet >= gross {
+			t.Fatalf("horizon %d, 4bps: gross %v, net %v; want gross %v and net below it", horizonMs, costGross, costNet, gross)
+		}
+	}
+}
+
+// TestBoundarySweepSeparatesRobustFromCutDependent sweeps the boundary over
+// a signal that predicts the whole series and one that predicts up to 70%
+// of it and is contrarian after. The first keeps its IC sign at every cut
+// and is marked robust; the second flips sign and is marked cut-dependent.
+func TestBoundarySweepSeparatesRobustFromCutDependent(t *testing.T) {
+	const n = 10000
+	fracs := []float64{0.5, 0.6, 0.7, 0.8}
+	times, feats, rets := oosFixture(n, 17)
+	robust := BoundarySweepOOS(times, feats, rets, fracs, 60_000)
+
+	rng := rand.New(rand.NewSource(19))
+	cutRets := make([]float64, n)
+	for i := range n {
+		beta := 0.3
+		if i >= 7*n/10 {
+			beta = -0.1
+		}
+		cutRets[i] = beta*feats[i] + rng.NormFloat64()
+	}
+	cut := BoundarySweepOOS(times, feats, cutRets, fracs, 60_000)
+
+	if len(robust.ICs) != len(fracs) || len(cut.ICs) != len(fracs) {
+		t.Fatalf("got %d and %d ICs, want one per boundary", len(robust.ICs), len(cut.ICs))
+	}
+	if robust.Verdict() != "ROBUST" || robust.Min <= 0 {
+		t.Fatalf("robust signal: %+v, want a positive IC at every cut", robust)
+	}
+	if cut.Verdict() != "CUT-DEPENDENT" || cut.Min >= 0 || cut.Max <= 0 {
+		t.Fatalf("cut-dependent signal: %+v, want the IC sign to depend on the cut", cut)
+	}
+	if cut.Std < 3*robust.Std {
+		t.Fatalf("IC spread %.4f (cut-dependent) vs %.4f (robust)", cut.Std, robust.Std)
+	}
+}
+
+// TestGatedEnsembleBeatsSingleModels builds returns that model A predicts
+// in low vol and model B in high vol (both half-predict the middle). The
+// gate must pick A for low and B for high vol, and its test IC must beat
+// either model on its own.
+func TestGatedEnsembleBeatsSingleModels(t *testing.T) {
+	const n = 6000
+	rng := rand.New(rand.NewSource(23))
+	times, rets, regime := make([]float64, n), make([]float64, n), make([]float64, n)
+	a, b := make([]float64, n), make([]float64, n)
+	for i := range n {
+		times[i] = float64(i * 60_000)
+		regime[i] = rng.Float64()
+		a[i], b[i] = rng.NormFloat64(), rng.NormFloat64()
+		switch {
+		case regime[i] < 1.0/3:
+			rets[i] = 0.3 * a[i]
+		case regime[i] > 2.0/3:
+			rets[i] = 0.3 * b[i]
+		default:
+			rets[i] = 0.15 * (a[i] + b[i])
+		}
+		rets[i] += rng.NormFloat64()
+	}
+
+	g := GatedEnsembleOOS(times, [][]float64{a, b}, rets, regime, 0.7, 60_000)
+	if g.Picks[0] != 0 || g.Picks[2] != 1 || g.Signs[0] != 1 || g.Signs[2] != 1 {
+		t.Fatalf("picks %v signs %v, want A long in low vol and B long in high vol", g.Picks, g.Signs)
+	}
+	icA := AnalyzeFullSuiteOOS(times, a, rets, 0.7, 60_000).PearsonIC
+	icB := AnalyzeFullSuiteOOS(times, b, rets, 0.7, 60_000).PearsonIC
+	if ic := g.Stats.PearsonIC; ic <= icA || ic <= icB {
+		t.Fatalf("gated test IC %.4f, want above A (%.4f) and B (%.4f)", ic, icA, icB)
+	}
+}
+
+// TestHoldingCurvePeaksAtKnownHold moves the log price after each signal
+// along a tent that peaks 5 minutes later and is gone after 10. The
+// signal-aligned PnL is then highest for a 5-minute hold.
+func TestHoldingCurvePeaksAtKnownHold(t *testing.T) {
+	const n, peak = 5000, 5
+	rng := rand.New(rand.NewSource(29))
+	tent := func(k int) float64 { return 0.001 * math.Max(peak-math.Abs(float64(k-peak)), 0) }
+	times, prices, feats := make([]float64, n), make([]float64, n), make([]float64, n)
+	logP := make([]float64, n)
+	var walk float64
+	for i := range n {
+		times[i] = float64(i * 60_000)
+		feats[i] = float64(2*rng.Intn(2) - 1)
+		walk += 0.0002 * rng.NormFloat64()
+		logP[i] = walk
+		for k := 1; k < 2*peak && k <= i; k++ {
+			logP[i] += feats[i-k] * tent(k)
+		}
+		prices[i] = 100 * math.Exp(logP[i])
+	}
+
+	h := HoldingCurveOOS(times, prices, feats, 0.5, 60_000, 60_000, 2*peak*60_000)
+	if h.Best < 0 || h.HoldMs(h.Best) != peak*60_000 {
+		t.Fatalf("best hold bucket %d (%v), want %d min; curve %.2f", h.Best, h.MeanBps, peak, h.MeanBps)
+	}
+}
+
+// TestHoldingCurveUsesPurgedSplit checks that the holding curve enters on
+// purgedSplit's test segment, embargo included, and that unsorted samples
+// give an empty curve like every other *OOS metric.
+func TestHoldingCurveUsesPurgedSplit(t *testing.T) {
+	const n = 1000
+	times, prices, feats := make([]float64, n), make([]float64, n), make([]float64, n)
+	for i := range n {
+		times[i] = float64(i * 60_000)
+		prices[i] = 100 + float64(i%7)
+		feats[i] = 1
+	}
+	entries := func(h HoldingCurve) int { return h.Counts[0] } // one 1m pair per entry but the last
+
+	defer func(prev int) { EmbargoSamples = prev }(EmbargoSamples)
+	EmbargoSamples = 0
+	if got, want := entries(HoldingCurveOOS(times, prices, feats, 0.7, 60_000, 60_000, 60_000)), 299; got != want {
+		t.Fatalf("%d entries without embargo, want %d", got, want)
+	}
+	EmbargoSamples = 50
+	if got, want := entries(HoldingCurveOOS(times, prices, feats, 0.7, 60_000, 60_000, 60_000)), 249; got != want {
+		t.Fatalf("%d entries with a 50-sample embargo, want %d", got, want)
+	}
+
+	slices.Reverse(times)
+	if h := HoldingCurveOOS(times, prices, feats, 0.7, 60_000, 60_000, 60_000); h.Best >= 0 || h.Counts != nil {
+		t.Fatalf("unsorted samples gave a curve: %+v", h)
+	}
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// MappedMonth is a read-only memory mapping of one month's data.quantdev,
+// plus its decoded index. Day blobs are handed out as sub-slices of the
+// mapping, so repeated passes over the same month never re-read from disk
+// (the OS page cache does the work).
+//
+// Slices returned by Day are only valid until Close.
+type MappedMonth struct {
+	data    []byte
+	entries map[int]indexEntry
+}
+
+// mmapMonth maps dir/data.quantdev and loads dir/index.quantdev once.
+func mmapMonth(dir string) (*MappedMonth, error) {
+	entries, err := readIndexEntries(filepath.Join(dir, "index.quantdev"))
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(filepath.Join(dir, "data.quantdev"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := fi.Size()
+	if size <= 0 {
+		return nil, fmt.Errorf("empty data file")
+	}
+	if int64(int(size)) != size {
+		return nil, fmt.Errorf("data file too large to map (%d bytes)", size)
+	}
+
+	data, err := mmapFile(f, int(size))
+	if err != nil {
+		return nil, err
+	}
+	return &MappedMonth{data: data, entries: entries}, nil
+}
+
+// Day returns a zero-copy view of the blob for day, or false if the day is
+// not indexed, its row points outside the mapping, or (with
+// VerifyBlobChecksums) the checksum doesn't match.
+func (m *MappedMonth) Day(day int) ([]byte, bool) {
+	if m == nil || m.data == nil {
+		return nil, false
+	}
+	e, ok := m.entries[day]
+	if !ok || e.Length == 0 {
+		return nil, false
+	}
+	end := e.Offset + e.Length
+	if end < e.Offset || end > uint64(len(m.data)) {
+		return nil, false
+	}
+	raw := m.data[e.Offset:end:end]
+	if VerifyBlobChecksums && blobChecksum(raw) != e.Checksum {
+		return nil, false
+	}
+	return raw, true
+}
+
+// Close unmaps the data file. Safe to call more than once.
+func (m *MappedMonth) Close() error {
+	if m == nil || m.data == nil {
+		return nil
+	}
+	err := munmap(m.data)
+	m.data = nil
+	return err
+}
+
+// DayLoader hands out day blobs for one symbol, preferring a zero-copy view
+// into a mapped data.quantdev and falling back to LoadGNCFile's read path
+// whenever mapping isn't possible (UseMmap off, unsupported filesystem,
+// missing index, ...). It keeps the most recently used month mapped, which
+// suits workers that pull days in chronological order.
+//
+// A DayLoader is not safe for concurrent use; give each worker its own.
+type DayLoader struct {
+	baseDir, sym string
+
+	dir   string       // month directory currently mapped (or that failed)
+	month *MappedMonth // nil if dir couldn't be mapped
+	buf   []byte       // read-path buffer
+}
+
+func NewDayLoader(baseDir, sym string) *DayLoader {
+	return &DayLoader{baseDir: baseDir, sym: sym}
+}
+
+// Load returns the blob for t. The slice is only valid until the next Load
+// for a different month, or Close.
+func (l *DayLoader) Load(t ofiTask) ([]byte, bool) {
+	if UseMmap {
+		dir := filepath.Join(l.baseDir, l.sym, sprintfYear(t.Year), sprintfMonth(t.Month))
+		if dir != l.dir {
+			l.month.Close()
+			l.month = nil
+			l.dir = dir
+			if m, err := mmapMonth(dir); err == nil {
+				l.month = m
+			}
+		}
+		if l.month != nil {
+			return l.month.Day(t.Day)
+		}
+	}
+
+	if !LoadGNCFile(l.baseDir, l.sym, t, &l.buf) {
+		return nil, false
+	}
+	return l.buf, true
+}
+
+// Close releases the current mapping, if any.
+func (l *DayLoader) Close() {
+	l.month.Close()
+	l.month = nil
+	l.dir = ""
+}
+
+// readIndexEntries decodes every row of an index.quantdev into a day->entry
+// map. Like findBlobEntry, the first row for a day wins.
+func readIndexEntries(idxPath string) (map[int]indexEntry, error) {
+	rows, err := readIndexRows(idxPath)
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[int]indexEntry, len(rows))
+	for _, r := range rows {
+		if _, dup := entries[r.Day]; dup {
+			continue
+		}
+		entries[r.Day] = r.indexEntry
+	}
+	return entries, nil
+}
+
+// readIndexRows decodes every row of an index.quantdev in file order,
+// duplicates included.
+func readIndexRows(idxPath string) ([]indexRow, error) {
+	f, err := os.Open(idxPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var hdr [16]byte
+	if _, err := io.ReadFull(f, hdr[:]); err != nil {
+		return nil, err
+	}
+	if string(hdr[0:4]) != IdxMagic {
+		return nil, fmt.Errorf("index magic mismatch")
+	}
+	count := binary.LittleEndian.Uint64(hdr[8:16])
+
+	rows := make([]indexRow, 0, 31)
+	var row [26]byte
+	for i := uint64(0); i < count; i++ {
+		if _, err := io.ReadFull(f, row[:]); err != nil {
+			return nil, fmt.Errorf("index truncated at row %d: %w", i, err)
+		}
+		rows = append(rows, indexRow{
+			Day: int(binary.LittleEndian.Uint16(row[0:2])),
+			indexEntry: indexEntry{
+				Offset:   binary.LittleEndian.Uint64(row[2:10]),
+				Length:   binary.LittleEndian.Uint64(row[10:18]),
+				Checksum: binary.LittleEndian.Uint64(row[18:26]),
+			},
+		})
+	}
+	return rows, nil
+}
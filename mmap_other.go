@@ -0,0 +1,19 @@
+//go:build !unix && !windows
+
+package main
+
+import (
+	"io"
+	"os"
+)
+
+// No mmap on this platform: fall back to reading the whole file.
+func mmapFile(f *os.File, size int) ([]byte, error) {
+	b := make([]byte, size)
+	if _, err := io.ReadFull(f, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func munmap(b []byte) error { return nil }
@@ -0,0 +1,33 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+func mmapFile(f *os.File, size int) ([]byte, error) {
+	h, err := syscall.CreateFileMapping(syscall.Handle(f.Fd()), nil, syscall.PAGE_READONLY, 0, 0, nil)
+	if err != nil {
+		return nil, os.NewSyscallError("CreateFileMapping", err)
+	}
+	// The view keeps the mapping object alive; the handle can go now.
+	defer syscall.CloseHandle(h)
+
+	addr, err := syscall.MapViewOfFile(h, syscall.FILE_MAP_READ, 0, 0, uintptr(size))
+	if err != nil {
+		return nil, os.NewSyscallError("MapViewOfFile", err)
+	}
+	// addr is the base of a mapped view, memory the Go heap never owns or
+	// moves, so converting it back to a pointer can't leave a dangling or
+	// stale reference. It stays valid until munmap unmaps the view. (vet's
+	// unsafeptr check can't tell OS-owned memory from a GC'd object's
+	// address held in a uintptr, and reports this line.)
+	return unsafe.Slice((*byte)(unsafe.Pointer(addr)), size), nil
+}
+
+func munmap(b []byte) error {
+	return syscall.UnmapViewOfFile(uintptr(unsafe.Pointer(&b[0])))
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ModelSpec is one entry of the models config file, e.g.
+//
+//	{"type": "Hawkes_OFI", "name": "Hawkes_OFI_600s", "tau": 600}
+//	{"type": "Hilbert_Phase", "params": {"h": 0.7}}
+//	{"type": "Sig_LevyArea", "disabled": true}
+//
+// Type is the model's default Name(). Tau (seconds) maps onto each model's
+// own decay/frequency parameter; Params sets any other named field. Disabled
+// entries are still validated but not instantiated, so a model can be
+// switched off without deleting its parameters.
+type ModelSpec struct {
+	Type     string             `json:"type"`
+	Name     string             `json:"name,omitempty"`
+	Tau      float64            `json:"tau,omitempty"`
+	Params   map[string]float64 `json:"params,omitempty"`
+	Disabled bool               `json:"disabled,omitempty"`
+}
+
+// modelFactory builds a model from a spec. Each entry documents how Tau is
+// interpreted for that model.
+var modelFactory = map[string]func(ModelSpec) (ContinuousModel, error){
+	"Hawkes_Intensity": func(s ModelSpec) (ContinuousModel, error) {
+		m := NewHawkesIntensity()
+		if s.Tau > 0 {
+			m.beta = 1 / s.Tau
+		}
+		return m, applyParams(s, map[string]*float64{"alpha": &m.alpha, "beta": &m.beta})
+	},
+	"Hawkes_OFI": func(s ModelSpec) (ContinuousModel, error) {
+		m := NewHawkesOFI()
+		if s.Tau > 0 {
+			m.beta = 1 / s.Tau
+		}
+		return m, applyParams(s, map[string]*float64{"beta": &m.beta})
+	},
+	"Hawkes_OFI_Signed": func(s ModelSpec) (ContinuousModel, error) {
+		m := NewHawkesOFISigned()
+		if s.Tau > 0 {
+			m.beta = 1 / s.Tau
+		}
+		return m, applyParams(s, map[string]*float64{"beta": &m.beta})
+	},
+	"Sig_LevyArea": func(s ModelSpec) (ContinuousModel, error) {
+		m := NewSignature()
+		if s.Tau > 0 {
+			m.decayRate = 1 / s.Tau
+		}
+		return m, applyParams(s, map[string]*float64{"decay": &m.decayRate})
+	},
+	"Hilbert_Phase": func(s ModelSpec) (ContinuousModel, error) {
+		m := NewHilbert()
+		if s.Tau > 0 {
+			m.r = 1 / s.Tau
+		}
+		return m, applyParams(s, map[string]*float64{"r": &m.r, "h": &m.h})
+	},
+	"VWAP_Dev": func(s ModelSpec) (ContinuousModel, error) {
+		m := NewVWAPDev()
+		if s.Tau > 0 {
+			m.tau = s.Tau
+		}
+		return m, applyParams(s, map[string]*float64{"squash": &m.squash})
+	},
+	"Norm_OFI": func(s ModelSpec) (ContinuousModel, error) {
+		m := NewNormOFI()
+		if s.Tau > 0 {
+			m.tau = s.Tau
+		}
+		return m, applyParams(s, nil)
+	},
+	"Kyle_Lambda": func(s ModelSpec) (ContinuousModel, error) {
+		m := NewKyleLambda()
+		if s.Tau > 0 {
+			m.tau = s.Tau
+		}
+		return m, applyParams(s, nil)
+	},
+	"Size_Entropy": func(s ModelSpec) (ContinuousModel, error) {
+		buckets := 16.0
+		if err := applyParams(s, map[string]*float64{"buckets": &buckets}); err != nil {
+			return nil, err
+		}
+		if buckets < 2 || buckets != math.Trunc(buckets) {
+			return nil, fmt.Errorf("model %q: buckets must be an integer >= 2, got %v", s.Type, buckets)
+		}
+		m := NewSizeEntropy(int(buckets))
+		if s.Tau > 0 {
+			m.tau = s.Tau
+		}
+		return m, nil
+	},
+	"OU_Revert": func(s ModelSpec) (ContinuousModel, error) {
+		m := NewOU()
+		if s.Tau > 0 {
+			m.tau = s.Tau
+		}
+		if err := applyParams(s, map[string]*float64{"theta": &m.theta}); err != nil {
+			return nil, err
+		}
+		if m.theta <= 0 {
+			return nil, fmt.Errorf("model %q: theta must be positive", s.Type)
+		}
+		return m, nil
+	},
+	"Kalman_Vel": func(s ModelSpec) (ContinuousModel, error) {
+		m := NewKalmanVel()
+		if s.Tau > 0 {
+			return nil, fmt.Errorf("model %q has no time constant; set params q/r instead of tau", s.Type)
+		}
+		return m, applyParams(s, map[string]*float64{"q": &m.q, "r": &m.r})
+	},
+	"Kalman_Adapt": func(s ModelSpec) (ContinuousModel, error) {
+		m := NewKalmanAdaptive()
+		if s.Tau > 0 {
+			m.volTau = s.Tau
+		}
+		return m, applyParams(s, map[string]*float64{"q": &m.q, "r": &m.r})
+	},
+}
+
+// applyParams copies s.Params into the model fields in known, rejecting
+// names the model doesn't have.
+func applyParams(s ModelSpec, known map[string]*float64) error {
+	for k, v := range s.Params {
+		dst, ok := known[k]
+		if !ok {
+			names := make([]string, 0, len(known))
+			for n := range known {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			return fmt.Errorf("model %q: unknown param %q (valid: %s)", s.Type, k, strings.Join(names, ", "))
+		}
+		*dst = v
+	}
+	return nil
+}
+
+// namedModel overrides Name() so several instances of one type can coexist
+// in a report.
+type namedModel struct {
+	ContinuousModel
+	name string
+}
+
+func (m namedModel) Name() string { return m.name }
+
+// UpdateTick forwards the whole Tick, so renaming doesn't cut a TickModel
+// off from sides and match counts.
+func (m namedModel) UpdateTick(t Tick) float64 {
+	return updateTick(m.ContinuousModel, t)
+}
+
+// LoadModelsFromConfig builds the model list described by a JSON array of
+// ModelSpec. A missing file returns an error wrapping fs.ErrNotExist.
+func LoadModelsFromConfig(path string) ([]ContinuousModel, error) {
+	specs, err := readModelSpecs(path)
+	if err != nil {
+		return nil, err
+	}
+	return modelsFromSpecs(path, specs)
+}
+
+// readModelSpecs parses the models config file without building anything.
+func readModelSpecs(path string) ([]ModelSpec, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var specs []ModelSpec
+	if err := json.Unmarshal(raw, &specs); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return specs, nil
+}
+
+// modelsFromSpecs builds a fresh model list from specs read from path.
+func modelsFromSpecs(path string, specs []ModelSpec) ([]ContinuousModel, error) {
+	models, err := buildModels(specs)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(models) == 0 {
+		return nil, fmt.Errorf("%s: no enabled models", path)
+	}
+	return models, nil
+}
+
+// buildModels instantiates specs via modelFactory, enforcing unique names.
+func buildModels(specs []ModelSpec) ([]ContinuousModel, error) {
+	models := make([]ContinuousModel, 0, len(specs))
+	seen := make(map[string]bool, len(specs))
+	for i, s := range specs {
+		build, ok := modelFactory[s.Type]
+		if !ok {
+			types := make([]string, 0, len(modelFactory))
+			for t := range modelFactory {
+				types = append(types, t)
+			}
+			sort.Strings(types)
+			return nil, fmt.Errorf("model #%d: unknown type %q (valid: %s)", i, s.Type, strings.Join(types, ", "))
+		}
+		if s.Tau < 0 {
+			return nil, fmt.Errorf("model #%d (%s): tau must be positive", i, s.Type)
+		}
+		m, err := build(s)
+		if err != nil {
+			return nil, err
+		}
+		if s.Disabled {
+			continue
+		}
+		if s.Name != "" && s.Name != m.Name() {
+			m = namedModel{ContinuousModel: m, name: s.Name}
+		}
+		if seen[m.Name()] {
+			return nil, fmt.Errorf("model #%d: duplicate name %q", i, m.Name())
+		}
+		seen[m.Name()] = true
+		models = append(models, m)
+	}
+	return models, nil
+}
+
+// checkModelsConfig validates ModelsConfigPath up front so a broken config
+// fails the run instead of silently falling back to the built-in set.
+func checkModelsConfig() error {
+	_, err := LoadModelsFromConfig(ModelsConfigPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// FeatureClusters groups features by single linkage on the correlation
+// matrix: i and j share a cluster when a chain of pairs with |rho| > minAbs
+// connects them. Clusters are numbered from 1 in order of their first
+// member, so a feature with no close neighbour gets a cluster of its own.
+func FeatureClusters(corr [][]float64, minAbs float64) []int {
+	n := len(corr)
+	ids := make([]int, n)
+	next := 0
+	for i := range n {
+		if ids[i] != 0 {
+			continue
+		}
+		next++
+		ids[i] = next
+		stack := []int{i}
+		for len(stack) > 0 {
+			a := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			for b := range n {
+				if ids[b] == 0 && b != a && math.Abs(corr[a][b]) > minAbs {
+					ids[b] = next
+					stack = append(stack, b)
+				}
+			}
+		}
+	}
+	return ids
+}
+
+// OrthogonalICs returns each feature's Pearson IC against ret and its IC
+// after Gram-Schmidt orthogonalization against every feature ranked above
+// it: features are taken in descending |orderIC| order, and each one's
+// centered column has its projections on the earlier residuals removed
+// before it is correlated with ret. orthIC is what a feature adds beyond
+// the stronger ones; a feature that is (numerically) a combination of them
+// gets 0. orderIC should come from data other than feats and ret (the
+// report passes train ICs and test columns), or the order itself is fit to
+// what it reports on. Columns must share ret's length; others are left at 0.
+func OrthogonalICs(feats [][]float64, ret, orderIC []float64) (ic, orthIC []float64) {
+	ic = make([]float64, len(feats))
+	orthIC = make([]float64, len(feats))
+	var order []int
+	for i, f := range feats {
+		if len(f) == len(ret) && len(ret) > 0 {
+			ic[i] = Pearson(f, ret)
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return math.Abs(orderIC[order[a]]) > math.Abs(orderIC[order[b]])
+	})
+
+	// basis holds the unit-norm residuals kept so far.
+	var basis [][]float64
+	for _, i := range order {
+		r := centered(feats[i])
+		norm0 := math.Sqrt(dot(r, r))
+		if norm0 == 0 {
+			continue
+		}
+		for _, q := range basis {
+			p := dot(r, q)
+			for k := range r {
+				r[k] -= p * q[k]
+			}
+		}
+		norm := math.Sqrt(dot(r, r))
+		if norm <= 1e-8*norm0 {
+			continue
+		}
+		orthIC[i] = Pearson(r, ret)
+		for k := range r {
+			r[k] /= norm
+		}
+		basis = append(basis, r)
+	}
+	return ic, orthIC
+}
+
+// SelectModels greedily picks up to k features in descending |IC| order,
+// skipping any whose |rho| with an already picked one reaches maxCorr, and
+// returns their indices in pick order. The sign of an IC doesn't matter: a
+// reliably wrong signal is a usable one flipped. Features with zero IC (no
+// data) are never picked. Like OrthogonalICs' order, ics and corr should
+// come from the train segment, so the picks can be judged on test.
+func SelectModels(corr [][]float64, ics []float64, maxCorr float64, k int) []int {
+	order := make([]int, 0, len(ics))
+	for i, ic := range ics {
+		if ic != 0 {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return math.Abs(ics[order[a]]) > math.Abs(ics[order[b]])
+	})
+
+	var picked []int
+	for _, i := range order {
+		if len(picked) >= k {
+			break
+		}
+		ok := true
+		for _, j := range picked {
+			if math.Abs(corr[i][j]) >= maxCorr {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			picked = append(picked, i)
+		}
+	}
+	return picked
+}
+
+// centered returns a copy of x minus its mean.
+func centered(x []float64) []float64 {
+	var m float64
+	for _, v := range x {
+		m += v
+	}
+	m /= float64(len(x))
+	out := make([]float64, len(x))
+	for k, v := range x {
+		out[k] = v - m
+	}
+	return out
+}
+
+func dot(a, b []float64) float64 {
+	var s float64
+	for k := range a {
+		s += a[k] * b[k]
+	}
+	return s
+}
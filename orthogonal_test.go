@@ -0,0 +1,81 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// TestOrthogonalICsFollowGivenOrder checks that the Gram-Schmidt order is
+// orderIC's, not the ICs being reported: whichever feature comes first
+// keeps its full IC, the other keeps only what it adds, and a multiple of
+// an earlier feature adds nothing.
+func TestOrthogonalICsFollowGivenOrder(t *testing.T) {
+	const n = 4000
+	rng := rand.New(rand.NewSource(1))
+	a, b, ret := make([]float64, n), make([]float64, n), make([]float64, n)
+	ab, dup := make([]float64, n), make([]float64, n)
+	for i := range n {
+		a[i], b[i] = rng.NormFloat64(), rng.NormFloat64()
+		ret[i] = a[i] + b[i] + rng.NormFloat64()
+		ab[i] = a[i] + 0.5*b[i]
+		dup[i] = 2*a[i] + 1
+	}
+	feats := [][]float64{a, ab, dup, a[:n/2]}
+
+	for _, tc := range []struct {
+		name    string
+		orderIC []float64
+		first   int
+	}{
+		{"a first", []float64{0.3, 0.2, 0.1, 0.9}, 0},
+		{"a+b/2 first", []float64{0.2, -0.3, 0.1, 0.9}, 1},
+	} {
+		ic, orth := OrthogonalICs(feats, ret, tc.orderIC)
+		if ic[tc.first] != orth[tc.first] {
+			t.Errorf("%s: first feature's orthIC %v != IC %v", tc.name, orth[tc.first], ic[tc.first])
+		}
+		second := 1 - tc.first
+		if math.Abs(orth[second]) >= math.Abs(ic[second])-0.05 {
+			t.Errorf("%s: second feature's orthIC %v not below its IC %v", tc.name, orth[second], ic[second])
+		}
+		if orth[2] != 0 {
+			t.Errorf("%s: orthIC of a multiple of a = %v, want 0", tc.name, orth[2])
+		}
+		if ic[3] != 0 || orth[3] != 0 {
+			t.Errorf("%s: short column got IC %v, orthIC %v", tc.name, ic[3], orth[3])
+		}
+	}
+}
+
+func TestSelectModels(t *testing.T) {
+	// Features 0 and 1 are near duplicates, 2 is moderately correlated
+	// with 0, 3 and 4 are independent of everything.
+	corr := [][]float64{
+		{1, 0.95, 0.5, 0, 0},
+		{0.95, 1, 0.4, 0, 0},
+		{0.5, 0.4, 1, 0, 0},
+		{0, 0, 0, 1, 0},
+		{0, 0, 0, 0, 1},
+	}
+	for _, tc := range []struct {
+		name    string
+		ics     []float64
+		maxCorr float64
+		k       int
+		want    []int
+	}{
+		{"cutoff drops the duplicate", []float64{0.05, 0.04, 0.03, 0.02, 0.01}, 0.9, 5, []int{0, 2, 3, 4}},
+		{"rho at the cutoff is dropped", []float64{0.05, 0.04, 0.03, 0.02, 0.01}, 0.5, 5, []int{0, 3, 4}},
+		{"k caps the picks", []float64{0.05, 0.04, 0.03, 0.02, 0.01}, 0.9, 2, []int{0, 2}},
+		{"sign doesn't matter", []float64{0.01, -0.06, 0.03, -0.02, 0.04}, 0.9, 5, []int{1, 4, 2, 3}},
+		{"zero IC never picked", []float64{0, 0.04, 0, 0.02, 0}, 0.9, 5, []int{1, 3}},
+		{"k = 0", []float64{0.05, 0.04, 0.03, 0.02, 0.01}, 0.9, 0, nil},
+	} {
+		got := SelectModels(corr, tc.ics, tc.maxCorr, tc.k)
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("%s: picked %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
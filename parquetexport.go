@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// exportResultContainers writes one symbol's sampled features and labels to
+// Features_<SYMBOL>.parquet for model training outside this program: one row
+// per sample, with columns time (epoch ms), <model>_feat and <horizon>_ret,
+// in chronological order. Every (horizon, model) cell holds the same samples,
+// already sorted by time (see streamTasks), so cell [0][0] supplies the
+// times and rows are written in cell order.
+func exportResultContainers(sym string, modelNames []string, results [][]*ResultContainer) (string, error) {
+	path := fmt.Sprintf("Features_%s.parquet", sym)
+	if len(results) == 0 || len(modelNames) == 0 {
+		return path, fmt.Errorf("no results")
+	}
+	times := results[0][0].Times
+	n := len(times)
+	for hIdx := range results {
+		for mIdx := range results[hIdx] {
+			if len(results[hIdx][mIdx].Times) != n {
+				return path, fmt.Errorf("cell %s/%s has %d samples, want %d",
+					modelNames[mIdx], HorizonLabels[hIdx], len(results[hIdx][mIdx].Times), n)
+			}
+		}
+	}
+
+	group := parquet.Group{"time": parquet.Timestamp(parquet.Millisecond)}
+	for _, name := range modelNames {
+		group[name+"_feat"] = parquet.Leaf(parquet.DoubleType)
+	}
+	for _, label := range HorizonLabels {
+		group[label+"_ret"] = parquet.Leaf(parquet.DoubleType)
+	}
+	schema := parquet.NewSchema("features", group)
+
+	// Group orders its columns by name; map each back to its source.
+	cols := schema.Columns()
+	colIdx := make(map[string]int, len(cols))
+	for i, c := range cols {
+		colIdx[c[0]] = i
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return path, err
+	}
+	defer f.Close()
+	w := parquet.NewWriter(f, schema)
+
+	const batch = 4096
+	rows := make([]parquet.Row, 0, batch)
+	for i := range n {
+		row := make(parquet.Row, len(cols))
+		c := colIdx["time"]
+		row[c] = parquet.Int64Value(int64(times[i])).Level(0, 0, c)
+		for mIdx, name := range modelNames {
+			c = colIdx[name+"_feat"]
+			row[c] = parquet.DoubleValue(results[0][mIdx].Feats[i]).Level(0, 0, c)
+		}
+		for hIdx, label := range HorizonLabels {
+			c = colIdx[label+"_ret"]
+			row[c] = parquet.DoubleValue(results[hIdx][0].Targs[i]).Level(0, 0, c)
+		}
+		rows = append(rows, row)
+		if len(rows) == batch {
+			if _, err := w.WriteRows(rows); err != nil {
+				return path, err
+			}
+			rows = rows[:0]
+		}
+	}
+	if _, err := w.WriteRows(rows); err != nil {
+		return path, err
+	}
+	if err := w.Close(); err != nil {
+		return path, err
+	}
+	return path, f.Close()
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// dayPrices is one processed day's first and last trade price.
+type dayPrices struct {
+	Day         ofiTask
+	First, Last float64
+}
+
+// priceBreak is a discontinuity between two consecutive processed days,
+// e.g. a contract multiplier change or a 1000x symbol rename. Ratio is the
+// day's first price over the previous day's last.
+type priceBreak struct {
+	Prev, Day ofiTask
+	Ratio     float64
+}
+
+// isPriceBreak reports whether going from price prev to next falls outside
+// the plausible band [1/PriceBreakRatio, PriceBreakRatio].
+func isPriceBreak(prev, next float64) bool {
+	if PriceBreakRatio <= 1 || prev <= 0 || next <= 0 {
+		return false
+	}
+	return math.Abs(math.Log(next/prev)) > math.Log(PriceBreakRatio)
+}
+
+// detectPriceBreaks sorts days chronologically and returns every break
+// between consecutive ones. Days missing from the data are skipped over, so
+// a break is still caught across a gap.
+func detectPriceBreaks(days []dayPrices) []priceBreak {
+	sort.Slice(days, func(i, j int) bool { return days[i].Day.before(days[j].Day) })
+	var out []priceBreak
+	for i := 1; i < len(days); i++ {
+		if isPriceBreak(days[i-1].Last, days[i].First) {
+			out = append(out, priceBreak{
+				Prev:  days[i-1].Day,
+				Day:   days[i].Day,
+				Ratio: days[i].First / days[i-1].Last,
+			})
+		}
+	}
+	return out
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestDetectPriceBreaks1000x(t *testing.T) {
+	days := []dayPrices{
+		{ofiTask{2024, 1, 3}, 40.1, 40.3},
+		{ofiTask{2024, 1, 1}, 40000, 40500},
+		{ofiTask{2024, 1, 2}, 40400, 40100}, // 1000x down overnight into the 3rd
+	}
+	breaks := detectPriceBreaks(days)
+	if len(breaks) != 1 {
+		t.Fatalf("got %d breaks, want 1: %+v", len(breaks), breaks)
+	}
+	b := breaks[0]
+	if b.Prev != (ofiTask{2024, 1, 2}) || b.Day != (ofiTask{2024, 1, 3}) {
+		t.Fatalf("break %v -> %v, want 2024-01-02 -> 2024-01-03", b.Prev, b.Day)
+	}
+	if math.Abs(b.Ratio-40.1/40100) > 1e-12 {
+		t.Fatalf("ratio = %g, want %g", b.Ratio, 40.1/40100)
+	}
+}
+
+// TestCrossDayLabelsOverPriceBreak streams two days with a 1000x drop
+// between them. Cross-day labels must never carry the jump: "split" drops
+// them and "adjust" rescales the next day first. With detection off, the
+// jump shows up as a label of about log(1/1000).
+func TestCrossDayLabelsOverPriceBreak(t *testing.T) {
+	root := t.TempDir()
+	withBaseDir(t, root)
+	rng := rand.New(rand.NewSource(1))
+	writeSynthMonth(t, root, "BTCUSDT", 2024, 1, map[int]synthDay{
+		1: randomDay(ofiTask{2024, 1, 1}, 20000, 40000, rng),
+		2: randomDay(ofiTask{2024, 1, 2}, 20000, 40, rng),
+	})
+	defer func(cross bool, mode string, ratio float64) {
+		CrossDayLabels, PriceBreakMode, PriceBreakRatio = cross, mode, ratio
+	}(CrossDayLabels, PriceBreakMode, PriceBreakRatio)
+	CrossDayLabels = true
+
+	// maxLabel streams both days and returns the breaks found and the
+	// largest |label| over every horizon.
+	maxLabel := func() (int, float64) {
+		so := streamTasks("BTCUSDT", symbolTasks("BTCUSDT"), specFactory(sweepSpecs("Hawkes_OFI", []float64{60})), 0)
+		var m float64
+		for _, row := range so.Results {
+			for _, r := range row[0].Targs {
+				m = math.Max(m, math.Abs(r))
+			}
+		}
+		return len(so.Breaks), m
+	}
+	jump := math.Log(1000)
+
+	for _, mode := range []string{"split", "adjust"} {
+		PriceBreakMode, PriceBreakRatio = mode, 1.5
+		breaks, m := maxLabel()
+		if breaks != 1 {
+			t.Fatalf("%s: %d breaks detected, want 1", mode, breaks)
+		}
+		if m > jump/2 {
+			t.Fatalf("%s: a label of %g spans the break", mode, m)
+		}
+	}
+
+	PriceBreakRatio = 0
+	breaks, m := maxLabel()
+	if breaks != 0 {
+		t.Fatalf("detection off: %d breaks detected", breaks)
+	}
+	if m < jump/2 {
+		t.Fatalf("detection off: largest label %g, want the ~%g jump", m, jump)
+	}
+}
+
+// TestCrossDayLabelsFromNextDay streams a flat day at 100 followed by a flat
+// day at 110. With CrossDayLabels the first day's samples within a horizon
+// of its close are labeled from the next day, log(110/100); without it they
+// are dropped. Samples earlier in the day label 0 either way.
+func TestCrossDayLabelsFromNextDay(t *testing.T) {
+	root := t.TempDir()
+	withBaseDir(t, root)
+	rng := rand.New(rand.NewSource(3))
+	flat := func(day int, p float64) synthDay {
+		d := randomDay(ofiTask{2024, 1, day}, 20000, p, rng)
+		for i := range d.Prices {
+			d.Prices[i] = p
+		}
+		return d
+	}
+	day1 := flat(1, 100)
+	writeSynthMonth(t, root, "BTCUSDT", 2024, 1, map[int]synthDay{1: day1, 2: flat(2, 110)})
+	defer func(prev bool) { CrossDayLabels = prev }(CrossDayLabels)
+	close1 := float64(day1.Times[len(day1.Times)-1])
+	day2 := float64(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC).UnixMilli())
+	jump := math.Log(110.0 / 100)
+
+	for _, cross := range []bool{false, true} {
+		CrossDayLabels = cross
+		so := streamTasks("BTCUSDT", symbolTasks("BTCUSDT"), specFactory(sweepSpecs("Hawkes_OFI", []float64{60})), 0)
+		for h, row := range so.Results {
+			c := row[0]
+			late := 0
+			for i, ts := range c.Times {
+				if ts >= day2 {
+					continue
+				}
+				switch {
+				case ts+float64(HorizonDelays[h]) <= close1:
+					if c.Targs[i] != 0 {
+						t.Fatalf("cross=%v h=%d: label %g inside the day, want 0", cross, h, c.Targs[i])
+					}
+				case !cross:
+					t.Fatalf("h=%d: sample at %v past the day's labels kept without CrossDayLabels", h, ts)
+				default:
+					if math.Abs(c.Targs[i]-jump) > 1e-12 {
+						t.Fatalf("h=%d: cross-day label %g, want %g", h, c.Targs[i], jump)
+					}
+					late++
+				}
+			}
+			if cross && late == 0 {
+				t.Fatalf("h=%d: no sample labeled from the next day", h)
+			}
+		}
+	}
+}
@@ -3,15 +3,25 @@ package main
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"sort"
 	"text/tabwriter"
 	"time"
 )
 
 // RunProbe performs a fast diagnostic over all symbols under BaseDir.
-// It samples up to 16 days per symbol, runs LoadGNCFile + InflateGNC,
-// and reports which symbols have healthy blobs.
-func RunProbe() {
+// It samples up to 16 days per symbol, runs LoadGNCFile + InflateGNCSafe
+// (so a corrupt blob is reported, not a crash), and reports which symbols
+// have healthy blobs.
+//
+// With deep set it decodes every indexed day instead and also checks the
+// trades themselves (see dayViolations): a blob that decodes but has
+// backwards timestamps or non-positive prices breaks RunStream's binary
+// search and the return labels, so it counts as BAD.
+//
+// Every month index is also checked for days listed more than once (see
+// duplicateDays); lookups take the first row, which may be the stale one.
+func RunProbe(deep bool) {
 	start := time.Now()
 
 	fmt.Println(">>> GNC DATA PROBE <<<")
@@ -29,19 +39,37 @@ func RunProbe() {
 	sort.Strings(symbols)
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "SYMBOL\tIDX_DAYS\tSAMPLED\tOK\tFAIL\tFIRST_DAY\tLAST_DAY\tMIN_ROWS\tMAX_ROWS\tAVG_ROWS")
-	fmt.Fprintln(w, "------\t--------\t-------\t--\t----\t---------\t--------\t--------\t--------\t--------")
+	fmt.Fprintln(w, "SYMBOL\tIDX_DAYS\tDUP\tSAMPLED\tOK\tFAIL\tBAD\tFIRST_DAY\tLAST_DAY\tMIN_ROWS\tMAX_ROWS\tAVG_ROWS")
+	fmt.Fprintln(w, "------\t--------\t---\t-------\t--\t----\t---\t---------\t--------\t--------\t--------\t--------")
 
 	const samplePerSymbol = 16
 
 	for _, sym := range symbols {
+		dupCount := 0
+		for dir := range discoverMonthDirs(sym) {
+			dups := duplicateDays(filepath.Join(dir, "index.quantdev"))
+			if len(dups) == 0 {
+				continue
+			}
+			days := make([]int, 0, len(dups))
+			for d, offs := range dups {
+				days = append(days, d)
+				dupCount += len(offs) - 1
+			}
+			sort.Ints(days)
+			fmt.Printf("  [%s] %s  STATUS=DUP_DAYS    days=%d (lookups use the first row; run compact)\n", sym, dir, len(days))
+			for _, d := range days {
+				fmt.Printf("      day %02d offsets=%v\n", d, dups[d])
+			}
+		}
+
 		// Collect all tasks (days) for this symbol.
 		var tasks []ofiTask
 		for t := range discoverTasks(sym) {
 			tasks = append(tasks, t)
 		}
 		if len(tasks) == 0 {
-			fmt.Fprintf(w, "%-8s\t0\t0\t0\t0\t-\t-\t0\t0\t0\n", sym)
+			fmt.Fprintf(w, "%-8s\t0\t%d\t0\t0\t0\t0\t-\t-\t0\t0\t0\n", sym, dupCount)
 			continue
 		}
 
@@ -62,7 +90,7 @@ func RunProbe() {
 
 		// Determine which indices to sample (spread across the history).
 		sampled := samplePerSymbol
-		if idxDays < sampled {
+		if deep || idxDays < sampled {
 			sampled = idxDays
 		}
 		var sampleIdxs []int
@@ -89,6 +117,7 @@ func RunProbe() {
 
 		okCount := 0
 		failCount := 0
+		badCount := 0
 		var minRows, maxRows, totalRows int
 
 		for _, idx := range sampleIdxs {
@@ -102,7 +131,7 @@ func RunProbe() {
 				)
 				continue
 			}
-			rows, err := InflateGNC(buf, cols)
+			rows, err := InflateGNCSafe(buf, cols)
 			if err != nil || rows <= 0 {
 				failCount++
 				fmt.Printf(
@@ -111,6 +140,16 @@ func RunProbe() {
 				)
 				continue
 			}
+			if deep {
+				if backwards, badPrices := dayViolations(cols); backwards+badPrices > 0 {
+					badCount++
+					fmt.Printf(
+						"  [%s] %04d-%02d-%02d  STATUS=DATA_BAD    rows=%d backwards_times=%d nonpositive_prices=%d\n",
+						sym, t.Year, t.Month, t.Day, rows, backwards, badPrices,
+					)
+					continue
+				}
+			}
 
 			okCount++
 			if okCount == 1 {
@@ -138,12 +177,14 @@ func RunProbe() {
 
 		fmt.Fprintf(
 			w,
-			"%-8s\t%d\t%d\t%d\t%d\t%s\t%s\t%d\t%d\t%d\n",
+			"%-8s\t%d\t%d\t%d\t%d\t%d\t%d\t%s\t%s\t%d\t%d\t%d\n",
 			sym,
 			idxDays,
+			dupCount,
 			sampled,
 			okCount,
 			failCount,
+			badCount,
 			firstStr,
 			lastStr,
 			minRows,
@@ -155,3 +196,40 @@ func RunProbe() {
 	w.Flush()
 	fmt.Printf("\n[probe] Finished in %s\n", time.Since(start))
 }
+
+// dayViolations counts the decoded trades that break what the pipeline
+// assumes of a day: timestamps that go backwards (RunStream labels by binary
+// search over them) and prices that aren't positive (labels are log ratios).
+func dayViolations(cols *DayColumns) (backwards, badPrices int) {
+	for i := 0; i < cols.Count; i++ {
+		if i > 0 && cols.Times[i] < cols.Times[i-1] {
+			backwards++
+		}
+		if !(cols.Prices[i] > 0) {
+			badPrices++
+		}
+	}
+	return backwards, badPrices
+}
+
+// duplicateDays returns the blob offsets, in index order, of every day that
+// has more than one row in a month index. updateIndex appends without
+// deduplicating, so a re-ingested day keeps its stale row ahead of the new
+// one. A missing or unreadable index yields nil; that is LOAD_FAIL's to
+// report.
+func duplicateDays(idxPath string) map[int][]uint64 {
+	rows, err := readIndexRows(idxPath)
+	if err != nil {
+		return nil
+	}
+	offsets := make(map[int][]uint64, len(rows))
+	for _, r := range rows {
+		offsets[r.Day] = append(offsets[r.Day], r.Offset)
+	}
+	for d, offs := range offsets {
+		if len(offs) < 2 {
+			delete(offsets, d)
+		}
+	}
+	return offsets
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// P2Quantile tracks one quantile of a stream in O(1) memory with the P²
+// algorithm (Jain & Chlamtac, 1985): five markers whose heights follow the
+// min, p/2, p, (1+p)/2 and max quantiles, adjusted by piecewise-parabolic
+// interpolation as observations arrive.
+type P2Quantile struct {
+	p   float64
+	n   int
+	q   [5]float64 // marker heights
+	pos [5]float64 // actual marker positions (1-based)
+	des [5]float64 // desired marker positions
+	inc [5]float64 // desired position increments per observation
+}
+
+func NewP2Quantile(p float64) *P2Quantile {
+	return &P2Quantile{p: p}
+}
+
+func (e *P2Quantile) Reset() {
+	*e = P2Quantile{p: e.p}
+}
+
+func (e *P2Quantile) Add(x float64) {
+	if e.n < 5 {
+		e.q[e.n] = x
+		e.n++
+		if e.n == 5 {
+			sort.Float64s(e.q[:])
+			p := e.p
+			e.pos = [5]float64{1, 2, 3, 4, 5}
+			e.des = [5]float64{1, 1 + 2*p, 1 + 4*p, 3 + 2*p, 5}
+			e.inc = [5]float64{0, p / 2, p, (1 + p) / 2, 1}
+		}
+		return
+	}
+	e.n++
+
+	// Cell k holding x; extend the extremes if needed.
+	var k int
+	switch {
+	case x < e.q[0]:
+		e.q[0] = x
+		k = 0
+	case x >= e.q[4]:
+		e.q[4] = x
+		k = 3
+	default:
+		for k = 0; k < 3 && x >= e.q[k+1]; k++ {
+		}
+	}
+	for i := k + 1; i < 5; i++ {
+		e.pos[i]++
+	}
+	for i := range e.des {
+		e.des[i] += e.inc[i]
+	}
+
+	// Move the middle markers toward their desired positions.
+	for i := 1; i <= 3; i++ {
+		d := e.des[i] - e.pos[i]
+		if (d >= 1 && e.pos[i+1]-e.pos[i] > 1) || (d <= -1 && e.pos[i-1]-e.pos[i] < -1) {
+			s := math.Copysign(1, d)
+			qp := e.parabolic(i, s)
+			if e.q[i-1] < qp && qp < e.q[i+1] {
+				e.q[i] = qp
+			} else {
+				j := i + int(s)
+				e.q[i] += s * (e.q[j] - e.q[i]) / (e.pos[j] - e.pos[i])
+			}
+			e.pos[i] += s
+		}
+	}
+}
+
+func (e *P2Quantile) parabolic(i int, s float64) float64 {
+	q, n := e.q, e.pos
+	return q[i] + s/(n[i+1]-n[i-1])*
+		((n[i]-n[i-1]+s)*(q[i+1]-q[i])/(n[i+1]-n[i])+
+			(n[i+1]-n[i]-s)*(q[i]-q[i-1])/(n[i]-n[i-1]))
+}
+
+// Value returns the current estimate; exact over the first five
+// observations, 0 before any.
+func (e *P2Quantile) Value() float64 {
+	if e.n >= 5 {
+		return e.q[2]
+	}
+	if e.n == 0 {
+		return 0
+	}
+	tmp := append([]float64(nil), e.q[:e.n]...)
+	sort.Float64s(tmp)
+	return tmp[int(e.p*float64(e.n-1)+0.5)]
+}
+
+// Count returns the number of observations added since Reset.
+func (e *P2Quantile) Count() int { return e.n }
+
+// adaptiveClamp clips a feature to its own running [ClampLowQ, ClampHighQ]
+// quantiles, so the clip adapts to each feature's natural scale. Values pass
+// through unclipped until clampWarmup observations have been seen.
+type adaptiveClamp struct {
+	lo, hi *P2Quantile
+}
+
+const clampWarmup = 100
+
+func newAdaptiveClamp() *adaptiveClamp {
+	return &adaptiveClamp{lo: NewP2Quantile(ClampLowQ), hi: NewP2Quantile(ClampHighQ)}
+}
+
+func (c *adaptiveClamp) apply(x float64) float64 {
+	if math.IsNaN(x) {
+		return x
+	}
+	c.lo.Add(x)
+	c.hi.Add(x)
+	if c.lo.Count() < clampWarmup {
+		return x
+	}
+	return math.Min(math.Max(x, c.lo.Value()), c.hi.Value())
+}
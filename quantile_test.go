@@ -0,0 +1,64 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestP2QuantileMatchesExact(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	xs := make([]float64, 20000)
+	for _, p := range []float64{0.01, 0.5, 0.99} {
+		e := NewP2Quantile(p)
+		for i := range xs {
+			xs[i] = rng.NormFloat64()
+			e.Add(xs[i])
+		}
+		sort.Float64s(xs)
+		exact := xs[int(p*float64(len(xs)-1))]
+		if math.Abs(e.Value()-exact) > 0.05 {
+			t.Fatalf("p=%g: P² %.4f, exact %.4f", p, e.Value(), exact)
+		}
+	}
+}
+
+// TestAdaptiveClampFollowsScale feeds one clamp a feature with a small
+// natural range and another one with a large range: the first must clip an
+// outlier far tighter, each near its own 1st/99th percentile.
+func TestAdaptiveClampFollowsScale(t *testing.T) {
+	defer func(lo, hi float64) { ClampLowQ, ClampHighQ = lo, hi }(ClampLowQ, ClampHighQ)
+	ClampLowQ, ClampHighQ = 0.01, 0.99
+
+	rng := rand.New(rand.NewSource(1))
+	small, large := newAdaptiveClamp(), newAdaptiveClamp()
+	for range 20000 {
+		small.apply(0.01 * rng.NormFloat64())
+		large.apply(100 * rng.NormFloat64())
+	}
+
+	const z99 = 2.326 // standard normal 99th percentile
+	for _, c := range []struct {
+		name  string
+		clamp *adaptiveClamp
+		sigma float64
+	}{{"small", small, 0.01}, {"large", large, 100}} {
+		hi, lo := c.clamp.apply(1e9), c.clamp.apply(-1e9)
+		if math.Abs(hi/(z99*c.sigma)-1) > 0.15 || math.Abs(lo/(-z99*c.sigma)-1) > 0.15 {
+			t.Fatalf("%s: clipped to [%g, %g], want about ±%g", c.name, lo, hi, z99*c.sigma)
+		}
+	}
+	if s, l := small.apply(1e9), large.apply(1e9); s >= l/1000 {
+		t.Fatalf("small-range clip %g not tighter than large-range %g", s, l)
+	}
+}
+
+func TestAdaptiveClampWarmup(t *testing.T) {
+	c := newAdaptiveClamp()
+	for i := range clampWarmup - 1 {
+		if got := c.apply(float64(i)); got != float64(i) {
+			t.Fatalf("clipped %d to %g during warm-up", i, got)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// indexRow is an indexEntry together with the day it belongs to, in the
+// order it appears (or will appear) in index.quantdev.
+type indexRow struct {
+	Day int
+	indexEntry
+}
+
+// RunReindex rebuilds index.quantdev for every month directory under BaseDir
+// by walking data.quantdev blob by blob. It is meant for recovering a lost or
+// truncated index when the data file is intact. A month whose rebuilt rows
+// equal its current index is left alone, so running it on a healthy tree
+// writes nothing; every changed row is printed as a diff. With dryRun set
+// the diff is all it does.
+func RunReindex(dryRun bool) {
+	start := time.Now()
+
+	fmt.Println(">>> INDEX REBUILD <<<")
+	fmt.Printf("BaseDir: %s\n", BaseDir)
+	if dryRun {
+		fmt.Println("Dry run: no index is written.")
+	}
+	fmt.Println()
+
+	var symbols []string
+	for sym := range discoverSymbols() {
+		symbols = append(symbols, sym)
+	}
+	sort.Strings(symbols)
+
+	var rewritten, unchanged, days, failed int
+	for _, sym := range symbols {
+		for dir := range discoverMonthDirs(sym) {
+			rows, diff, skipped, err := reindexMonth(dir, dryRun)
+			if err != nil {
+				failed++
+				fmt.Printf("  [%s] %s  STATUS=FAIL reason=%v\n", sym, dir, err)
+				continue
+			}
+			days += len(rows)
+			if len(diff) == 0 {
+				unchanged++
+				continue
+			}
+			rewritten++
+			fmt.Printf("  [%s] %s  days=%d changed_rows=%d skipped_bytes=%d\n", sym, dir, len(rows), len(diff), skipped)
+			for _, d := range diff {
+				fmt.Printf("      %s\n", d)
+			}
+		}
+	}
+
+	verb := "rewritten"
+	if dryRun {
+		verb = "would be rewritten"
+	}
+	fmt.Printf("\n[reindex] %d months %s, %d unchanged (%d days), %d failed, in %s\n",
+		rewritten, verb, unchanged, days, failed, time.Since(start))
+}
+
+// reindexMonth scans dir/data.quantdev and, unless dryRun is set or nothing
+// changed, atomically replaces dir/index.quantdev. It returns the rebuilt
+// rows, their diff against the current index (empty when equal), and the
+// number of bytes that had to be skipped to resynchronise on a blob
+// boundary.
+func reindexMonth(dir string, dryRun bool) ([]indexRow, []string, int64, error) {
+	year, month, err := monthOfDir(dir)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	data, release, err := mapDataFile(filepath.Join(dir, "data.quantdev"))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	defer release()
+
+	idxPath := filepath.Join(dir, "index.quantdev")
+	old, _ := readIndexRows(idxPath) // a lost or truncated index is why we're here
+	rows, skipped := scanBlobs(data, year, month, old)
+	if len(rows) == 0 {
+		return nil, nil, skipped, fmt.Errorf("no valid blobs in data.quantdev")
+	}
+
+	diff := diffIndexRows(old, rows)
+	if len(diff) == 0 || dryRun {
+		return rows, diff, skipped, nil
+	}
+	if err := writeIndex(idxPath, rows); err != nil {
+		return nil, nil, skipped, err
+	}
+	return rows, diff, skipped, nil
+}
+
+// mapDataFile maps a data.quantdev read-only (see mmapFile), so scanning a
+// month doesn't copy it onto the heap. release unmaps it.
+func mapDataFile(path string) (data []byte, release func(), err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	if fi.Size() == 0 {
+		return nil, func() {}, nil
+	}
+	if int64(int(fi.Size())) != fi.Size() {
+		return nil, nil, fmt.Errorf("data file too large to map (%d bytes)", fi.Size())
+	}
+	data, err = mmapFile(f, int(fi.Size()))
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, func() { munmap(data) }, nil
+}
+
+// diffIndexRows describes how rebuilt rows differ from the current index, one
+// line per day: "+" added, "-" dropped, "~" moved or resized. Duplicate rows
+// in the current index count as a change too, since the rebuild drops them.
+func diffIndexRows(old, rows []indexRow) []string {
+	if slices.Equal(old, rows) {
+		return nil
+	}
+	oldByDay := make(map[int][]indexEntry, len(old))
+	for _, r := range old {
+		oldByDay[r.Day] = append(oldByDay[r.Day], r.indexEntry)
+	}
+	var diff []string
+	seen := make(map[int]bool, len(rows))
+	for _, r := range rows {
+		seen[r.Day] = true
+		prev := oldByDay[r.Day]
+		switch {
+		case len(prev) == 0:
+			diff = append(diff, fmt.Sprintf("+ day %02d offset=%d length=%d", r.Day, r.Offset, r.Length))
+		case len(prev) > 1 || prev[0] != r.indexEntry:
+			diff = append(diff, fmt.Sprintf("~ day %02d offset=%d length=%d (was %s)", r.Day, r.Offset, r.Length, entriesString(prev)))
+		}
+	}
+	for _, r := range old {
+		if !seen[r.Day] {
+			seen[r.Day] = true
+			diff = append(diff, fmt.Sprintf("- day %02d (was %s)", r.Day, entriesString(oldByDay[r.Day])))
+		}
+	}
+	if len(diff) == 0 {
+		// Same rows in a different order.
+		diff = append(diff, "~ row order")
+	}
+	return diff
+}
+
+func entriesString(es []indexEntry) string {
+	parts := make([]string, len(es))
+	for i, e := range es {
+		parts[i] = fmt.Sprintf("offset=%d length=%d", e.Offset, e.Length)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// scanBlobs walks a data.quantdev image and returns one row per day, sorted
+// by day. Blobs are assumed to start on a CacheLine boundary and to extend
+// to the end of their last column rounded up to CacheLine. That length is
+// only an inference, so where a row of the current index (old) starts at
+// the same offset and its checksum matches the bytes it spans, its length
+// is used instead. Anything that doesn't parse as a TBV1 header is skipped
+// a cache line at a time. If a day appears more than once (a re-ingest),
+// the later blob wins.
+func scanBlobs(data []byte, year, month int, old []indexRow) ([]indexRow, int64) {
+	byDay := make(map[int]indexRow)
+	var skipped int64
+
+	known := make(map[uint64]indexEntry, len(old))
+	for _, r := range old {
+		end := r.Offset + r.Length
+		if r.Length > 0 && end >= r.Offset && end <= uint64(len(data)) &&
+			blobChecksum(data[r.Offset:end]) == r.Checksum {
+			known[r.Offset] = r.indexEntry
+		}
+	}
+
+	for off := 0; off+TBHdrSize <= len(data); {
+		h, err := parseTBHeader(data[off:], uint64(len(data)-off))
+		if err != nil {
+			off += CacheLine
+			skipped += CacheLine
+			continue
+		}
+
+		end := uint64(h.OffBits) + h.BitWords*8
+		for _, colOff := range []uint32{h.OffAgg, h.OffPrice, h.OffQty, h.OffFirst, h.OffLast, h.OffTime} {
+			if e := uint64(colOff) + h.Rows*8; e > end {
+				end = e
+			}
+		}
+		end = (end + CacheLine - 1) &^ (CacheLine - 1)
+		if end > uint64(len(data)-off) {
+			end = uint64(len(data) - off)
+		}
+		if e, ok := known[uint64(off)]; ok && e.Length >= TBHdrSize {
+			end = e.Length
+		}
+		raw := data[off : off+int(end)]
+
+		tb, err := mapTradeBlock(raw)
+		if err != nil {
+			off += CacheLine
+			skipped += CacheLine
+			continue
+		}
+
+		first := time.UnixMilli(tb.Times[0]).UTC()
+		if first.Year() != year || int(first.Month()) != month {
+			// Blob belongs to another month's file; don't index it here.
+			off += len(raw)
+			skipped += int64(len(raw))
+			continue
+		}
+
+		byDay[first.Day()] = indexRow{
+			Day: first.Day(),
+			indexEntry: indexEntry{
+				Offset:   uint64(off),
+				Length:   uint64(len(raw)),
+				Checksum: blobChecksum(raw),
+			},
+		}
+		off += len(raw)
+	}
+
+	rows := make([]indexRow, 0, len(byDay))
+	for _, r := range byDay {
+		rows = append(rows, r)
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Day < rows[j].Day })
+	return rows, skipped
+}
+
+// writeIndex writes rows as a fresh index.quantdev via a temp file + rename,
+// so a crash never leaves a half-written index behind. Header bytes 4..8 are
+// carried over from the existing index when there is one.
+func writeIndex(idxPath string, rows []indexRow) error {
+	var hdr [16]byte
+	if f, err := os.Open(idxPath); err == nil {
+		var old [16]byte
+		if _, err := io.ReadFull(f, old[:]); err == nil && string(old[0:4]) == IdxMagic {
+			copy(hdr[4:8], old[4:8])
+		}
+		f.Close()
+	}
+	copy(hdr[0:4], IdxMagic)
+	binary.LittleEndian.PutUint64(hdr[8:16], uint64(len(rows)))
+
+	buf := make([]byte, 0, len(hdr)+26*len(rows))
+	buf = append(buf, hdr[:]...)
+	var row [26]byte
+	for _, r := range rows {
+		binary.LittleEndian.PutUint16(row[0:2], uint16(r.Day))
+		binary.LittleEndian.PutUint64(row[2:10], r.Offset)
+		binary.LittleEndian.PutUint64(row[10:18], r.Length)
+		binary.LittleEndian.PutUint64(row[18:26], r.Checksum)
+		buf = append(buf, row[:]...)
+	}
+
+	tmp := idxPath + ".tmp"
+	if err := os.WriteFile(tmp, buf, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, idxPath)
+}
+
+// monthOfDir parses the trailing YYYY/MM of a month directory.
+func monthOfDir(dir string) (year, month int, err error) {
+	month, err = strconv.Atoi(filepath.Base(dir))
+	if err != nil {
+		return 0, 0, fmt.Errorf("bad month dir %q", dir)
+	}
+	year, err = strconv.Atoi(filepath.Base(filepath.Dir(dir)))
+	if err != nil {
+		return 0, 0, fmt.Errorf("bad year dir %q", dir)
+	}
+	return year, month, nil
+}
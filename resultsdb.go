@@ -0,0 +1,260 @@
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// ResultsDB appends report rows to a SQLite database so results can be
+// queried across runs and symbols. Every row carries the run timestamp and a
+// hash of the tunables that shaped it, so runs under different settings can
+// be told apart.
+type ResultsDB struct {
+	db         *sql.DB
+	runTS      string
+	configHash string
+}
+
+// dbColumn is one SQLite column and its declared type.
+type dbColumn struct {
+	Name, Type string
+}
+
+// dbTable is a results table: the run/symbol/model/horizon prefix columns
+// (dbPrefixColumns) followed by Columns, in insert order.
+type dbTable struct {
+	Name    string
+	Columns []dbColumn
+}
+
+var dbPrefixColumns = cols("run_ts TEXT, config_hash TEXT, symbol TEXT, model TEXT, horizon TEXT")
+
+var reportStatsTable = dbTable{"report_stats", cols(`
+	train_n INTEGER, test_n INTEGER, effective_n REAL, suppressed INTEGER,
+	pearson_ic REAL, pearson_ic_low REAL, pearson_ic_high REAL,
+	ic_tstat REAL, ic_tstat_hac REAL, ic_p REAL, ic_q REAL,
+	daily_ic_days INTEGER, daily_ic_mean REAL, daily_ic_tstat REAL, daily_ic_tstat_nw REAL, spearman_ic REAL, kendall_tau REAL, dcor REAL, hit_rate REAL, hit_rate_z REAL,
+	decile_mean TEXT, decile_count TEXT, decile_stderr TEXT, thin_buckets INTEGER, top_decile_bps REAL, bottom_decile_bps REAL, spread_bps REAL,
+	mi REAL, nmi REAL, mi_rank REAL, nmi_rank REAL,
+	baseline_logloss REAL, signal_logloss REAL, delta_logloss REAL, delta_logloss_rank REAL,
+	vol_scale REAL, is_sharpe REAL, sharpe REAL, sharpe_low REAL, sharpe_high REAL, sharpe_ann REAL, gross_sharpe REAL, net_sharpe REAL, flips INTEGER, turnover REAL,
+	max_drawdown REAL, max_dd_duration INTEGER, avg_underwater REAL, avg_trade REAL, avg_win REAL, avg_loss REAL, win_loss_ratio REAL,
+	trade_skew REAL, trade_kurtosis REAL, dsr REAL`)}
+
+var windowMetricsTable = dbTable{"window_metrics", cols(`
+	win INTEGER, start_time REAL, end_time REAL, count INTEGER,
+	pearson_ic REAL, spearman_ic REAL, hit_rate REAL, sharpe REAL`)}
+
+var regimeMetricsTable = dbTable{"regime_metrics", cols(`
+	kind TEXT, regime TEXT, count INTEGER,
+	pearson_ic REAL, spearman_ic REAL, hit_rate REAL, sharpe REAL`)}
+
+var resultsTables = []dbTable{reportStatsTable, windowMetricsTable, regimeMetricsTable}
+
+// resultsSchemaVersion is stored in the database's user_version. Bump it
+// whenever a table gains columns; older databases are migrated on open by
+// adding the missing columns (their old rows read NULL there), and a
+// database from a newer build is refused.
+const resultsSchemaVersion = 2
+
+// cols parses "name TYPE, name TYPE, ..." into columns.
+func cols(decl string) []dbColumn {
+	var out []dbColumn
+	for _, d := range strings.Split(decl, ",") {
+		f := strings.Fields(d)
+		out = append(out, dbColumn{f[0], f[1]})
+	}
+	return out
+}
+
+// OpenResultsDB opens (creating if needed) the database at path and brings
+// its schema up to resultsSchemaVersion. The run timestamp is fixed here, so
+// every symbol of one run shares it.
+func OpenResultsDB(path string, modelNames []string) (*ResultsDB, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := migrateResultsDB(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init schema: %w", err)
+	}
+	return &ResultsDB{
+		db:         db,
+		runTS:      time.Now().UTC().Format(time.RFC3339),
+		configHash: configHash(modelNames),
+	}, nil
+}
+
+// migrateResultsDB creates missing tables and adds missing columns to
+// existing ones, then records resultsSchemaVersion.
+func migrateResultsDB(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version > resultsSchemaVersion {
+		return fmt.Errorf("database schema version %d is newer than this build's %d", version, resultsSchemaVersion)
+	}
+
+	for _, t := range resultsTables {
+		all := append(slices.Clone(dbPrefixColumns), t.Columns...)
+		decls := make([]string, len(all))
+		for i, c := range all {
+			decls[i] = c.Name + " " + c.Type
+		}
+		if _, err := db.Exec("CREATE TABLE IF NOT EXISTS " + t.Name + " (" + strings.Join(decls, ", ") + ")"); err != nil {
+			return err
+		}
+
+		have := make(map[string]bool)
+		rows, err := db.Query("SELECT name FROM pragma_table_info(?)", t.Name)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				rows.Close()
+				return err
+			}
+			have[name] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		for _, c := range all {
+			if have[c.Name] {
+				continue
+			}
+			if _, err := db.Exec("ALTER TABLE " + t.Name + " ADD COLUMN " + c.Name + " " + c.Type); err != nil {
+				return fmt.Errorf("add %s.%s: %w", t.Name, c.Name, err)
+			}
+		}
+	}
+
+	if _, err := db.Exec("CREATE INDEX IF NOT EXISTS report_stats_run ON report_stats(run_ts, symbol)"); err != nil {
+		return err
+	}
+	_, err := db.Exec(fmt.Sprintf("PRAGMA user_version = %d", resultsSchemaVersion))
+	return err
+}
+
+func (r *ResultsDB) Close() error {
+	return r.db.Close()
+}
+
+// configHash fingerprints the settings that change report numbers: the
+// sampling, day selection, labels, splits and metric tunables.
+func configHash(modelNames []string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "rate=%d align=%v sampling=%s trades=%d volume=%g frac=%g seed=%d from=%v to=%v ",
+		SamplingRateSec, AlignSamplingGrid, SamplingMode, SampleTrades, SampleVolume,
+		SampleFrac, SampleSeed, DayFrom, DayTo)
+	fmt.Fprintf(h, "horizons=%v returns=%s crossday=%v breaks=%s barrier=%v k=%g clamp=%v lowmem=%v purge=%v embargo=%d ",
+		HorizonDelays, ReturnMode, CrossDayLabels, PriceBreakMode, TripleBarrier, BarrierK,
+		AdaptiveClamp, LowMemory, PurgeSplit, EmbargoSamples)
+	fmt.Fprintf(h, "rank=%v vol=%g minN=%g cost=%g buckets=%d mibins=%d mibias=%v models=%s",
+		RankNormMetrics, VolTarget, MinEffectiveSamples, CostBps, CurveBuckets, MIBins, MIBiasCorrection,
+		strings.Join(modelNames, ","))
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// ResultsBatch collects one symbol's rows in a single transaction. A nil
+// *ResultsBatch discards everything, so callers needn't check whether
+// export is enabled.
+type ResultsBatch struct {
+	r   *ResultsDB
+	tx  *sql.Tx
+	sym string
+	err error
+}
+
+func (r *ResultsDB) Begin(sym string) (*ResultsBatch, error) {
+	if r == nil {
+		return nil, nil
+	}
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &ResultsBatch{r: r, tx: tx, sym: sym}, nil
+}
+
+// insert writes one row into t, prefixed with the run, symbol, model and
+// horizon columns. Columns are named, so a migrated table's column order
+// doesn't matter. It records the first error and turns later calls into
+// no-ops; Commit reports it.
+func (b *ResultsBatch) insert(t dbTable, model, horizon string, vals ...any) {
+	if b == nil || b.err != nil {
+		return
+	}
+	if len(vals) != len(t.Columns) {
+		b.err = fmt.Errorf("%s: %d values for %d columns", t.Name, len(vals), len(t.Columns))
+		return
+	}
+	args := append([]any{b.r.runTS, b.r.configHash, b.sym, model, horizon}, vals...)
+	names := make([]string, 0, len(args))
+	for _, c := range dbPrefixColumns {
+		names = append(names, c.Name)
+	}
+	for _, c := range t.Columns {
+		names = append(names, c.Name)
+	}
+	query := "INSERT INTO " + t.Name + " (" + strings.Join(names, ", ") + ") VALUES (" +
+		strings.TrimSuffix(strings.Repeat("?,", len(args)), ",") + ")"
+	_, b.err = b.tx.Exec(query, args...)
+}
+
+func (b *ResultsBatch) Stats(model, horizon string, s ReportStats) {
+	deciles, _ := json.Marshal(s.DecileMean)
+	counts, _ := json.Marshal(s.DecileCount)
+	stdErrs, _ := json.Marshal(s.DecileStdErr)
+	b.insert(reportStatsTable, model, horizon,
+		s.TrainCount, s.TestCount, s.EffectiveN, s.Suppressed,
+		s.PearsonIC, s.PearsonICLow, s.PearsonICHigh,
+		s.ICTStat, s.ICTStatHAC, s.ICPValue, s.ICQValue,
+		s.DailyICDays, s.DailyICMean, s.DailyICTStat, s.DailyICTStatNW, s.SpearmanIC, s.KendallTau, s.DistCorr, s.HitRate, s.HitRateZ,
+		string(deciles), string(counts), string(stdErrs), s.ThinBuckets, s.TopDecileRetBps, s.BottomDecileRetBps, s.SpreadBps,
+		s.MutualInfo, s.NormalizedMI, s.MutualInfoRank, s.NormalizedMIRank,
+		s.BaselineLogLoss, s.SignalLogLoss, s.DeltaLogLoss, s.DeltaLogLossRank,
+		s.VolScale, s.ISSharpe, s.Sharpe, s.SharpeLow, s.SharpeHigh, s.SharpeAnn, s.GrossSharpe, s.NetSharpe, s.Flips, s.Turnover,
+		s.MaxDrawdown, s.MaxDDDuration, s.AvgUnderwater, s.AvgTrade, s.AvgWin, s.AvgLoss, s.WinLossRatio,
+		s.TradeSkew, s.TradeKurtosis, s.DSR,
+	)
+}
+
+func (b *ResultsBatch) Window(model, horizon string, win int, wm WindowMetrics) {
+	b.insert(windowMetricsTable, model, horizon,
+		win, wm.StartTime, wm.EndTime, wm.Count,
+		wm.PearsonIC, wm.SpearmanIC, wm.HitRate, wm.Sharpe,
+	)
+}
+
+// Regime stores one regime row; kind is "vol" or "tod".
+func (b *ResultsBatch) Regime(model, horizon, kind string, rm RegimeMetrics) {
+	b.insert(regimeMetricsTable, model, horizon,
+		kind, rm.Name, rm.Count,
+		rm.PearsonIC, rm.SpearmanIC, rm.HitRate, rm.Sharpe,
+	)
+}
+
+func (b *ResultsBatch) Commit() error {
+	if b == nil {
+		return nil
+	}
+	if b.err != nil {
+		b.tx.Rollback()
+		return b.err
+	}
+	return b.tx.Commit()
+}
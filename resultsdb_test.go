@@ -0,0 +1,121 @@
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestResultsDBMigratesOldSchema opens a database created by an earlier,
+// narrower report_stats and checks that rows still insert.
+func TestResultsDBMigratesOldSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.db")
+	old, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := old.Exec(`CREATE TABLE report_stats (
+		run_ts TEXT, config_hash TEXT, symbol TEXT, model TEXT, horizon TEXT,
+		train_n INTEGER, test_n INTEGER, pearson_ic REAL, sharpe REAL)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := old.Exec(`INSERT INTO report_stats VALUES ('old', 'h', 'BTCUSDT', 'm', '1h', 1, 2, 0.1, 0.2)`); err != nil {
+		t.Fatal(err)
+	}
+	old.Close()
+
+	db, err := OpenResultsDB(path, []string{"m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := db.Begin("ETHUSDT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Stats("m", "1h", ReportStats{TrainCount: 10, TestCount: 5, PearsonIC: 0.03, ICQValue: 0.5})
+	b.Window("m", "1h", 0, WindowMetrics{Count: 3})
+	b.Regime("m", "1h", "vol", RegimeMetrics{Name: "low", Count: 3})
+	if err := b.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	var n int
+	var q sql.NullFloat64
+	if err := db.db.QueryRow(`SELECT COUNT(*) FROM report_stats`).Scan(&n); err != nil || n != 2 {
+		t.Fatalf("rows = %d, %v; want 2", n, err)
+	}
+	if err := db.db.QueryRow(`SELECT ic_q FROM report_stats WHERE symbol = 'ETHUSDT'`).Scan(&q); err != nil || q.Float64 != 0.5 {
+		t.Fatalf("ic_q = %v, %v; want 0.5", q, err)
+	}
+	if err := db.db.QueryRow(`SELECT ic_q FROM report_stats WHERE symbol = 'BTCUSDT'`).Scan(&q); err != nil || q.Valid {
+		t.Fatalf("migrated old row ic_q = %v, %v; want NULL", q, err)
+	}
+	db.Close()
+}
+
+func TestResultsDBRefusesNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("PRAGMA user_version = 99"); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	if _, err := OpenResultsDB(path, nil); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Fatalf("err = %v, want a newer-schema refusal", err)
+	}
+}
+
+// TestConfigHashCoversSettings checks that each setting that changes
+// report numbers (the MI settings, the splits' purge and embargo, and the
+// sampling, day-selection and labeling knobs) changes the config hash.
+func TestConfigHashCoversSettings(t *testing.T) {
+	models := []string{"m"}
+	base := configHash(models)
+	for _, c := range []struct {
+		name   string
+		change func() (undo func())
+	}{
+		{"MIBins", func() func() { MIBins++; return func() { MIBins-- } }},
+		{"MIBiasCorrection", toggle(&MIBiasCorrection)},
+		{"PurgeSplit", toggle(&PurgeSplit)},
+		{"EmbargoSamples", func() func() { EmbargoSamples--; return func() { EmbargoSamples++ } }},
+		{"ReturnMode", swap(&ReturnMode, "micromid")},
+		{"SamplingMode", swap(&SamplingMode, "trades")},
+		{"SampleTrades", swap(&SampleTrades, 500)},
+		{"SampleVolume", swap(&SampleVolume, 12.5)},
+		{"SampleFrac", swap(&SampleFrac, 0.5)},
+		{"SampleSeed", swap(&SampleSeed, 7)},
+		{"DayFrom", swap(&DayFrom, ofiTask{2024, 1, 1})},
+		{"DayTo", swap(&DayTo, ofiTask{2024, 6, 30})},
+		{"CrossDayLabels", toggle(&CrossDayLabels)},
+		{"PriceBreakMode", swap(&PriceBreakMode, "adjust")},
+		{"TripleBarrier", toggle(&TripleBarrier)},
+		{"AdaptiveClamp", toggle(&AdaptiveClamp)},
+		{"LowMemory", toggle(&LowMemory)},
+	} {
+		undo := c.change()
+		if configHash(models) == base {
+			t.Errorf("%s doesn't change the config hash", c.name)
+		}
+		undo()
+	}
+	if configHash(models) != base {
+		t.Fatal("settings not restored")
+	}
+}
+
+// swap returns a change for TestConfigHashCoversSettings that sets *p to v.
+func swap[T any](p *T, v T) func() func() {
+	return func() func() {
+		prev := *p
+		*p = v
+		return func() { *p = prev }
+	}
+}
+
+func toggle(p *bool) func() func() { return swap(p, !*p) }
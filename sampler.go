@@ -0,0 +1,141 @@
+package main
+
+import "math"
+
+// SampleState is what a Sampler sees for each trade RunStream processes.
+type SampleState struct {
+	T int64   // trade time, ms
+	Q float64 // trade quantity
+}
+
+// Sampler decides on which trades RunStream snapshots the models. Samplers
+// are stateful: RunStream calls Reset with the day's first trade time, then
+// ShouldSample once per trade in order. Give each worker its own instance.
+type Sampler interface {
+	Reset(firstT int64)
+	ShouldSample(st SampleState) bool
+}
+
+// TimeSampler fires on the first trade at or after each grid point spaced
+// StepMs apart (the original wall-clock behaviour). With Align the grid sits
+// on UTC multiples of StepMs; otherwise it starts at the first trade.
+type TimeSampler struct {
+	StepMs int64
+	Align  bool
+
+	next int64
+}
+
+// NewTimeSampler returns the default sampler: every SamplingRateSec seconds,
+// aligned per AlignSamplingGrid.
+func NewTimeSampler() *TimeSampler {
+	return &TimeSampler{StepMs: SamplingRateSec * 1000, Align: AlignSamplingGrid}
+}
+
+func (s *TimeSampler) Reset(firstT int64) {
+	s.next = firstT + s.StepMs
+	if s.Align {
+		// First grid point strictly after the first trade.
+		s.next = (firstT/s.StepMs + 1) * s.StepMs
+	}
+}
+
+func (s *TimeSampler) ShouldSample(st SampleState) bool {
+	if st.T < s.next {
+		return false
+	}
+	for st.T >= s.next {
+		s.next += s.StepMs
+	}
+	return true
+}
+
+// VolumeSampler fires each time cumulative traded quantity crosses another
+// multiple of Bucket (a volume clock). A single huge print that spans several
+// buckets still yields one sample.
+type VolumeSampler struct {
+	Bucket float64
+
+	cum float64
+}
+
+func (s *VolumeSampler) Reset(int64) { s.cum = 0 }
+
+func (s *VolumeSampler) ShouldSample(st SampleState) bool {
+	if s.Bucket <= 0 {
+		return false
+	}
+	s.cum += st.Q
+	if s.cum < s.Bucket {
+		return false
+	}
+	s.cum = math.Mod(s.cum, s.Bucket)
+	return true
+}
+
+// TradeCountSampler fires on every N-th trade (a tick clock).
+type TradeCountSampler struct {
+	N int
+
+	count int
+}
+
+func (s *TradeCountSampler) Reset(int64) { s.count = 0 }
+
+func (s *TradeCountSampler) ShouldSample(SampleState) bool {
+	s.count++
+	if s.N <= 0 || s.count < s.N {
+		return false
+	}
+	s.count = 0
+	return true
+}
+
+// newDaySampler returns the sampler SamplingMode selects for one day. A
+// SampleTrades or SampleVolume of 0 sizes the trade or volume clock from
+// prior, the previous calendar day's trades, so that it fires about as often
+// as the time clock did then; sizing from the day being sampled would let
+// the clock know the day's activity in advance. It returns nil when the
+// clock needs prior and there is none (the first day of the data, or after
+// a gap); the caller skips that day.
+func newDaySampler(prior *DayColumns) Sampler {
+	switch SamplingMode {
+	case "trades":
+		n := SampleTrades
+		if n <= 0 {
+			if prior == nil || prior.Count == 0 {
+				return nil
+			}
+			n, _ = eventBudget(prior)
+		}
+		return &TradeCountSampler{N: n}
+	case "volume":
+		bucket := SampleVolume
+		if bucket <= 0 {
+			if prior == nil || prior.Count == 0 {
+				return nil
+			}
+			_, bucket = eventBudget(prior)
+		}
+		return &VolumeSampler{Bucket: bucket}
+	default:
+		return NewTimeSampler()
+	}
+}
+
+// samplerNeedsPriorDay reports whether newDaySampler sizes its clock from
+// the previous day.
+func samplerNeedsPriorDay() bool {
+	return (SamplingMode == "trades" && SampleTrades <= 0) ||
+		(SamplingMode == "volume" && SampleVolume <= 0)
+}
+
+// eventBudget returns the trade count (at least 1) and traded quantity per
+// SamplingRateSec of one day's trades.
+func eventBudget(cols *DayColumns) (trades int, volume float64) {
+	perDay := 86400 / SamplingRateSec
+	for _, q := range cols.Qtys[:cols.Count] {
+		volume += q
+	}
+	return max(cols.Count/perDay, 1), volume / float64(perDay)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestEventClockSizedFromPriorDay checks that a zero SampleTrades or
+// SampleVolume takes its rate from the prior day alone, and that without
+// one there is no sampler.
+func TestEventClockSizedFromPriorDay(t *testing.T) {
+	defer func(mode string, n int, v float64) {
+		SamplingMode, SampleTrades, SampleVolume = mode, n, v
+	}(SamplingMode, SampleTrades, SampleVolume)
+	SampleTrades, SampleVolume = 0, 0
+
+	perDay := 86400 / SamplingRateSec
+	prior := &DayColumns{Count: 3 * perDay, Qtys: make([]float64, 3*perDay)}
+	for i := range prior.Qtys {
+		prior.Qtys[i] = 0.5
+	}
+
+	SamplingMode = "trades"
+	if s := newDaySampler(nil); s != nil {
+		t.Fatalf("trades clock without a prior day: %#v, want nil", s)
+	}
+	if s, ok := newDaySampler(prior).(*TradeCountSampler); !ok || s.N != 3 {
+		t.Fatalf("trades clock = %#v, want every 3rd trade", s)
+	}
+
+	SamplingMode = "volume"
+	if s := newDaySampler(nil); s != nil {
+		t.Fatalf("volume clock without a prior day: %#v, want nil", s)
+	}
+	if s, ok := newDaySampler(prior).(*VolumeSampler); !ok || s.Bucket != 1.5 {
+		t.Fatalf("volume clock = %#v, want a 1.5 bucket", s)
+	}
+
+	SamplingMode, SampleTrades = "trades", 7
+	if s, ok := newDaySampler(nil).(*TradeCountSampler); !ok || s.N != 7 {
+		t.Fatalf("fixed trades clock = %#v, want every 7th trade", s)
+	}
+	SamplingMode = "time"
+	if newDaySampler(nil) == nil {
+		t.Fatal("time clock needs no prior day")
+	}
+}
+
+// TestEventClockSkipsDayWithoutPrior streams two consecutive days on the
+// trades clock: the first has no prior day and is skipped, the second is
+// sampled at the first day's rate.
+func TestEventClockSkipsDayWithoutPrior(t *testing.T) {
+	root := t.TempDir()
+	withBaseDir(t, root)
+	rng := rand.New(rand.NewSource(1))
+	writeSynthMonth(t, root, "BTCUSDT", 2024, 1, map[int]synthDay{
+		1: randomDay(ofiTask{2024, 1, 1}, 5000, 40000, rng),
+		2: randomDay(ofiTask{2024, 1, 2}, 50000, 40000, rng),
+	})
+	defer func(mode string, n int) { SamplingMode, SampleTrades = mode, n }(SamplingMode, SampleTrades)
+	SamplingMode, SampleTrades = "trades", 0
+
+	so := streamTasks("BTCUSDT", symbolTasks("BTCUSDT"), specFactory(sweepSpecs("Hawkes_OFI", []float64{60})), 0)
+	if len(so.DaySamples) != 1 {
+		t.Fatalf("sampled %d days, want 1 (the day with a prior)", len(so.DaySamples))
+	}
+	// 5000 trades the day before: every 3rd trade, ~16667 before labels
+	// run past the day. Sizing from the day itself would give ~1440.
+	if n := so.DaySamples[0]; n < 10000 {
+		t.Fatalf("%d samples on day 2, want the prior day's rate (~16000)", n)
+	}
+}
+
+// TestAlignedGridIgnoresFirstTradeOffset samples two days that trade over
+// the same window on the same 250ms tape, one from a grid point and one
+// from half a step later. On the aligned grid both get the same samples;
+// the per-day grid of the later day loses one.
+func TestAlignedGridIgnoresFirstTradeOffset(t *testing.T) {
+	defer func(prev bool) { AlignSamplingGrid = prev }(AlignSamplingGrid)
+
+	step := int64(SamplingRateSec * 1000)
+	start, end := 10*3600*1000-10*3600*1000%step, int64(11*3600*1000)
+	count := func(firstT int64) int {
+		s := NewTimeSampler()
+		s.Reset(firstT)
+		n := 0
+		for ts := start; ts <= end; ts += 250 {
+			if ts < firstT {
+				continue
+			}
+			if s.ShouldSample(SampleState{T: ts, Q: 1}) {
+				n++
+			}
+		}
+		return n
+	}
+
+	AlignSamplingGrid = true
+	a, b := count(start), count(start+step/2)
+	if a != b {
+		t.Fatalf("aligned grid: %d samples vs %d with a later first trade", a, b)
+	}
+	if want := int((end - start) / step); a != want {
+		t.Fatalf("aligned grid: %d samples, want %d", a, want)
+	}
+
+	AlignSamplingGrid = false
+	if a, b := count(start), count(start+step/2); a == b {
+		t.Fatalf("per-day grid: %d samples for both offsets, want them to differ", a)
+	}
+}
+
+// TestVolumeClockHugePrint checks that a print spanning many buckets fires
+// once and carries only the remainder into the next bucket, and that a
+// non-positive bucket never fires.
+func TestVolumeClockHugePrint(t *testing.T) {
+	s := &VolumeSampler{Bucket: 2}
+	s.Reset(0)
+	if !s.ShouldSample(SampleState{Q: 1e12 + 1.5}) {
+		t.Fatal("huge print did not fire")
+	}
+	if s.cum != 1.5 {
+		t.Fatalf("carried %v into the next bucket, want 1.5", s.cum)
+	}
+	if !s.ShouldSample(SampleState{Q: 0.5}) || s.ShouldSample(SampleState{Q: 0.5}) {
+		t.Fatal("remainder not carried into the next bucket")
+	}
+
+	for _, b := range []float64{0, -1} {
+		s := &VolumeSampler{Bucket: b}
+		s.Reset(0)
+		if s.ShouldSample(SampleState{Q: 10}) {
+			t.Fatalf("bucket %v fired", b)
+		}
+	}
+}
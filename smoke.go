@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// smokeHorizonLabels and smokeHorizonDelays are the horizons RunSmoke
+// labels with. They are shorter than HorizonDelays so a single day's test
+// segment clears MinEffectiveSamples: at the 60s grid a 15m horizon leaves
+// only ~28 independent test samples, and every cell would be suppressed.
+var smokeHorizonLabels = []string{"1m", "5m"}
+var smokeHorizonDelays = []int64{60 * 1000, 5 * 60 * 1000}
+
+// RunSmoke runs the whole research pipeline (load -> decode -> stream ->
+// OOS metrics) on a single day of the preferred symbol and returns the
+// number of (model, horizon) cells it reported, or an error naming the
+// first stage that produced empty output. A cell suppressed for too few
+// effective samples counts as a metrics failure. Meant for CI and quick
+// "is the wiring still intact" checks, not for research.
+func RunSmoke() (int, error) {
+	start := time.Now()
+	sym := Symbol()
+
+	fmt.Println(">>> SMOKE TEST <<<")
+	fmt.Printf("BaseDir: %s | Symbol: %s\n", BaseDir, sym)
+
+	// 1) Discovery: use the first indexed day that loads.
+	var task ofiTask
+	var buf []byte
+	found := false
+	for t := range discoverTasks(sym) {
+		if LoadGNCFile(BaseDir, sym, t, &buf) {
+			task, found = t, true
+			break
+		}
+	}
+	if !found {
+		return 0, fmt.Errorf("load: no loadable day for %s", sym)
+	}
+	fmt.Printf("  load     %04d-%02d-%02d  bytes=%d\n", task.Year, task.Month, task.Day, len(buf))
+
+	// 2) Decode.
+	cols := DayColumnPool.Get().(*DayColumns)
+	defer DayColumnPool.Put(cols)
+	rows, err := InflateGNC(buf, cols)
+	if err != nil {
+		return 0, fmt.Errorf("decode: %w", err)
+	}
+	if rows == 0 {
+		return 0, fmt.Errorf("decode: zero rows")
+	}
+	fmt.Printf("  decode   rows=%d\n", rows)
+
+	// 3) Stream.
+	models := GetContinuousModels()
+	res := RunStream(cols, nil, models, smokeHorizonDelays, NewTimeSampler(), nil)
+	if len(res.Times) == 0 {
+		return 0, fmt.Errorf("stream: no labeled samples")
+	}
+	fmt.Printf("  stream   samples=%d models=%d horizons=%d\n", len(res.Times), res.NumModels, res.NumHorizons)
+
+	// 4) Metrics, per (model, horizon), on the same flat layout test.go uses.
+	n := len(res.Times)
+	reported := 0
+	for mIdx, m := range models {
+		for hIdx, label := range smokeHorizonLabels {
+			times := make([]float64, n)
+			feats := make([]float64, n)
+			targs := make([]float64, n)
+			for s := 0; s < n; s++ {
+				times[s] = float64(res.Times[s])
+				feats[s] = res.Features[s*res.NumModels+mIdx]
+				targs[s] = res.Targets[s*res.NumHorizons+hIdx]
+			}
+			stats := AnalyzeFullSuiteOOS(times, feats, targs, 0.7, smokeHorizonDelays[hIdx])
+			if stats.TestCount == 0 {
+				return 0, fmt.Errorf("metrics: empty test split for %s/%s", m.Name(), label)
+			}
+			if stats.Suppressed {
+				return 0, fmt.Errorf("metrics: %s/%s suppressed (effective n=%.1f < %g)", m.Name(), label, stats.EffectiveN, MinEffectiveSamples)
+			}
+			reported++
+		}
+	}
+	fmt.Printf("  metrics  cells=%d\n", reported)
+
+	fmt.Printf("\n[smoke] OK in %s\n", time.Since(start))
+	return reported, nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestRunSmokeSynthetic runs the smoke pipeline end to end on a synthetic
+// one-day tree, so it needs no data under the real BaseDir.
+func TestRunSmokeSynthetic(t *testing.T) {
+	root := t.TempDir()
+	withBaseDir(t, root)
+	rng := rand.New(rand.NewSource(1))
+	writeSynthMonth(t, root, "BTCUSDT", 2024, 1, map[int]synthDay{
+		2: randomDay(ofiTask{2024, 1, 2}, 20000, 40000, rng),
+	})
+
+	cells, err := RunSmoke()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := len(GetContinuousModels()) * len(smokeHorizonDelays); cells != want {
+		t.Fatalf("reported %d cells, want %d", cells, want)
+	}
+}
+
+func TestRunSmokeEmptyTree(t *testing.T) {
+	withBaseDir(t, t.TempDir())
+	if _, err := RunSmoke(); err == nil {
+		t.Fatal("RunSmoke succeeded with no data")
+	}
+}
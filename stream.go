@@ -3,6 +3,7 @@ package main
 import (
 	"math"
 	"sort"
+	"time"
 )
 
 type StreamResult struct {
@@ -12,31 +13,134 @@ type StreamResult struct {
 	Targets     []float64 // [sample * numHorizons]
 	NumModels   int
 	NumHorizons int
+
+	// Triple-barrier mode only (see TripleBarrier), [sample * numHorizons]:
+	// the barrier hit (+1 up, -1 down, 0 horizon expired) and the time of
+	// the touch or expiry. Targets then hold the log return at that time.
+	Labels     []int8
+	TouchTimes []int64
+}
+
+// BarrierSummary tallies one horizon's triple-barrier labels: how many hit
+// the upper or lower barrier or expired at the horizon, and their summed
+// holding time (touch or expiry minus sample time).
+type BarrierSummary struct {
+	Up, Down, Expired int
+	HoldMs            float64
+}
+
+func (b *BarrierSummary) add(label int8, holdMs int64) {
+	switch {
+	case label > 0:
+		b.Up++
+	case label < 0:
+		b.Down++
+	default:
+		b.Expired++
+	}
+	b.HoldMs += float64(holdMs)
+}
+
+func (b *BarrierSummary) merge(o BarrierSummary) {
+	b.Up += o.Up
+	b.Down += o.Down
+	b.Expired += o.Expired
+	b.HoldMs += o.HoldMs
+}
+
+// N is the number of labels tallied.
+func (b BarrierSummary) N() int { return b.Up + b.Down + b.Expired }
+
+// MeanHold is the average holding period, 0 with no labels.
+func (b BarrierSummary) MeanHold() time.Duration {
+	if b.N() == 0 {
+		return 0
+	}
+	return time.Duration(b.HoldMs / float64(b.N()) * float64(time.Millisecond))
+}
+
+// StreamBuffers holds RunStream's per-day slices so a worker can reuse them
+// across days instead of allocating (and collecting) them every call, the
+// way DayColumnPool does for decoded trades. Give each worker its own.
+type StreamBuffers struct {
+	times, touchTimes  []int64
+	prices, features   []float64
+	targets, sampleVar []float64
+	mid, nextMid       []float64
+	labels             []int8
+	sampleTick         []int
+}
+
+// reuseSlice returns s resized to n, reallocating only when it is too small.
+// Contents are not cleared.
+func reuseSlice[T any](s []T, n int) []T {
+	if cap(s) < n {
+		return make([]T, n, n+n/4)
+	}
+	return s[:n]
 }
 
-func RunStream(cols *DayColumns, models []ContinuousModel) StreamResult {
+// RunStream feeds one day of trades through models, snapshots them whenever
+// sampler fires, and labels each snapshot with forward log returns at each
+// of horizons (ms delays). A nil horizons uses the package HorizonDelays; a
+// nil sampler uses NewTimeSampler().
+//
+// With a non-nil buf the result's slices live in buf and stay valid only
+// until the next RunStream call with it; nil allocates fresh slices.
+//
+// Samples whose horizon runs past the day's last trade are dropped, unless
+// next holds the following day's trades, in which case the label is looked
+// up there (cross-day labeling). next is only read for labels; it never
+// feeds the models.
+//
+// With AdaptiveClamp set, every model output is clipped to its running
+// quantiles over the day's ticks before it is sampled.
+//
+// With ReturnMode "micromid", labels are taken from the micro-mid (see
+// microMid) instead of the last trade price, at the sample and the horizon.
+//
+// With TripleBarrier set, each label instead walks forward from the sample
+// until price moves ±BarrierK trailing sigmas (scaled to the horizon) or the
+// horizon expires, whichever comes first; validity is unchanged.
+func RunStream(cols, next *DayColumns, models []ContinuousModel, horizons []int64, sampler Sampler, buf *StreamBuffers) StreamResult {
 	n := cols.Count
 	if n < 100 {
 		return StreamResult{}
 	}
+	if horizons == nil {
+		horizons = HorizonDelays
+	}
+	if sampler == nil {
+		sampler = NewTimeSampler()
+	}
+	if buf == nil {
+		buf = &StreamBuffers{}
+	}
 
 	numModels := len(models)
-	numHorizons := len(HorizonDelays)
+	numHorizons := len(horizons)
 
-	for _, m := range models {
+	// TickModels get the whole Tick; the rest only (dt, p, v).
+	ticked := make([]TickModel, numModels)
+	for j, m := range models {
 		m.Reset()
+		ticked[j], _ = m.(TickModel)
 	}
+	hasSides := len(cols.Sides) >= n
+	hasMatches := len(cols.Matches) >= n
 
-	// Rough capacity estimate: one sample per minute.
-	estSamples := n / 60
-	if estSamples < 1 {
-		estSamples = 1
+	// Rough capacity estimate for fresh buffers: one sample per minute.
+	estSamples := max(n/60, 1)
+	if buf.times == nil {
+		buf.times = make([]int64, 0, estSamples)
+		buf.prices = make([]float64, 0, estSamples)
+		buf.features = make([]float64, 0, estSamples*numModels)
 	}
 
 	res := StreamResult{
-		Times:       make([]int64, 0, estSamples),
-		Prices:      make([]float64, 0, estSamples),
-		Features:    make([]float64, 0, estSamples*numModels),
+		Times:       buf.times[:0],
+		Prices:      buf.prices[:0],
+		Features:    buf.features[:0],
 		Targets:     nil, // filled after labeling
 		NumModels:   numModels,
 		NumHorizons: numHorizons,
@@ -45,8 +149,30 @@ func RunStream(cols *DayColumns, models []ContinuousModel) StreamResult {
 	// Scratch slice reused per tick to hold model outputs.
 	currFeats := make([]float64, numModels)
 
+	// Per-model adaptive clamps, fresh each day like the models.
+	var clamps []*adaptiveClamp
+	if AdaptiveClamp {
+		clamps = make([]*adaptiveClamp, numModels)
+		for j := range clamps {
+			clamps[j] = newAdaptiveClamp()
+		}
+	}
+
+	// Prices the labels are computed from: last trade, or the micro-mid.
+	prices := cols.Prices
+	if ReturnMode == "micromid" {
+		buf.mid = microMid(buf.mid, cols)
+		prices = buf.mid
+	}
+
+	// Triple-barrier state: the tick index and trailing variance rate
+	// (log-return variance per second) at each sample.
+	sampleTick := buf.sampleTick[:0]
+	sampleVar := buf.sampleVar[:0]
+	var vol barrierVol
+
 	lastT := cols.Times[0]
-	nextSampleT := lastT + (SamplingRateSec * 1000)
+	sampler.Reset(lastT)
 
 	for i := 0; i < n; i++ {
 		t := cols.Times[i]
@@ -59,34 +185,72 @@ func RunStream(cols *DayColumns, models []ContinuousModel) StreamResult {
 		}
 		lastT = t
 
+		tick := Tick{DT: dt, P: p, V: v}
+		if hasSides {
+			tick.Side = cols.Sides[i]
+		}
+		if hasMatches {
+			tick.Matches = cols.Matches[i]
+		}
+
 		for j, m := range models {
-			currFeats[j] = m.Update(dt, p, v)
+			if ticked[j] != nil {
+				currFeats[j] = ticked[j].UpdateTick(tick)
+			} else {
+				currFeats[j] = m.Update(dt, p, v)
+			}
+			if clamps != nil {
+				currFeats[j] = clamps[j].apply(currFeats[j])
+			}
+		}
+		if TripleBarrier {
+			vol.update(dt, p)
 		}
 
-		if t >= nextSampleT {
+		if sampler.ShouldSample(SampleState{T: t, Q: v}) {
 			// Append one sample row.
 			res.Times = append(res.Times, t)
-			res.Prices = append(res.Prices, p)
+			res.Prices = append(res.Prices, prices[i])
 			res.Features = append(res.Features, currFeats...)
-
-			for t >= nextSampleT {
-				nextSampleT += (SamplingRateSec * 1000)
+			if TripleBarrier {
+				sampleTick = append(sampleTick, i)
+				sampleVar = append(sampleVar, vol.rate())
 			}
 		}
 	}
 
+	// Keep whatever the appends grew for the next day.
+	buf.times, buf.prices, buf.features = res.Times, res.Prices, res.Features
+	buf.sampleTick, buf.sampleVar = sampleTick, sampleVar
+
 	sampleCount := len(res.Times)
 	if sampleCount == 0 {
 		return StreamResult{}
 	}
 
-	// Lookahead labeling on the flat arrays.
+	// Lookahead labeling on the flat arrays. Every row that survives has
+	// all its targets written, so the reused slices need no clearing.
 	maxTime := cols.Times[n-1]
-	res.Targets = make([]float64, sampleCount*numHorizons)
+	buf.targets = reuseSlice(buf.targets, sampleCount*numHorizons)
+	res.Targets = buf.targets
+	if TripleBarrier {
+		buf.labels = reuseSlice(buf.labels, sampleCount*numHorizons)
+		buf.touchTimes = reuseSlice(buf.touchTimes, sampleCount*numHorizons)
+		res.Labels, res.TouchTimes = buf.labels, buf.touchTimes
+	}
+
+	nextN := 0
+	var nextPrices []float64
+	if next != nil && next.Count > 0 && next.Times[0] > maxTime {
+		nextN = next.Count
+		nextPrices = next.Prices
+		if ReturnMode == "micromid" {
+			buf.nextMid = microMid(buf.nextMid, next)
+			nextPrices = buf.nextMid
+		}
+	}
 
 	validCount := 0
-	ticksTimes := cols.Times
-	ticksPrices := cols.Prices
 
 	for i := 0; i < sampleCount; i++ {
 		basePrice := res.Prices[i]
@@ -95,18 +259,22 @@ func RunStream(cols *DayColumns, models []ContinuousModel) StreamResult {
 		valid := true
 		baseTarg := validCount * numHorizons
 
-		for hIdx, delay := range HorizonDelays {
+		for hIdx, delay := range horizons {
 			targetT := sampleT + delay
+			ticksTimes, ticksPrices, m := cols.Times, prices, n
 			if targetT > maxTime {
-				valid = false
-				break
+				if nextN == 0 || targetT > next.Times[nextN-1] {
+					valid = false
+					break
+				}
+				ticksTimes, ticksPrices, m = next.Times, nextPrices, nextN
 			}
 
 			// Binary search for first tick with time >= targetT.
-			idx := sort.Search(n, func(k int) bool {
+			idx := sort.Search(m, func(k int) bool {
 				return ticksTimes[k] >= targetT
 			})
-			if idx == n {
+			if idx == m {
 				valid = false
 				break
 			}
@@ -117,6 +285,14 @@ func RunStream(cols *DayColumns, models []ContinuousModel) StreamResult {
 			}
 
 			res.Targets[baseTarg+hIdx] = math.Log(foundP / basePrice)
+
+			if TripleBarrier {
+				width := BarrierK * math.Sqrt(sampleVar[i]*float64(delay)/1000)
+				ret, touchT, label := walkBarriers(cols.Times, prices, next, nextPrices, nextN, sampleTick[i], basePrice, width, targetT)
+				res.Targets[baseTarg+hIdx] = ret
+				res.Labels[baseTarg+hIdx] = label
+				res.TouchTimes[baseTarg+hIdx] = touchT
+			}
 		}
 
 		if !valid {
@@ -144,6 +320,90 @@ func RunStream(cols *DayColumns, models []ContinuousModel) StreamResult {
 	res.Prices = res.Prices[:validCount]
 	res.Features = res.Features[:validCount*numModels]
 	res.Targets = res.Targets[:validCount*numHorizons]
+	if TripleBarrier {
+		res.Labels = res.Labels[:validCount*numHorizons]
+		res.TouchTimes = res.TouchTimes[:validCount*numHorizons]
+	}
 
 	return res
 }
+
+// barrierVol is a trailing per-second variance estimate of tick log returns,
+// both sums decaying with time constant BarrierVolTau.
+type barrierVol struct {
+	sumSq, sumT, lastP float64
+}
+
+func (b *barrierVol) update(dt, p float64) {
+	if b.lastP > 0 && p > 0 {
+		decay := math.Exp(-dt / BarrierVolTau)
+		r := math.Log(p / b.lastP)
+		b.sumSq = b.sumSq*decay + r*r
+		b.sumT = b.sumT*decay + dt
+	}
+	b.lastP = p
+}
+
+func (b *barrierVol) rate() float64 {
+	if b.sumT <= 0 {
+		return 0
+	}
+	return b.sumSq / b.sumT
+}
+
+// walkBarriers scans the ticks (times, prices) after index start, continuing
+// into the first nextN ticks of next (priced by nextPrices), until the log
+// return from basePrice leaves ±width or a tick reaches endT. It returns the
+// log return and time at that tick, with label +1/-1 for the upper/lower
+// barrier and 0 for expiry. A zero width (no volatility estimate yet) only
+// expires. The caller has checked that a tick at or after endT exists.
+func walkBarriers(times []int64, prices []float64, next *DayColumns, nextPrices []float64, nextN, start int, basePrice, width float64, endT int64) (float64, int64, int8) {
+	n := len(prices)
+	i := start + 1
+	for {
+		if i == n {
+			times, prices, n, i = next.Times, nextPrices, nextN, 0
+		}
+		t := times[i]
+		r := math.Log(prices[i] / basePrice)
+		switch {
+		case width > 0 && r >= width:
+			return r, t, 1
+		case width > 0 && r <= -width:
+			return r, t, -1
+		case t >= endT:
+			return r, t, 0
+		}
+		i++
+	}
+}
+
+// microMid fills dst (resized to the day) with, per trade, the average of
+// the latest buy-aggressor and sell-aggressor prices: a mid-price proxy that
+// strips most of the bid-ask bounce out of last-trade prices. Until both
+// sides have traded it is the last trade price, and so is every entry when
+// cols has no aggressor sides.
+func microMid(dst []float64, cols *DayColumns) []float64 {
+	n := cols.Count
+	out := reuseSlice(dst, n)
+	if len(cols.Sides) < n {
+		copy(out, cols.Prices[:n])
+		return out
+	}
+	var lastBuy, lastSell float64
+	for i := 0; i < n; i++ {
+		p := cols.Prices[i]
+		switch cols.Sides[i] {
+		case 1:
+			lastBuy = p
+		case -1:
+			lastSell = p
+		}
+		if lastBuy > 0 && lastSell > 0 {
+			out[i] = (lastBuy + lastSell) / 2
+		} else {
+			out[i] = p
+		}
+	}
+	return out
+}
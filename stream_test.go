@@ -0,0 +1,237 @@
+package main
+
+import (
+	"cmp"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+)
+
+// decodeSynthDay runs d through the TBV1 encoder and InflateGNC, the way
+// a worker gets its DayColumns.
+func decodeSynthDay(t testing.TB, d synthDay) *DayColumns {
+	t.Helper()
+	cols := &DayColumns{}
+	if _, err := InflateGNC(encodeTBV1(d), cols); err != nil {
+		t.Fatal(err)
+	}
+	return cols
+}
+
+func streamModels(t testing.TB) []ContinuousModel {
+	t.Helper()
+	models, err := buildModels(sweepSpecs("Hawkes_OFI", []float64{10, 60, 300}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return models
+}
+
+// cloneStream copies the slices of r out of any StreamBuffers.
+func cloneStream(r StreamResult) StreamResult {
+	r.Times = slices.Clone(r.Times)
+	r.Prices = slices.Clone(r.Prices)
+	r.Features = slices.Clone(r.Features)
+	r.Targets = slices.Clone(r.Targets)
+	return r
+}
+
+// TestStreamBuffersMatchFresh runs a day through buffers already used for a
+// bigger day and checks the result against a freshly allocated run.
+func TestStreamBuffersMatchFresh(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	big := decodeSynthDay(t, randomDay(ofiTask{2024, 1, 1}, 40000, 40000, rng))
+	day := decodeSynthDay(t, randomDay(ofiTask{2024, 1, 2}, 20000, 41000, rng))
+	models := streamModels(t)
+
+	fresh := cloneStream(RunStream(day, nil, models, nil, nil, nil))
+	if len(fresh.Times) == 0 {
+		t.Fatal("no samples")
+	}
+	buf := &StreamBuffers{}
+	RunStream(big, nil, models, nil, nil, buf)
+	reused := RunStream(day, nil, models, nil, nil, buf)
+
+	if !slices.Equal(reused.Times, fresh.Times) ||
+		!slices.Equal(reused.Prices, fresh.Prices) ||
+		!slices.Equal(reused.Features, fresh.Features) ||
+		!slices.Equal(reused.Targets, fresh.Targets) {
+		t.Fatal("reused-buffer output differs from a fresh run")
+	}
+}
+
+// TestStreamBuffersCutAllocs checks that warm buffers remove the per-day
+// slice allocations.
+func TestStreamBuffersCutAllocs(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	day := decodeSynthDay(t, randomDay(ofiTask{2024, 1, 2}, 20000, 40000, rng))
+	models := streamModels(t)
+	sampler := NewTimeSampler()
+
+	fresh := testing.AllocsPerRun(10, func() {
+		RunStream(day, nil, models, nil, sampler, nil)
+	})
+	buf := &StreamBuffers{}
+	reused := testing.AllocsPerRun(10, func() {
+		RunStream(day, nil, models, nil, sampler, buf)
+	})
+	if reused >= fresh {
+		t.Fatalf("%.0f allocs per day with reused buffers, %.0f fresh; want fewer", reused, fresh)
+	}
+	t.Logf("allocs per day: fresh %.0f, reused %.0f", fresh, reused)
+}
+
+// TestTripleBarrierSummary streams a synthetic day with triple-barrier
+// labels and checks the per-horizon tallies: one per labeled sample, and a
+// mean holding period no longer than the horizon.
+func TestTripleBarrierSummary(t *testing.T) {
+	root := t.TempDir()
+	withBaseDir(t, root)
+	rng := rand.New(rand.NewSource(1))
+	writeSynthMonth(t, root, "BTCUSDT", 2024, 1, map[int]synthDay{
+		2: randomDay(ofiTask{2024, 1, 2}, 20000, 40000, rng),
+	})
+	defer func(prev bool) { TripleBarrier = prev }(TripleBarrier)
+	TripleBarrier = true
+
+	so := streamTasks("BTCUSDT", symbolTasks("BTCUSDT"), specFactory(sweepSpecs("Hawkes_OFI", []float64{60})), 0)
+	if len(so.Barriers) != len(HorizonLabels) {
+		t.Fatalf("got %d barrier summaries, want %d", len(so.Barriers), len(HorizonLabels))
+	}
+	for hIdx, b := range so.Barriers {
+		if want := len(so.Results[hIdx][0].Times); b.N() != want || want == 0 {
+			t.Fatalf("%s: %d labels tallied, want %d (> 0)", HorizonLabels[hIdx], b.N(), want)
+		}
+		if b.Up+b.Down == 0 {
+			t.Fatalf("%s: no label touched a barrier", HorizonLabels[hIdx])
+		}
+		if hold, limit := b.MeanHold(), time.Duration(HorizonDelays[hIdx])*time.Millisecond; hold <= 0 || hold > limit {
+			t.Fatalf("%s: mean hold %s, want in (0, %s]", HorizonLabels[hIdx], hold, limit)
+		}
+	}
+}
+
+func TestReuseSlice(t *testing.T) {
+	s := reuseSlice[float64](nil, 8)
+	if len(s) != 8 || cap(s) < 8 {
+		t.Fatalf("len %d cap %d, want 8 and >= 8", len(s), cap(s))
+	}
+	s[0] = 1
+	shrunk := reuseSlice(s, 4)
+	if len(shrunk) != 4 || &shrunk[0] != &s[0] {
+		t.Fatal("shrinking reallocated")
+	}
+	grown := reuseSlice(s, cap(s)+1)
+	if len(grown) != cap(s)+1 || &grown[0] == &s[0] {
+		t.Fatal("growing past cap kept the old array")
+	}
+}
+
+func TestMicroMid(t *testing.T) {
+	cols := &DayColumns{
+		Count:  4,
+		Prices: []float64{100, 101, 99, 102},
+		Sides:  []int8{1, 1, -1, 1},
+	}
+	if got, want := microMid(nil, cols), []float64{100, 101, 100, 100.5}; !slices.Equal(got, want) {
+		t.Fatalf("micro-mid %v, want %v", got, want)
+	}
+	// No sides: the trade prices.
+	cols.Sides = nil
+	if got := microMid(nil, cols); !slices.Equal(got, cols.Prices) {
+		t.Fatalf("micro-mid without sides %v, want %v", got, cols.Prices)
+	}
+}
+
+// TestMergeWorkerCellsMatchesFullConcat spreads several days of samples
+// over workers in scrambled order and checks that the cell-by-cell merge
+// gives the same columns as the original merge: every worker's cells
+// concatenated in full, then each cell stably sorted by time.
+func TestMergeWorkerCellsMatchesFullConcat(t *testing.T) {
+	const workers, days, perDay, horizons, models = 3, 7, 50, 2, 3
+	rng := rand.New(rand.NewSource(4))
+	newWorker := func() *WorkerResults {
+		wr := &WorkerResults{Data: make([][]*ResultContainer, horizons)}
+		for h := range wr.Data {
+			wr.Data[h] = make([]*ResultContainer, models)
+			for m := range wr.Data[h] {
+				wr.Data[h][m] = &ResultContainer{}
+			}
+		}
+		return wr
+	}
+	ws := make([]*WorkerResults, workers)
+	for i := range ws {
+		ws[i] = newWorker()
+	}
+	for _, d := range rng.Perm(days) {
+		wr := ws[rng.Intn(workers)]
+		for s := range perDay {
+			// Pairs of samples share a timestamp, as same-day samples can.
+			tm := float64(d*86_400_000 + (s/2)*60_000)
+			wr.Prices = append(wr.Prices, rng.Float64())
+			for h := range horizons {
+				for m := range models {
+					rc := wr.Data[h][m]
+					rc.Times = append(rc.Times, tm)
+					rc.Feats = append(rc.Feats, rng.NormFloat64())
+					rc.Targs = append(rc.Targs, rng.NormFloat64())
+				}
+			}
+		}
+	}
+
+	// The original merge, on copies of the worker data.
+	want := make([][]ResultContainer, horizons)
+	for h := range want {
+		want[h] = make([]ResultContainer, models)
+		for m := range want[h] {
+			var all ResultContainer
+			for _, wr := range ws {
+				src := wr.Data[h][m]
+				all.Times = append(all.Times, src.Times...)
+				all.Feats = append(all.Feats, src.Feats...)
+				all.Targs = append(all.Targs, src.Targs...)
+			}
+			idx := make([]int, len(all.Times))
+			for i := range idx {
+				idx[i] = i
+			}
+			slices.SortStableFunc(idx, func(a, b int) int { return cmp.Compare(all.Times[a], all.Times[b]) })
+			for _, i := range idx {
+				want[h][m].Times = append(want[h][m].Times, all.Times[i])
+				want[h][m].Feats = append(want[h][m].Feats, all.Feats[i])
+				want[h][m].Targs = append(want[h][m].Targs, all.Targs[i])
+			}
+		}
+	}
+
+	results := make([][]*ResultContainer, horizons)
+	for h := range results {
+		results[h] = make([]*ResultContainer, models)
+		for m := range results[h] {
+			results[h][m] = &ResultContainer{}
+		}
+	}
+	var prices []float64
+	for _, wr := range ws {
+		prices = append(prices, wr.Prices...)
+	}
+	mergeWorkerCells(results, ws, prices, []string{"a", "b", "c"})
+
+	for h := range results {
+		for m := range results[h] {
+			got := results[h][m]
+			if !slices.Equal(got.Times, want[h][m].Times) || !slices.Equal(got.Feats, want[h][m].Feats) || !slices.Equal(got.Targs, want[h][m].Targs) {
+				t.Fatalf("cell %d/%d differs from the full-concat merge", h, m)
+			}
+			if len(ws[0].Data[h][m].Times) != 0 {
+				t.Fatalf("cell %d/%d: worker slices not released", h, m)
+			}
+		}
+	}
+	if n := len(results[0][0].Times); n != days*perDay {
+		t.Fatalf("%d merged samples, want %d", n, days*perDay)
+	}
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultSweepTaus is the tau grid (seconds) used when none is given.
+var DefaultSweepTaus = []float64{1, 2, 5, 15, 30, 60, 300}
+
+// sweepSpecs expands one model type into one spec per tau, named
+// <Type>_tau<tau>s so the report rows sort into a curve.
+func sweepSpecs(typ string, taus []float64) []ModelSpec {
+	specs := make([]ModelSpec, len(taus))
+	for i, tau := range taus {
+		specs[i] = ModelSpec{
+			Type: typ,
+			Name: fmt.Sprintf("%s_tau%gs", typ, tau),
+			Tau:  tau,
+		}
+	}
+	return specs
+}
+
+// parseTaus parses a comma-separated list of positive seconds.
+func parseTaus(list string) ([]float64, error) {
+	var taus []float64
+	for _, f := range strings.Split(list, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		tau, err := strconv.ParseFloat(f, 64)
+		if err != nil || tau <= 0 {
+			return nil, fmt.Errorf("bad tau %q", f)
+		}
+		taus = append(taus, tau)
+	}
+	if len(taus) == 0 {
+		return nil, fmt.Errorf("empty tau list")
+	}
+	return taus, nil
+}
+
+// RunTauSweep streams every symbol once with one instance of model type typ
+// per tau and writes Tau_Sweep_<TYPE>_<SYMBOL>.txt. The core table lists the
+// instances in tau order, so IC/Sharpe per horizon read as a curve over tau.
+// With SweepMinIC > 0, candidates are first screened on an early IS slice
+// (see screenSpecs) and only the survivors get the full run.
+func RunTauSweep(typ string, taus []float64) error {
+	specs := sweepSpecs(typ, taus)
+	// Validate once up front; factories can then ignore the error.
+	if _, err := buildModels(specs); err != nil {
+		return err
+	}
+
+	symbols := sortedSymbols()
+	if len(symbols) == 0 {
+		return fmt.Errorf("no symbols discovered under BaseDir")
+	}
+
+	var js *JSONReport
+	if ReportFormat == "json" {
+		js = newJSONReport()
+	}
+
+	jsonPath := fmt.Sprintf("Tau_Sweep_%s.json", typ)
+	startAll := time.Now()
+	fmt.Printf(">>> TAU SWEEP: %s over %v s <<<\n", typ, taus)
+	for _, sym := range symbols {
+		kept := specs
+		if SweepMinIC > 0 {
+			kept = screenSpecs(sym, specs)
+			fmt.Printf("[%s] screening: %d of %d candidates kept, %d pruned (|IS IC| < %g)\n",
+				sym, len(kept), len(specs), len(specs)-len(kept), SweepMinIC)
+			if len(kept) == 0 {
+				continue
+			}
+		}
+		report := fmt.Sprintf("Tau_Sweep_%s_%s.txt", typ, sym)
+		RunTestForSymbol(sym, specFactory(kept), report, nil, js)
+		if FlushReportSections {
+			writeJSONReport(js, jsonPath)
+		}
+	}
+	if !FlushReportSections {
+		writeJSONReport(js, jsonPath)
+	}
+	fmt.Printf("Sweep completed in %s\n", time.Since(startAll))
+	return nil
+}
+
+// specFactory returns a newModels func for RunTestForSymbol over specs,
+// which must already have passed buildModels.
+func specFactory(specs []ModelSpec) func() []ContinuousModel {
+	return func() []ContinuousModel {
+		models, _ := buildModels(specs)
+		return models
+	}
+}
+
+// screenSpecs streams the earliest SweepScreenFrac of sym's days, which lie
+// inside the train segment, and keeps the specs whose best |Pearson IC|
+// across horizons reaches SweepMinIC. Only in-sample data is looked at, so
+// pruning doesn't peek at the test segment of the full run.
+func screenSpecs(sym string, specs []ModelSpec) []ModelSpec {
+	tasks := symbolTasks(sym)
+	n := int(math.Ceil(SweepScreenFrac * float64(len(tasks))))
+	if n == 0 {
+		return specs
+	}
+	results := streamTasks(sym, tasks[:n], specFactory(specs), 0).Results
+
+	var kept []ModelSpec
+	for mIdx, spec := range specs {
+		best := 0.0
+		for hIdx := range HorizonLabels {
+			rc := results[hIdx][mIdx]
+			best = math.Max(best, math.Abs(Pearson(rc.Feats, rc.Targs)))
+		}
+		if best >= SweepMinIC {
+			kept = append(kept, spec)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestSweepScreeningOffByDefault(t *testing.T) {
+	if SweepMinIC != 0 {
+		t.Fatalf("SweepMinIC = %g, want 0 so sweeps run every candidate unless -min-ic is set", SweepMinIC)
+	}
+}
+
+func TestScreenSpecs(t *testing.T) {
+	root := t.TempDir()
+	withBaseDir(t, root)
+	rng := rand.New(rand.NewSource(1))
+	days := make(map[int]synthDay)
+	for d := 1; d <= 5; d++ {
+		days[d] = randomDay(ofiTask{2024, 1, d}, 5000, 40000, rng)
+	}
+	writeSynthMonth(t, root, "BTCUSDT", 2024, 1, days)
+
+	specs := sweepSpecs("Hawkes_OFI", []float64{10, 60, 300})
+	defer func(prev float64) { SweepMinIC = prev }(SweepMinIC)
+
+	// Any |IC| beats a threshold this small, and none reaches 1.
+	SweepMinIC = 1e-12
+	if kept := screenSpecs("BTCUSDT", specs); len(kept) != len(specs) {
+		t.Fatalf("min-ic 1e-12 kept %d of %d", len(kept), len(specs))
+	}
+	SweepMinIC = 1
+	if kept := screenSpecs("BTCUSDT", specs); len(kept) != 0 {
+		t.Fatalf("min-ic 1 kept %d of %d", len(kept), len(specs))
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// synthDay is one day of synthetic trades for the TBV1 test fixtures.
+type synthDay struct {
+	Times  []int64
+	Prices []float64
+	Qtys   []float64
+	Sides  []int8 // -1 marks a buyer-maker (sell-aggressor) trade
+}
+
+// randomDay returns rows trades spread over the UTC day t, a geometric
+// random walk from p0.
+func randomDay(t ofiTask, rows int, p0 float64, rng *rand.Rand) synthDay {
+	start := time.Date(t.Year, time.Month(t.Month), t.Day, 0, 0, 0, 0, time.UTC).UnixMilli()
+	step := int64(86_400_000-1000) / int64(rows)
+	d := synthDay{
+		Times:  make([]int64, rows),
+		Prices: make([]float64, rows),
+		Qtys:   make([]float64, rows),
+		Sides:  make([]int8, rows),
+	}
+	p := p0
+	for i := range rows {
+		p *= math.Exp(rng.NormFloat64() * 0.0005)
+		d.Times[i] = start + int64(i)*step + rng.Int63n(step)
+		d.Prices[i] = p
+		d.Qtys[i] = math.Exp(rng.NormFloat64())
+		d.Sides[i] = 1
+		if rng.Intn(2) == 0 {
+			d.Sides[i] = -1
+		}
+	}
+	return d
+}
+
+// encodeTBV1 lays d out as a TBV1 blob the way the downloader does: a
+// 64-byte header, then cache-line aligned columns.
+func encodeTBV1(d synthDay) []byte {
+	rows := len(d.Times)
+	align := func(n int) int { return (n + CacheLine - 1) &^ (CacheLine - 1) }
+	offs := make([]int, 7) // agg, price, qty, first, last, time, bits
+	offs[0] = TBHdrSize
+	for i := 1; i < len(offs); i++ {
+		offs[i] = align(offs[i-1] + rows*8)
+	}
+	b := make([]byte, align(offs[6]+(rows+63)/64*8))
+
+	le := binary.LittleEndian
+	copy(b, TBMagic)
+	le.PutUint32(b[4:], TBVersion)
+	le.PutUint64(b[8:], uint64(rows))
+	for i, o := range offs {
+		le.PutUint32(b[16+4*i:], uint32(o))
+	}
+	for i := range rows {
+		le.PutUint64(b[offs[0]+8*i:], uint64(i))
+		le.PutUint64(b[offs[1]+8*i:], math.Float64bits(d.Prices[i]))
+		le.PutUint64(b[offs[2]+8*i:], math.Float64bits(d.Qtys[i]))
+		le.PutUint64(b[offs[3]+8*i:], uint64(2*i))
+		le.PutUint64(b[offs[4]+8*i:], uint64(2*i+i%3))
+		le.PutUint64(b[offs[5]+8*i:], uint64(d.Times[i]))
+		if d.Sides[i] < 0 {
+			w := offs[6] + 8*(i/64)
+			le.PutUint64(b[w:], le.Uint64(b[w:])|1<<(i%64))
+		}
+	}
+	return b
+}
+
+// writeSynthMonth writes days (keyed by day of month) of one month as
+// root/sym/YYYY/MM/{data,index}.quantdev, blobs packed on cache lines.
+func writeSynthMonth(t testing.TB, root, sym string, year, month int, days map[int]synthDay) {
+	t.Helper()
+	dir := filepath.Join(root, sym, fmt.Sprintf("%04d", year), fmt.Sprintf("%02d", month))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	var data []byte
+	var rows []indexRow
+	for day := 1; day <= 31; day++ {
+		d, ok := days[day]
+		if !ok {
+			continue
+		}
+		blob := encodeTBV1(d)
+		rows = append(rows, indexRow{Day: day, indexEntry: indexEntry{
+			Offset:   uint64(len(data)),
+			Length:   uint64(len(blob)),
+			Checksum: blobChecksum(blob),
+		}})
+		data = append(data, blob...)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "data.quantdev"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeIndex(filepath.Join(dir, "index.quantdev"), rows); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// withBaseDir points BaseDir at dir for the rest of the test.
+func withBaseDir(t testing.TB, dir string) {
+	t.Helper()
+	prev := BaseDir
+	BaseDir = dir
+	t.Cleanup(func() { BaseDir = prev })
+}
@@ -1,13 +1,20 @@
 package main
 
 import (
+	"cmp"
 	"fmt"
+	"io"
+	"math"
 	"os"
+	"slices"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"text/tabwriter"
 	"time"
+	"unicode/utf8"
 )
 
 type ResultContainer struct {
@@ -19,6 +26,21 @@ type ResultContainer struct {
 // Per-worker storage: [horizon][model] -> ResultContainer
 type WorkerResults struct {
 	Data [][]*ResultContainer
+
+	// Label price per sample, in the same order as every Data cell.
+	Prices []float64
+
+	// Labeled samples produced per processed day (for grid diagnostics).
+	DaySamples []int
+
+	// First/last trade price per decoded day (for price-break detection).
+	DayPrices []dayPrices
+
+	// [horizon][model] accumulators instead of Data, under a split.
+	Moments [][]CellMoments
+
+	// Triple-barrier label tallies per horizon (see TripleBarrier).
+	Barriers []BarrierSummary
 }
 
 // RunTest now runs the full OOS pipeline for **all discovered symbols** under BaseDir.
@@ -28,34 +50,94 @@ type WorkerResults struct {
 func RunTest() {
 	startAll := time.Now()
 
-	// Discover all symbols, same logic as RunProbe.
-	var symbols []string
-	for sym := range discoverSymbols() {
-		symbols = append(symbols, sym)
-	}
+	symbols := sortedSymbols()
 	if len(symbols) == 0 {
 		fmt.Println("No symbols discovered under BaseDir.")
 		return
 	}
-	sort.Strings(symbols)
+
+	var db *ResultsDB
+	if ResultsDBPath != "" {
+		var names []string
+		for _, m := range GetContinuousModels() {
+			names = append(names, m.Name())
+		}
+		var err error
+		db, err = OpenResultsDB(ResultsDBPath, names)
+		if err != nil {
+			fmt.Printf("ERROR: could not open results db %s: %v\n", ResultsDBPath, err)
+			return
+		}
+		defer db.Close()
+	}
+
+	var js *JSONReport
+	if ReportFormat == "json" {
+		js = newJSONReport()
+	}
 
 	fmt.Printf(">>> CONTINUOUS-TIME ALGO DISCOVERY (OOS REPORT, ALL SYMBOLS) <<<\n")
 	fmt.Printf("   Workers: %d | Symbols: %d\n\n", CPUThreads, len(symbols))
 
+	var allStats []symbolStats
 	for _, sym := range symbols {
 		fmt.Printf("=== [%s] Starting OOS discovery ===\n", sym)
-		RunTestForSymbol(sym)
+		report := fmt.Sprintf("Continuous_Algo_Report_OOS_%s.txt", sym)
+		if core := RunTestForSymbol(sym, GetContinuousModels, report, db, js); core != nil {
+			allStats = append(allStats, symbolStats{sym, core})
+		}
+		if FlushReportSections {
+			writeJSONReport(js, "Continuous_Algo_Report_OOS.json")
+		}
 		fmt.Printf("=== [%s] Finished OOS discovery ===\n\n", sym)
 	}
 
+	if !FlushReportSections {
+		writeJSONReport(js, "Continuous_Algo_Report_OOS.json")
+	}
+
+	if ReportFormat == "text" && len(allStats) > 1 {
+		var names []string
+		for _, m := range GetContinuousModels() {
+			names = append(names, m.Name())
+		}
+		const summary = "Continuous_Algo_Report_OOS_CrossSymbol.txt"
+		if err := writeCrossSymbolSummary(summary, names, allStats); err != nil {
+			fmt.Printf("ERROR: could not write cross-symbol summary %s: %v\n", summary, err)
+		} else {
+			fmt.Printf("Cross-symbol summary saved to %s\n", summary)
+		}
+	}
+
 	fmt.Printf("All symbols completed in %s\n", time.Since(startAll))
 }
 
-// RunTestForSymbol runs the original OOS pipeline for a single symbol.
-func RunTestForSymbol(sym string) {
+// reportSectionDone is called by RunTestForSymbol after each report section;
+// tests replace it to stop a run part way through.
+var reportSectionDone = func() {}
+
+// sortedSymbols discovers all symbols under BaseDir (same logic as RunProbe)
+// in name order.
+func sortedSymbols() []string {
+	var symbols []string
+	for sym := range discoverSymbols() {
+		symbols = append(symbols, sym)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// RunTestForSymbol runs the original OOS pipeline for a single symbol and
+// writes the report to filename. newModels is called once per worker, since
+// models carry state, and must return the same list every time. When db is
+// non-nil the report rows are also written to it, and likewise to js. The
+// text report is only written when ReportFormat is "text"; "csv" writes the
+// CSV next to where it would have been. It returns the core table,
+// core[model][horizon], or nil when nothing was reported.
+func RunTestForSymbol(sym string, newModels func() []ContinuousModel, filename string, db *ResultsDB, js *JSONReport) [][]ReportStats {
 	start := time.Now()
 
-	models := GetContinuousModels()
+	models := newModels()
 	modelNames := make([]string, len(models))
 	for i, m := range models {
 		modelNames[i] = m.Name()
@@ -64,210 +146,343 @@ func RunTestForSymbol(sym string) {
 	fmt.Printf(">>> CONTINUOUS-TIME ALGO DISCOVERY (OOS REPORT) <<<\n")
 	fmt.Printf("   Symbol: %s | Workers: %d | Models: %d\n", sym, CPUThreads, len(models))
 
-	// Global results[horizon][model].
-	results := make([][]*ResultContainer, len(HorizonLabels))
-	for h := range results {
-		results[h] = make([]*ResultContainer, len(models))
-		for m := range results[h] {
-			results[h][m] = &ResultContainer{}
-		}
+	tasks := symbolTasks(sym)
+	if len(tasks) == 0 {
+		fmt.Printf("[%s] No tasks discovered; nothing to do.\n", sym)
+		return nil
 	}
 
-	// Collect all (year,month,day) tasks for this symbol.
-	tasks := make([]ofiTask, 0)
-	for t := range discoverTasks(sym) {
-		tasks = append(tasks, t)
-	}
+	const trainFrac = 0.7 // 70% earliest samples train, 30% latest samples test
 
-	if len(tasks) == 0 {
-		fmt.Printf("[%s] No tasks discovered; nothing to do.\n", sym)
-		return
+	var splitMs int64
+	if LowMemory {
+		splitMs = lowMemSplit(tasks, trainFrac)
+	}
+	so := streamTasks(sym, tasks, newModels, splitMs)
+	results, daySamples, breaks := so.Results, so.DaySamples, so.Breaks
+	for _, b := range breaks {
+		fmt.Printf("[%s] WARNING: price break %s -> %s (x%.4g)\n", sym, b.Prev, b.Day, b.Ratio)
 	}
 
-	// Sort tasks chronologically so workers process days in a sensible order.
-	sort.Slice(tasks, func(i, j int) bool {
-		if tasks[i].Year != tasks[j].Year {
-			return tasks[i].Year < tasks[j].Year
+	if DumpParquet && so.Moments == nil {
+		if path, err := exportResultContainers(sym, modelNames, results); err != nil {
+			fmt.Printf("[%s] ERROR: parquet export %s: %v\n", sym, path, err)
+		} else {
+			fmt.Printf("[%s] Features saved to %s\n", sym, path)
 		}
-		if tasks[i].Month != tasks[j].Month {
-			return tasks[i].Month < tasks[j].Month
-		}
-		return tasks[i].Day < tasks[j].Day
-	})
+	}
 
-	// Per-worker result storage.
-	workerResults := make([]*WorkerResults, CPUThreads)
-	for i := 0; i < CPUThreads; i++ {
-		wr := &WorkerResults{
-			Data: make([][]*ResultContainer, len(HorizonLabels)),
+	// ---------------------------------------------------------------------
+	// Reporting phase (per symbol)
+	// ---------------------------------------------------------------------
+
+	var out io.Writer = io.Discard
+	if ReportFormat == "text" {
+		f, err := os.Create(filename)
+		if err != nil {
+			fmt.Printf("[%s] ERROR: could not create report file %s: %v\n", sym, filename, err)
+			return nil
 		}
-		for h := range wr.Data {
-			wr.Data[h] = make([]*ResultContainer, len(models))
-			for m := range wr.Data[h] {
-				wr.Data[h][m] = &ResultContainer{}
+		defer f.Close()
+		out = f
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 1, ' ', 0)
+
+	// Feature correlations and holding curves pair cells by index.
+	// Features don't depend on the horizon; the first one's cells serve.
+	// Correlations are taken on the (purged) train segment only, since
+	// clustering and model selection act on them.
+	trainFeats := make([][]float64, len(modelNames))
+	for i := range modelNames {
+		data := results[0][i]
+		trainFeats[i] = purgedSplit(data.Times, data.Feats, data.Targs, trainFrac, HorizonDelays[0]).TrainF
+	}
+	featCorr := make([][]float64, len(modelNames))
+	for i := range modelNames {
+		featCorr[i] = make([]float64, len(modelNames))
+		for j := range i + 1 {
+			fi, fj := trainFeats[i], trainFeats[j]
+			if len(fi) > 0 && len(fi) == len(fj) {
+				featCorr[i][j] = Pearson(fi, fj)
+				featCorr[j][i] = featCorr[i][j]
 			}
 		}
-		workerResults[i] = wr
 	}
 
-	// Task channel and worker pool.
-	taskCh := make(chan ofiTask, len(tasks))
-	for _, t := range tasks {
-		taskCh <- t
+	var holding []HoldingCurve
+	if HoldingStepSec > 0 {
+		stepMs := int64(HoldingStepSec * 1000)
+		holding = make([]HoldingCurve, len(modelNames))
+		for mIdx := range modelNames {
+			data := results[0][mIdx]
+			if len(data.Times) == len(so.Prices) {
+				holding[mIdx] = HoldingCurveOOS(data.Times, so.Prices, data.Feats, trainFrac, HorizonDelays[0], stepMs, slices.Max(HorizonDelays))
+			}
+		}
 	}
-	close(taskCh)
-
-	var wg sync.WaitGroup
-	var processed atomic.Int64
 
-	for wID := 0; wID < CPUThreads; wID++ {
-		wg.Add(1)
-		go func(id int) {
-			defer wg.Done()
+	var csvOut *csvReport
+	if ReportFormat == "csv" {
+		var err error
+		csvPath := strings.TrimSuffix(filename, ".txt") + ".csv"
+		if csvOut, err = createCSVReport(csvPath, sym); err != nil {
+			fmt.Printf("[%s] ERROR: could not create CSV report %s: %v\n", sym, csvPath, err)
+		}
+		defer func() {
+			if err := csvOut.Close(); err != nil {
+				fmt.Printf("[%s] ERROR: CSV report: %v\n", sym, err)
+			}
+		}()
+	}
 
-			localStore := workerResults[id]
-			localModels := GetContinuousModels()
+	batch, err := db.Begin(sym)
+	if err != nil {
+		fmt.Printf("[%s] ERROR: results db: %v\n", sym, err)
+	}
+	defer func() {
+		if err := batch.Commit(); err != nil {
+			fmt.Printf("[%s] ERROR: results db: %v\n", sym, err)
+		}
+	}()
 
-			cols := DayColumnPool.Get().(*DayColumns)
-			defer DayColumnPool.Put(cols)
+	// endSection pushes a finished section out to the report files, so an
+	// interrupted run keeps everything up to the last complete section.
+	endSection := func() {
+		if FlushReportSections {
+			w.Flush()
+			csvOut.Flush()
+		}
+		reportSectionDone()
+	}
 
-			var buf []byte
+	// 1) Core OOS summary, per model × horizon (columns from statsFields)
+	coreHeader := statsHeader(true)
+	coreDashes := make([]string, len(coreHeader))
+	for i, h := range coreHeader {
+		coreDashes[i] = strings.Repeat("-", utf8.RuneCountInString(h))
+	}
+	fmt.Fprintf(w, "MODEL\tHORIZON\t%s\n", strings.Join(coreHeader, "\t"))
+	fmt.Fprintf(w, "-----\t-------\t%s\n", strings.Join(coreDashes, "\t"))
 
-			for task := range taskCh {
-				if !LoadGNCFile(BaseDir, sym, task, &buf) {
-					continue
-				}
-				if _, err := InflateGNC(buf, cols); err != nil {
-					continue
-				}
+	// The per-cell metrics of sections 1 and 6-11, computed in parallel; a
+	// -low-mem run only has the core stats its moments give.
+	var cells [][]cellMetrics
+	if so.Moments != nil {
+		cells = momentCells(so.Moments, len(modelNames))
+	} else {
+		cells = analyzeCells(results, len(modelNames), trainFrac)
+	}
 
-				streamRes := RunStream(cols, localModels)
-				if len(streamRes.Times) == 0 {
-					continue
-				}
+	// core[model][horizon]; cells without data keep TestCount == 0.
+	core := make([][]ReportStats, len(modelNames))
+	var pValues []float64
+	for mIdx := range modelNames {
+		core[mIdx] = make([]ReportStats, len(HorizonLabels))
+		for hIdx := range HorizonLabels {
+			stats := cells[mIdx][hIdx].Stats
+			if stats.TestCount == 0 || stats.Suppressed {
+				continue
+			}
+			core[mIdx][hIdx] = stats
+			pValues = append(pValues, stats.ICPValue)
+		}
+	}
 
-				numSamples := len(streamRes.Times)
-				numModels := streamRes.NumModels
-				numHorizons := streamRes.NumHorizons
+	// Second pass: the IC q-values need every cell's p-value, and the DSR
+	// every cell's Sharpe (each reported cell is one trial).
+	nTrials, trialVar := trialSharpeVariance(core)
+	sr0 := make([][]float64, len(modelNames))
+	for mIdx := range core {
+		sr0[mIdx] = make([]float64, len(HorizonLabels))
+		for hIdx, st := range core[mIdx] {
+			if st.TestCount > 0 {
+				core[mIdx][hIdx].DSR, sr0[mIdx][hIdx] = DeflatedSharpe(st.Sharpe, st.TradeSkew, st.TradeKurtosis,
+					st.EffectiveN, nTrials, trialVar)
+			}
+		}
+	}
+	qValues := BenjaminiHochberg(pValues)
+	for mIdx, name := range modelNames {
+		for hIdx, hName := range HorizonLabels {
+			if st := cells[mIdx][hIdx].Stats; st.Suppressed && st.TestCount > 0 {
+				// Too few effective samples for any metric: reported as such,
+				// outside the BH and DSR families, with p = q = 1 so it can't
+				// pass the FDR column.
+				st.ICPValue, st.ICQValue = 1, 1
+				batch.Stats(name, hName, st)
+				csvOut.Stats(name, hName, st)
+				js.Stats(sym, name, hName, st)
+				fmt.Fprintf(w, "%s\t%s\t%d\t%d\tsuppressed (effective n %.1f < %g)\n",
+					name, hName, st.TrainCount, st.TestCount, st.EffectiveN, MinEffectiveSamples)
+				continue
+			}
+			if core[mIdx][hIdx].TestCount == 0 {
+				continue
+			}
+			core[mIdx][hIdx].ICQValue, qValues = qValues[0], qValues[1:]
+			stats := core[mIdx][hIdx]
+			batch.Stats(name, hName, stats)
 
-				// Append into thread-local storage.
-				for s := 0; s < numSamples; s++ {
-					t := float64(streamRes.Times[s])
+			csvOut.Stats(name, hName, stats)
+			js.Stats(sym, name, hName, stats)
 
-					featBase := s * numModels
-					targBase := s * numHorizons
+			fmt.Fprintf(w, "%s\t%s\t%s\n", name, hName, strings.Join(statsToRecord(stats, true), "\t"))
+		}
+		fmt.Fprintf(w, "\n")
+	}
 
-					for mIdx := 0; mIdx < numModels; mIdx++ {
-						featVal := streamRes.Features[featBase+mIdx]
-						for hIdx := 0; hIdx < numHorizons; hIdx++ {
-							targVal := streamRes.Targets[targBase+hIdx]
+	endSection()
 
-							rc := localStore.Data[hIdx][mIdx]
-							rc.Times = append(rc.Times, t)
-							rc.Feats = append(rc.Feats, featVal)
-							rc.Targs = append(rc.Targs, targVal)
-						}
-					}
-				}
+	// 2) Deflated Sharpe: the core table's DSR column with its inputs.
+	fmt.Fprintf(w, "\n\n# Deflated Sharpe (%d trials, Sharpe std across trials %.4f; T = EffectiveN)\n", nTrials, math.Sqrt(trialVar))
+	fmt.Fprintf(w, "MODEL\tHORIZON\tSharpe\tSR0\tSkew\tKurt\tEffN\tDSR\n")
+	fmt.Fprintf(w, "-----\t-------\t------\t---\t----\t----\t----\t---\n")
 
-				processed.Add(1)
+	for mIdx, name := range modelNames {
+		for hIdx, hName := range HorizonLabels {
+			stats := core[mIdx][hIdx]
+			if stats.TestCount == 0 {
+				continue
 			}
-		}(wID)
+			fmt.Fprintf(
+				w,
+				"%s\t%s\t%.4f\t%.4f\t%+.2f\t%.2f\t%.0f\t%.3f\n",
+				name,
+				hName,
+				stats.Sharpe,
+				sr0[mIdx][hIdx],
+				stats.TradeSkew,
+				stats.TradeKurtosis,
+				stats.EffectiveN,
+				stats.DSR,
+			)
+		}
+		fmt.Fprintf(w, "\n")
 	}
-	wg.Wait()
 
-	// Merge worker-local results into global results.
-	for wID := 0; wID < CPUThreads; wID++ {
-		wr := workerResults[wID]
-		for hIdx := range HorizonLabels {
-			for mIdx := range models {
-				src := wr.Data[hIdx][mIdx]
-				dst := results[hIdx][mIdx]
+	endSection()
 
-				if len(src.Times) == 0 {
-					continue
-				}
+	// 3) One horizon per model, chosen on IS Sharpe only; OOS columns are
+	// that horizon's test-segment results. OOSBest is the horizon that would
+	// have won on OOS Sharpe, for comparison. The DSR is the core table's,
+	// deflated over every model×horizon cell the selection ranged over.
+	fmt.Fprintf(w, "\n\n# Best horizon per model (selected on IS Sharpe; DSR over %d model x horizon trials)\n", nTrials)
+	fmt.Fprintf(w, "MODEL\tSELECTED\tIS_Sharpe\tOOS_Sharpe\tOOS_IC\tOOS_HitRate\tDSR\tOOSBest\n")
+	fmt.Fprintf(w, "-----\t--------\t---------\t----------\t------\t-----------\t---\t-------\n")
 
-				dst.Times = append(dst.Times, src.Times...)
-				dst.Feats = append(dst.Feats, src.Feats...)
-				dst.Targs = append(dst.Targs, src.Targs...)
-			}
+	for mIdx, name := range modelNames {
+		sel, best := selectHorizon(core[mIdx])
+		if sel < 0 {
+			continue
 		}
+		st := core[mIdx][sel]
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%.4f\t%.4f\t%.4f\t%.3f\t%.3f\t%s\n",
+			name,
+			HorizonLabels[sel],
+			st.ISSharpe,
+			st.Sharpe,
+			st.PearsonIC,
+			st.HitRate,
+			st.DSR,
+			HorizonLabels[best],
+		)
 	}
 
-	// ---------------------------------------------------------------------
-	// Reporting phase (per symbol)
-	// ---------------------------------------------------------------------
+	endSection()
 
-	// One report per symbol.
-	filename := fmt.Sprintf("Continuous_Algo_Report_OOS_%s.txt", sym)
-	f, err := os.Create(filename)
-	if err != nil {
-		fmt.Printf("[%s] ERROR: could not create report file %s: %v\n", sym, filename, err)
-		return
+	// 4) IC term structure: each model's PearsonIC across horizons and the
+	// horizon where it decays to half its peak.
+	fmt.Fprintf(w, "\n\n# IC decay across horizons (PearsonIC; ~ marks a half-life extrapolated past the last horizon)\n")
+	fmt.Fprintf(w, "MODEL")
+	for _, hName := range HorizonLabels {
+		fmt.Fprintf(w, "\t%s", hName)
 	}
-	defer f.Close()
-	w := tabwriter.NewWriter(f, 0, 0, 1, ' ', 0)
-
-	const trainFrac = 0.7 // 70% earliest samples train, 30% latest samples test
-
-	// 1) Core OOS summary, per model × horizon
-	fmt.Fprintf(w, "MODEL\tHORIZON\tTrainN\tTestN\tPearsonIC\tSpearmanIC\tHitRate\tHitZ\tSharpe\tSpread(bps)\tTopDecile(bps)\tBotDecile(bps)\tMI(bits)\tNMI\tΔLogLoss\n")
-	fmt.Fprintf(w, "-----\t-------\t------\t-----\t---------\t-----------\t-------\t----\t------\t-----------\t--------------\t---------------\t--------\t---\t--------\n")
+	fmt.Fprintf(w, "\tPeak\tHalfLife\n-----")
+	for _, hName := range HorizonLabels {
+		fmt.Fprintf(w, "\t%s", strings.Repeat("-", len(hName)))
+	}
+	fmt.Fprintf(w, "\t----\t--------\n")
 
 	for mIdx, name := range modelNames {
+		var delays []int64
+		var ics []float64
+		var labels []string
+		cols := make([]string, len(HorizonLabels))
 		for hIdx, hName := range HorizonLabels {
-			data := results[hIdx][mIdx]
-			if len(data.Feats) == 0 {
-				continue
+			cols[hIdx] = "-"
+			if st := core[mIdx][hIdx]; st.TestCount > 0 {
+				delays = append(delays, HorizonDelays[hIdx])
+				ics = append(ics, st.PearsonIC)
+				labels = append(labels, hName)
+				cols[hIdx] = fmt.Sprintf("%+.4f", st.PearsonIC)
+			}
+		}
+		decay := ICDecay(delays, ics)
+		if decay.Peak < 0 {
+			continue
+		}
+		halfLife := "-"
+		if decay.HalfLifeMs > 0 {
+			halfLife = (time.Duration(decay.HalfLifeMs) * time.Millisecond).Round(time.Second).String()
+			if decay.Extrapolated {
+				halfLife = "~" + halfLife
 			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, strings.Join(cols, "\t"), labels[decay.Peak], halfLife)
+	}
 
-			stats := AnalyzeFullSuiteOOS(data.Times, data.Feats, data.Targs, trainFrac)
-			if stats.TestCount == 0 {
+	endSection()
+
+	// 5) Daily IC significance: naive vs. Newey-West t-stat of the mean
+	fmt.Fprintf(w, "\n\n# Daily IC (test segment, per UTC day)\n")
+	fmt.Fprintf(w, "MODEL\tHORIZON\tDays\tMeanIC\tt\tt(NW)\n")
+	fmt.Fprintf(w, "-----\t-------\t----\t------\t-\t-----\n")
+
+	for mIdx, name := range modelNames {
+		for hIdx, hName := range HorizonLabels {
+			st := core[mIdx][hIdx]
+			if st.TestCount == 0 || st.DailyICDays == 0 {
 				continue
 			}
-
 			fmt.Fprintf(
 				w,
-				"%s\t%s\t%d\t%d\t%.4f\t%.4f\t%.3f\t%.2f\t%.3f\t%+.1f\t%+.1f\t%+.1f\t%.3f\t%.3f\t%.4f\n",
+				"%s\t%s\t%d\t%.4f\t%.2f\t%.2f\n",
 				name,
 				hName,
-				stats.TrainCount,
-				stats.TestCount,
-				stats.PearsonIC,
-				stats.SpearmanIC,
-				stats.HitRate,
-				stats.HitRateZ,
-				stats.Sharpe,
-				stats.SpreadBps,
-				stats.TopDecileRetBps,
-				stats.BottomDecileRetBps,
-				stats.MutualInfo,
-				stats.NormalizedMI,
-				stats.DeltaLogLoss,
+				st.DailyICDays,
+				st.DailyICMean,
+				st.DailyICTStat,
+				st.DailyICTStatNW,
 			)
 		}
 		fmt.Fprintf(w, "\n")
 	}
 
-	// 2) Rolling OOS metrics on the test segment
+	endSection()
+
+	if so.Moments != nil {
+		fmt.Fprintf(w, "\n\n# Low-memory mode: sections that need every sample are skipped\n")
+		writeDaySections(w, endSection, daySamples, breaks, so.Barriers)
+		reportDone(sym, so.Processed, start, filename)
+		return core
+	}
+
+	// 6) Rolling OOS metrics on the test segment
 	fmt.Fprintf(w, "\n\n# Rolling OOS metrics (test segment only)\n")
 	fmt.Fprintf(w, "MODEL\tHORIZON\tWIN\tCount\tPearsonIC\tSpearmanIC\tHitRate\tSharpe\n")
 	fmt.Fprintf(w, "-----\t-------\t---\t-----\t---------\t-----------\t-------\t------\n")
 
-	const rollingWindows = 8
-
 	for mIdx, name := range modelNames {
 		for hIdx, hName := range HorizonLabels {
-			data := results[hIdx][mIdx]
-			if len(data.Feats) == 0 {
-				continue
-			}
-			wins := RollingWindowMetricsOOS(data.Times, data.Feats, data.Targs, trainFrac, rollingWindows)
-			for winIdx, wm := range wins {
+			for winIdx, wm := range cells[mIdx][hIdx].Windows {
 				if wm.Count == 0 {
 					continue
 				}
+				batch.Window(name, hName, winIdx, wm)
+				csvOut.Window(name, hName, winIdx, wm)
+				js.Window(sym, name, hName, wm)
 				fmt.Fprintf(
 					w,
 					"%s\t%s\t%d\t%d\t%.4f\t%.4f\t%.3f\t%.3f\n",
@@ -285,22 +500,22 @@ func RunTestForSymbol(sym string) {
 		fmt.Fprintf(w, "\n")
 	}
 
-	// 3) Volatility regime OOS metrics
-	fmt.Fprintf(w, "\n\n# Volatility regime OOS metrics (test segment only)\n")
+	endSection()
+
+	// 7) Volatility regime OOS metrics, regimes from backward realized vol
+	fmt.Fprintf(w, "\n\n# Volatility regime OOS metrics (test segment only; backward RV, span %d samples)\n", VolRegimeWindow)
 	fmt.Fprintf(w, "MODEL\tHORIZON\tREGIME\tCount\tPearsonIC\tSpearmanIC\tHitRate\tSharpe\n")
 	fmt.Fprintf(w, "-----\t-------\t------\t-----\t---------\t-----------\t-------\t------\n")
 
 	for mIdx, name := range modelNames {
 		for hIdx, hName := range HorizonLabels {
-			data := results[hIdx][mIdx]
-			if len(data.Feats) == 0 {
-				continue
-			}
-			regs := VolRegimeMetricsOOS(data.Times, data.Feats, data.Targs, trainFrac)
-			for _, rm := range regs {
+			for _, rm := range cells[mIdx][hIdx].VolRegimes {
 				if rm.Count == 0 {
 					continue
 				}
+				batch.Regime(name, hName, "vol", rm)
+				csvOut.Regime(name, hName, "vol", rm)
+				js.Regime(sym, name, hName, "vol", rm)
 				fmt.Fprintf(
 					w,
 					"%s\t%s\t%s\t%d\t%.4f\t%.4f\t%.3f\t%.3f\n",
@@ -318,22 +533,22 @@ func RunTestForSymbol(sym string) {
 		fmt.Fprintf(w, "\n")
 	}
 
-	// 4) Time-of-day regime OOS metrics
+	endSection()
+
+	// 8) Time-of-day regime OOS metrics
 	fmt.Fprintf(w, "\n\n# Time-of-day regime OOS metrics (test segment only)\n")
 	fmt.Fprintf(w, "MODEL\tHORIZON\tREGIME\tCount\tPearsonIC\tSpearmanIC\tHitRate\tSharpe\n")
 	fmt.Fprintf(w, "-----\t-------\t------\t-----\t---------\t-----------\t-------\t------\n")
 
 	for mIdx, name := range modelNames {
 		for hIdx, hName := range HorizonLabels {
-			data := results[hIdx][mIdx]
-			if len(data.Feats) == 0 {
-				continue
-			}
-			regs := TimeOfDayRegimeMetricsOOS(data.Times, data.Feats, data.Targs, trainFrac)
-			for _, rm := range regs {
+			for _, rm := range cells[mIdx][hIdx].TODRegimes {
 				if rm.Count == 0 {
 					continue
 				}
+				batch.Regime(name, hName, "tod", rm)
+				csvOut.Regime(name, hName, "tod", rm)
+				js.Regime(sym, name, hName, "tod", rm)
 				fmt.Fprintf(
 					w,
 					"%s\t%s\t%s\t%d\t%.4f\t%.4f\t%.3f\t%.3f\n",
@@ -351,6 +566,948 @@ func RunTestForSymbol(sym string) {
 		fmt.Fprintf(w, "\n")
 	}
 
-	w.Flush()
-	fmt.Printf("Done. [%s] Processed %d days in %s. OOS report saved to %s\n", sym, processed.Load(), time.Since(start), filename)
+	endSection()
+
+	// 9) OOS boundary robustness
+	fmt.Fprintf(w, "\n\n# OOS boundary robustness (test-segment PearsonIC by train fraction)\n")
+	fmt.Fprintf(w, "MODEL\tHORIZON")
+	for _, f := range BoundaryFracs {
+		fmt.Fprintf(w, "\tIC@%.2f", f)
+	}
+	fmt.Fprintf(w, "\tMean\tStd\tMin\tMax\tSignAgree\tVerdict\n")
+	fmt.Fprintf(w, "-----\t-------")
+	for range BoundaryFracs {
+		fmt.Fprintf(w, "\t-------")
+	}
+	fmt.Fprintf(w, "\t----\t---\t---\t---\t---------\t-------\n")
+
+	for mIdx, name := range modelNames {
+		for hIdx, hName := range HorizonLabels {
+			bm := cells[mIdx][hIdx].Boundary
+			if len(bm.ICs) == 0 {
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%s", name, hName)
+			for _, ic := range bm.ICs {
+				fmt.Fprintf(w, "\t%.4f", ic)
+			}
+			fmt.Fprintf(w, "\t%.4f\t%.4f\t%.4f\t%.4f\t%.2f\t%s\n", bm.Mean, bm.Std, bm.Min, bm.Max, bm.SignAgree, bm.Verdict())
+		}
+		fmt.Fprintf(w, "\n")
+	}
+
+	endSection()
+
+	// 10) Walk-forward (expanding train window, fixed-size test blocks)
+	fmt.Fprintf(w, "\n\n# Walk-forward OOS (%d folds, expanding train window)\n", WalkForwardFolds)
+	fmt.Fprintf(w, "MODEL\tHORIZON\tFOLD\tTrainN\tTestN\tPearsonIC\tHitRate\tΔLogLoss\tSharpe\n")
+	fmt.Fprintf(w, "-----\t-------\t----\t------\t-----\t---------\t-------\t--------\t------\n")
+
+	for mIdx, name := range modelNames {
+		for hIdx, hName := range HorizonLabels {
+			folds, sum := cells[mIdx][hIdx].Folds, cells[mIdx][hIdx].FoldSummary
+			if len(folds) == 0 {
+				continue
+			}
+			for k, st := range folds {
+				fmt.Fprintf(
+					w,
+					"%s\t%s\t%d\t%d\t%d\t%.4f\t%.3f\t%.4f\t%.3f\n",
+					name,
+					hName,
+					k,
+					st.TrainCount,
+					st.TestCount,
+					st.PearsonIC,
+					st.HitRate,
+					st.DeltaLogLoss,
+					st.Sharpe,
+				)
+			}
+			fmt.Fprintf(
+				w,
+				"%s\t%s\tmean±sd\t\t\t%.4f±%.4f\t\t%.4f±%.4f\t%.3f±%.3f\n",
+				name,
+				hName,
+				sum.MeanIC, sum.StdIC,
+				sum.MeanDeltaLogLoss, sum.StdDeltaLogLoss,
+				sum.MeanSharpe, sum.StdSharpe,
+			)
+		}
+		fmt.Fprintf(w, "\n")
+	}
+
+	endSection()
+
+	// 11) Big-move event study
+	fmt.Fprintf(w, "\n\n# Big-move event study (test segment, top %d non-overlapping moves)\n", bigMoves)
+	fmt.Fprintf(w, "MODEL\tHORIZON\tMoves\tBigMoveHit\tHitZ\tMeanZ\tElevated\n")
+	fmt.Fprintf(w, "-----\t-------\t-----\t----------\t----\t-----\t--------\n")
+
+	for mIdx, name := range modelNames {
+		for hIdx, hName := range HorizonLabels {
+			bm := cells[mIdx][hIdx].BigMoves
+			if bm.Count == 0 {
+				continue
+			}
+			fmt.Fprintf(
+				w,
+				"%s\t%s\t%d\t%.3f\t%.2f\t%+.3f\t%.3f\n",
+				name,
+				hName,
+				bm.Count,
+				bm.HitRate,
+				bm.HitRateZ,
+				bm.MeanZ,
+				bm.Elevated,
+			)
+		}
+		fmt.Fprintf(w, "\n")
+	}
+
+	endSection()
+
+	// 12) Regime-gated ensemble: within each backward-vol tercile, the model
+	// with the best IS |IC| supplies the signal. BestSingle is the best
+	// individual model on OOS Sharpe, which the ensemble has to beat.
+	fmt.Fprintf(w, "\n\n# Regime-gated ensemble (backward vol terciles; picks fit on IS)\n")
+	fmt.Fprintf(w, "MODEL\tHORIZON\tVolLow\tVolMed\tVolHigh\tPearsonIC\tHitRate\tSharpe\tBestSingle\tBestSharpe\n")
+	fmt.Fprintf(w, "-----\t-------\t------\t------\t-------\t---------\t-------\t------\t----------\t----------\n")
+
+	// Every cell holds the same samples in the same (chronological) order,
+	// so the multi-model sections (12, 13) line models up by index.
+	base := results[0][0]
+	times := base.Times
+	bestSingle := func(hIdx int) (string, float64) {
+		best := -1
+		for mIdx := range modelNames {
+			c := core[mIdx][hIdx]
+			if c.TestCount > 0 && (best < 0 || c.Sharpe > core[best][hIdx].Sharpe) {
+				best = mIdx
+			}
+		}
+		if best < 0 {
+			return "-", 0
+		}
+		return modelNames[best], core[best][hIdx].Sharpe
+	}
+
+	if len(modelNames) > 1 && len(times) > 0 {
+		regime := RegimeVol(times, base.Targs, HorizonDelays[0], VolRegimeWindow)
+		pickName := func(ge GatedEnsemble, r int) string {
+			if ge.Picks[r] < 0 {
+				return "-"
+			}
+			if ge.Signs[r] < 0 {
+				return "-" + modelNames[ge.Picks[r]]
+			}
+			return "+" + modelNames[ge.Picks[r]]
+		}
+
+		for hIdx, hName := range HorizonLabels {
+			feats := make([][]float64, len(modelNames))
+			for mIdx := range modelNames {
+				feats[mIdx] = results[hIdx][mIdx].Feats
+			}
+			ge := GatedEnsembleOOS(times, feats, results[hIdx][0].Targs, regime, trainFrac, HorizonDelays[hIdx])
+			st := ge.Stats
+			if st.TestCount == 0 || st.Suppressed {
+				continue
+			}
+			bestName, bestSharpe := bestSingle(hIdx)
+			fmt.Fprintf(
+				w,
+				"Gated_Ensemble\t%s\t%s\t%s\t%s\t%.4f\t%.3f\t%.3f\t%s\t%.3f\n",
+				hName,
+				pickName(ge, 0),
+				pickName(ge, 1),
+				pickName(ge, 2),
+				st.PearsonIC,
+				st.HitRate,
+				st.Sharpe,
+				bestName,
+				bestSharpe,
+			)
+			// Picked or fit across every model, so not one of the BH family's
+			// tests: q = 1 keeps it from ever passing the FDR column.
+			st.ICQValue = 1
+			batch.Stats("Gated_Ensemble", hName, st)
+			csvOut.Stats("Gated_Ensemble", hName, st)
+			js.Stats(sym, "Gated_Ensemble", hName, st)
+		}
+	}
+
+	endSection()
+
+	// 13) Ridge combiner: one linear blend of all models per horizon, fit on
+	// IS. The weights (per z-scored signal) show which models carry it.
+	fmt.Fprintf(w, "\n\n# Ridge combiner (lambda %g; weights fit on IS)\n", RidgeLambda)
+	fmt.Fprintf(w, "MODEL\tHORIZON\tPearsonIC\tHitRate\tSharpe\tBestSingle\tBestSharpe\n")
+	fmt.Fprintf(w, "-----\t-------\t---------\t-------\t------\t----------\t----------\n")
+
+	if len(modelNames) > 1 && len(times) > 0 {
+		combos := make([]RidgeCombo, len(HorizonLabels))
+		for hIdx, hName := range HorizonLabels {
+			feats := make([][]float64, len(modelNames))
+			for mIdx := range modelNames {
+				feats[mIdx] = results[hIdx][mIdx].Feats
+			}
+			combos[hIdx] = RidgeCombineOOS(times, feats, results[hIdx][0].Targs, trainFrac, RidgeLambda, HorizonDelays[hIdx])
+			st := combos[hIdx].Stats
+			if st.TestCount == 0 || st.Suppressed {
+				continue
+			}
+			bestName, bestSharpe := bestSingle(hIdx)
+			fmt.Fprintf(w, "Ridge_Combo\t%s\t%.4f\t%.3f\t%.3f\t%s\t%.3f\n", hName, st.PearsonIC, st.HitRate, st.Sharpe, bestName, bestSharpe)
+			// Picked or fit across every model, so not one of the BH family's
+			// tests: q = 1 keeps it from ever passing the FDR column.
+			st.ICQValue = 1
+			batch.Stats("Ridge_Combo", hName, st)
+			csvOut.Stats("Ridge_Combo", hName, st)
+			js.Stats(sym, "Ridge_Combo", hName, st)
+		}
+
+		fmt.Fprintf(w, "\nWEIGHT\t%s\n", strings.Join(HorizonLabels, "\t"))
+		fmt.Fprintf(w, "------")
+		for _, hName := range HorizonLabels {
+			fmt.Fprintf(w, "\t%s", strings.Repeat("-", len(hName)))
+		}
+		fmt.Fprintf(w, "\n")
+		for mIdx, name := range modelNames {
+			fmt.Fprintf(w, "%s", name)
+			for hIdx := range HorizonLabels {
+				fmt.Fprintf(w, "\t%+.4f", combos[hIdx].Weights[mIdx])
+			}
+			fmt.Fprintf(w, "\n")
+		}
+	}
+
+	endSection()
+
+	// 14) Holding-period PnL (alpha decay): mean sign(signal) PnL by time
+	// since entry, and the holding time where it peaks.
+	if holding != nil {
+		fmt.Fprintf(w, "\n\n# Holding-period PnL (test segment, sign(signal) entries, %gs steps, bps)\n", HoldingStepSec)
+		fmt.Fprintf(w, "MODEL\tBestHold\tPnL@Best")
+		for _, hName := range HorizonLabels {
+			fmt.Fprintf(w, "\tPnL@%s", hName)
+		}
+		fmt.Fprintf(w, "\n-----\t--------\t--------")
+		for _, hName := range HorizonLabels {
+			fmt.Fprintf(w, "\t%s", strings.Repeat("-", len("PnL@"+hName)))
+		}
+		fmt.Fprintf(w, "\n")
+
+		for mIdx, name := range modelNames {
+			hc := holding[mIdx]
+			if hc.Best < 0 {
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%gm\t%+.2f", name, float64(hc.HoldMs(hc.Best))/60000, hc.MeanBps[hc.Best])
+			for _, delay := range HorizonDelays {
+				k := int((delay+hc.StepMs-1)/hc.StepMs) - 1
+				if k < len(hc.Counts) && hc.Counts[k] > 0 {
+					fmt.Fprintf(w, "\t%+.2f", hc.MeanBps[k])
+				} else {
+					fmt.Fprintf(w, "\t-")
+				}
+			}
+			fmt.Fprintf(w, "\n")
+		}
+	}
+
+	endSection()
+
+	// 15) Feature cross-correlation, to spot redundant models
+	fmt.Fprintf(w, "\n\n# Feature correlation (Pearson, train segment; * marks |rho| > %g)\n", CorrFlagAbove)
+	fmt.Fprintf(w, "#\tMODEL")
+	for i := range modelNames {
+		fmt.Fprintf(w, "\t%d", i+1)
+	}
+	fmt.Fprintf(w, "\n-\t-----")
+	for i := range modelNames {
+		fmt.Fprintf(w, "\t%s", strings.Repeat("-", len(strconv.Itoa(i+1))))
+	}
+	fmt.Fprintf(w, "\n")
+	var redundant []string
+	for i, name := range modelNames {
+		fmt.Fprintf(w, "%d\t%s", i+1, name)
+		for j, rho := range featCorr[i] {
+			flag := ""
+			if j != i && math.Abs(rho) > CorrFlagAbove {
+				flag = "*"
+				if j > i {
+					redundant = append(redundant, fmt.Sprintf("%s ~ %s (%+.3f)", name, modelNames[j], rho))
+				}
+			}
+			fmt.Fprintf(w, "\t%+.2f%s", rho, flag)
+		}
+		fmt.Fprintf(w, "\n")
+	}
+	for _, r := range redundant {
+		fmt.Fprintf(w, "Redundant: %s\n", r)
+	}
+
+	endSection()
+
+	// 16) Feature orthogonality: clusters of near-duplicate features and
+	// what each adds beyond the stronger ones, per horizon. "Stronger" is
+	// by train IC; the ICs shown are the test segment's.
+	clusters := FeatureClusters(featCorr, CorrFlagAbove)
+	trainICs := make([][]float64, len(HorizonLabels))
+	icCols := make([][]float64, len(HorizonLabels))
+	orthCols := make([][]float64, len(HorizonLabels))
+	for hIdx := range HorizonLabels {
+		trainICs[hIdx] = make([]float64, len(modelNames))
+		testFeats := make([][]float64, len(modelNames))
+		var testRet []float64
+		for mIdx := range modelNames {
+			data := results[hIdx][mIdx]
+			s := purgedSplit(data.Times, data.Feats, data.Targs, trainFrac, HorizonDelays[hIdx])
+			trainICs[hIdx][mIdx] = Pearson(s.TrainF, s.TrainR)
+			testFeats[mIdx] = s.TestF
+			if testRet == nil {
+				testRet = s.TestR
+			}
+		}
+		icCols[hIdx], orthCols[hIdx] = OrthogonalICs(testFeats, testRet, trainICs[hIdx])
+	}
+
+	fmt.Fprintf(w, "\n\n# Feature orthogonality (clusters link |rho| > %g; OrthIC = test IC after Gram-Schmidt against features with higher train |IC|)\n", CorrFlagAbove)
+	fmt.Fprintf(w, "CLUSTER\tMODEL\tNearest\tRho")
+	for _, hName := range HorizonLabels {
+		fmt.Fprintf(w, "\tIC_%s\tOrthIC_%s", hName, hName)
+	}
+	fmt.Fprintf(w, "\n-------\t-----\t-------\t---")
+	for _, hName := range HorizonLabels {
+		fmt.Fprintf(w, "\t%s\t%s", strings.Repeat("-", len(hName)+3), strings.Repeat("-", len(hName)+7))
+	}
+	fmt.Fprintf(w, "\n")
+	byCluster := make([]int, len(modelNames))
+	for i := range byCluster {
+		byCluster[i] = i
+	}
+	sort.SliceStable(byCluster, func(a, b int) bool { return clusters[byCluster[a]] < clusters[byCluster[b]] })
+	for _, i := range byCluster {
+		nearest, rho := "-", 0.0
+		for j, r := range featCorr[i] {
+			if j != i && math.Abs(r) > math.Abs(rho) {
+				nearest, rho = modelNames[j], r
+			}
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%+.2f", clusters[i], modelNames[i], nearest, rho)
+		for hIdx := range HorizonLabels {
+			fmt.Fprintf(w, "\t%+.4f\t%+.4f", icCols[hIdx][i], orthCols[hIdx][i])
+		}
+		fmt.Fprintf(w, "\n")
+	}
+	for hIdx, hName := range HorizonLabels {
+		var names []string
+		for _, i := range SelectModels(featCorr, trainICs[hIdx], SelectMaxCorr, SelectCount) {
+			names = append(names, fmt.Sprintf("%s (IS %+.4f, OOS %+.4f)", modelNames[i], trainICs[hIdx][i], icCols[hIdx][i]))
+		}
+		fmt.Fprintf(w, "Selected %s (on train IC, up to %d, |rho| < %g): %s\n", hName, SelectCount, SelectMaxCorr, strings.Join(names, ", "))
+	}
+
+	endSection()
+
+	writeDaySections(w, endSection, daySamples, breaks, so.Barriers)
+	reportDone(sym, so.Processed, start, filename)
+	return core
+}
+
+// writeDaySections writes the report's per-day sections (17, 18), the
+// triple-barrier summary (19) when there is one, and flushes w.
+func writeDaySections(w *tabwriter.Writer, endSection func(), daySamples []int, breaks []priceBreak, barriers []BarrierSummary) {
+	// 17) Realized samples per day (sampling-grid diagnostics)
+	fmt.Fprintf(w, "\n\n# Samples per day (labeled, after horizon truncation)\n")
+	fmt.Fprintf(w, "Days\tMin\tP10\tMedian\tP90\tMax\tMean\tAligned\n")
+	fmt.Fprintf(w, "----\t---\t---\t------\t---\t---\t----\t-------\n")
+	if len(daySamples) > 0 {
+		sort.Ints(daySamples)
+		nd := len(daySamples)
+		total := 0
+		for _, c := range daySamples {
+			total += c
+		}
+		fmt.Fprintf(
+			w,
+			"%d\t%d\t%d\t%d\t%d\t%d\t%.1f\t%v\n",
+			nd,
+			daySamples[0],
+			daySamples[nd/10],
+			daySamples[nd/2],
+			daySamples[(9*nd)/10],
+			daySamples[nd-1],
+			float64(total)/float64(nd),
+			AlignSamplingGrid,
+		)
+	}
+
+	endSection()
+
+	// 18) Price discontinuities between consecutive days
+	mode := "no cross-day labels, so detection only"
+	if CrossDayLabels {
+		mode = "cross-day labels: " + PriceBreakMode
+	}
+	fmt.Fprintf(w, "\n\n# Price breaks (day-over-day ratio outside 1/%g..%g; %s)\n", PriceBreakRatio, PriceBreakRatio, mode)
+	fmt.Fprintf(w, "PrevDay\tDay\tRatio\n")
+	fmt.Fprintf(w, "-------\t---\t-----\n")
+	for _, b := range breaks {
+		fmt.Fprintf(w, "%s\t%s\t%.6g\n", b.Prev, b.Day, b.Ratio)
+	}
+
+	// 19) Triple-barrier labels: which barrier ended each label and after
+	// how long (all labeled samples, so every model shares it)
+	if barriers != nil {
+		endSection()
+		fmt.Fprintf(w, "\n\n# Triple barrier (±%g sigma; share of labels ending at each barrier, mean holding period)\n", BarrierK)
+		fmt.Fprintf(w, "HORIZON\tN\tUp\tDown\tExpired\tMeanHold\n")
+		fmt.Fprintf(w, "-------\t-\t--\t----\t-------\t--------\n")
+		for hIdx, b := range barriers {
+			n := b.N()
+			if n == 0 {
+				continue
+			}
+			fmt.Fprintf(
+				w,
+				"%s\t%d\t%.3f\t%.3f\t%.3f\t%s\n",
+				HorizonLabels[hIdx],
+				n,
+				float64(b.Up)/float64(n),
+				float64(b.Down)/float64(n),
+				float64(b.Expired)/float64(n),
+				b.MeanHold().Round(time.Second),
+			)
+		}
+	}
+
+	w.Flush()
+}
+
+// reportDone prints RunTestForSymbol's closing line.
+func reportDone(sym string, processed int64, start time.Time, filename string) {
+	if ReportFormat != "text" {
+		fmt.Printf("Done. [%s] Processed %d days in %s.\n", sym, processed, time.Since(start))
+		return
+	}
+	fmt.Printf("Done. [%s] Processed %d days in %s. OOS report saved to %s\n", sym, processed, time.Since(start), filename)
+}
+
+// writeJSONReport writes js to path; a nil js (text format) is a no-op.
+func writeJSONReport(js *JSONReport, path string) {
+	if js == nil {
+		return
+	}
+	if err := js.WriteFile(path); err != nil {
+		fmt.Printf("ERROR: could not write JSON report %s: %v\n", path, err)
+		return
+	}
+	fmt.Printf("JSON report saved to %s\n", path)
+}
+
+// ciString formats a confidence interval as [lo,hi], or "-" when
+// BootstrapIntervals is off.
+func ciString(lo, hi float64, prec int) string {
+	if !BootstrapIntervals {
+		return "-"
+	}
+	return fmt.Sprintf("[%.*f,%.*f]", prec, lo, prec, hi)
+}
+
+// rankString formats a rank-normalized metric, or "-" when RankNormMetrics
+// is off.
+func rankString(v float64, prec int) string {
+	if !RankNormMetrics {
+		return "-"
+	}
+	return fmt.Sprintf("%.*f", prec, v)
+}
+
+// selectHorizon picks from one model's row of core the horizon with the best
+// IS Sharpe (sel, the one the report commits to) and the one with the best
+// OOS Sharpe (best, for comparison), over the reported cells; -1 when there
+// are none.
+func selectHorizon(row []ReportStats) (sel, best int) {
+	sel, best = -1, -1
+	for hIdx, st := range row {
+		if st.TestCount == 0 {
+			continue
+		}
+		if sel < 0 || st.ISSharpe > row[sel].ISSharpe {
+			sel = hIdx
+		}
+		if best < 0 || st.Sharpe > row[best].Sharpe {
+			best = hIdx
+		}
+	}
+	return sel, best
+}
+
+// trialSharpeVariance counts the reported cells of core (the DSR's trials)
+// and returns the sample variance of their Sharpes, 0 with fewer than two.
+func trialSharpeVariance(core [][]ReportStats) (n int, variance float64) {
+	var sharpes []float64
+	for _, row := range core {
+		for _, st := range row {
+			if st.TestCount > 0 {
+				sharpes = append(sharpes, st.Sharpe)
+			}
+		}
+	}
+	if len(sharpes) < 2 {
+		return len(sharpes), 0
+	}
+	// meanStd's variance is the population one.
+	_, std := meanStd(sharpes)
+	n = len(sharpes)
+	return n, std * std * float64(n) / float64(n-1)
+}
+
+// fdrFlag is the core table's FDR column: "*" when the cell's IC survives
+// the Benjamini-Hochberg adjustment at FDRLevel.
+func fdrFlag(s ReportStats) string {
+	if s.FDRPass() {
+		return "*"
+	}
+	return "-"
+}
+
+// symbolTasks returns sym's days, restricted to [DayFrom, DayTo] and the
+// SampleFrac subset, in chronological order.
+func symbolTasks(sym string) []ofiTask {
+	tasks := make([]ofiTask, 0)
+	for t := range discoverTasks(sym) {
+		if t.inDateRange() && t.inSample() {
+			tasks = append(tasks, t)
+		}
+	}
+
+	// Sort tasks chronologically so workers process days in a sensible order.
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].before(tasks[j]) })
+	return tasks
+}
+
+// Report section parameters for the per-cell metrics.
+const (
+	rollingWindows = 8
+	bigMoves       = 50
+)
+
+// cellMetrics holds one (model, horizon) cell's results for the per-cell
+// report sections.
+type cellMetrics struct {
+	Stats       ReportStats
+	Windows     []WindowMetrics
+	VolRegimes  []RegimeMetrics
+	TODRegimes  []RegimeMetrics
+	Boundary    BoundaryMetrics
+	Folds       []ReportStats
+	FoldSummary WalkForwardSummary
+	BigMoves    BigMoveMetrics
+}
+
+// momentCells wraps a -low-mem run's moments (see FinalizeMoments) as
+// cells[model][horizon].
+func momentCells(moments [][]CellMoments, numModels int) [][]cellMetrics {
+	cells := make([][]cellMetrics, numModels)
+	for m := range cells {
+		cells[m] = make([]cellMetrics, len(HorizonDelays))
+		for h, delay := range HorizonDelays {
+			if moments[h][m].Train.N+moments[h][m].Test.N > 0 {
+				cells[m][h].Stats = FinalizeMoments(moments[h][m], delay)
+			}
+		}
+	}
+	return cells
+}
+
+// newCellMoments returns zeroed [horizon][model] accumulators.
+func newCellMoments(numModels int) [][]CellMoments {
+	out := make([][]CellMoments, len(HorizonDelays))
+	for h := range out {
+		out[h] = make([]CellMoments, numModels)
+	}
+	return out
+}
+
+// analyzeCells computes every (model, horizon) cell's metrics on a
+// CPUThreads worker pool and returns them as cells[model][horizon], so the
+// report can print them in its usual order. The metrics only read the
+// (chronological) cells, so tasks share them. Each model's feature is
+// ranked once up front for all of its horizons. Empty cells stay zero.
+func analyzeCells(results [][]*ResultContainer, numModels int, trainFrac float64) [][]cellMetrics {
+	// Every horizon's test segment starts at or after the unembargoed cut.
+	ranks := make([]*FeatureRanks, numModels)
+	parallelFor(numModels, func(m int) {
+		src := results[0][m]
+		if test := sortedTrainTestSplit(src.Times, src.Feats, src.Targs, trainFrac, 0, 0).TestF; len(test) > 0 {
+			ranks[m] = NewFeatureRanks(src.Feats, len(src.Feats)-len(test))
+		}
+	})
+
+	cells := make([][]cellMetrics, numModels)
+	type cellTask struct{ m, h int }
+	var tasks []cellTask
+	for m := range cells {
+		cells[m] = make([]cellMetrics, len(HorizonDelays))
+		for h := range HorizonDelays {
+			if len(results[h][m].Feats) > 0 {
+				tasks = append(tasks, cellTask{m, h})
+			}
+		}
+	}
+	parallelFor(len(tasks), func(i int) {
+		t := tasks[i]
+		src := results[t.h][t.m]
+		times, feats, targs := src.Times, src.Feats, src.Targs
+		horizon := HorizonDelays[t.h]
+
+		c := &cells[t.m][t.h]
+		c.Stats = AnalyzeFullSuiteOOSCached(times, feats, targs, trainFrac, horizon, ranks[t.m])
+		c.Windows = RollingWindowMetricsOOS(times, feats, targs, trainFrac, horizon, rollingWindows)
+		c.VolRegimes = VolRegimeMetricsOOS(times, feats, targs, trainFrac, horizon, VolRegimeWindow)
+		c.TODRegimes = TimeOfDayRegimeMetricsOOS(times, feats, targs, trainFrac, horizon)
+		c.Boundary = BoundarySweepOOS(times, feats, targs, BoundaryFracs, horizon)
+		c.Folds, c.FoldSummary = WalkForwardOOS(times, feats, targs, WalkForwardFolds, horizon)
+		c.BigMoves = BigMoveMetricsOOS(times, feats, targs, trainFrac, horizon, bigMoves)
+	})
+	return cells
+}
+
+// parallelFor calls fn(i) for every i in [0, n) on CPUThreads workers and
+// returns when all calls have.
+func parallelFor(n int, fn func(i int)) {
+	next := make(chan int, n)
+	for i := range n {
+		next <- i
+	}
+	close(next)
+
+	var wg sync.WaitGroup
+	for range CPUThreads {
+		wg.Go(func() {
+			for i := range next {
+				fn(i)
+			}
+		})
+	}
+	wg.Wait()
+}
+
+// streamOutput is what streamTasks returns for one symbol.
+type streamOutput struct {
+	Results [][]*ResultContainer // [horizon][model], merged across workers in time order
+
+	// Prices is each sample's label price, in the same order as every
+	// Results cell: chronological.
+	Prices []float64
+
+	DaySamples []int        // labeled samples per processed day
+	Processed  int64        // days processed
+	Breaks     []priceBreak // detected between consecutive days
+
+	// Moments replaces Results (and Prices) when streamTasks is given a
+	// train/test cut: [horizon][model] accumulators, no per-sample data.
+	Moments [][]CellMoments
+
+	// Barriers tallies the triple-barrier labels per horizon over every
+	// labeled sample; nil unless TripleBarrier.
+	Barriers []BarrierSummary
+}
+
+// streamTasks runs every task through RunStream on a CPUThreads worker pool
+// and collects the labeled samples (see streamOutput). With splitMs > 0
+// (-low-mem, see lowMemSplit) it keeps only per-cell train/test moments.
+func streamTasks(sym string, tasks []ofiTask, newModels func() []ContinuousModel, splitMs int64) streamOutput {
+	models := newModels()
+
+	// Global results[horizon][model].
+	results := make([][]*ResultContainer, len(HorizonLabels))
+	for h := range results {
+		results[h] = make([]*ResultContainer, len(models))
+		for m := range results[h] {
+			results[h][m] = &ResultContainer{}
+		}
+	}
+
+	// Per-worker result storage.
+	workerResults := make([]*WorkerResults, CPUThreads)
+	for i := 0; i < CPUThreads; i++ {
+		wr := &WorkerResults{
+			Data: make([][]*ResultContainer, len(HorizonLabels)),
+		}
+		for h := range wr.Data {
+			wr.Data[h] = make([]*ResultContainer, len(models))
+			for m := range wr.Data[h] {
+				wr.Data[h][m] = &ResultContainer{}
+			}
+		}
+		if splitMs > 0 {
+			wr.Moments = newCellMoments(len(models))
+		}
+		if TripleBarrier {
+			wr.Barriers = make([]BarrierSummary, len(HorizonLabels))
+		}
+		workerResults[i] = wr
+	}
+
+	// Task channel and worker pool.
+	taskCh := make(chan ofiTask, len(tasks))
+	for _, t := range tasks {
+		taskCh <- t
+	}
+	close(taskCh)
+
+	var wg sync.WaitGroup
+	var processed atomic.Int64
+
+	for wID := 0; wID < CPUThreads; wID++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+
+			localStore := workerResults[id]
+			localModels := newModels()
+
+			cols := DayColumnPool.Get().(*DayColumns)
+			defer DayColumnPool.Put(cols)
+
+			var next *DayColumns
+			if CrossDayLabels {
+				next = DayColumnPool.Get().(*DayColumns)
+				defer DayColumnPool.Put(next)
+			}
+			var prior *DayColumns
+			if samplerNeedsPriorDay() {
+				prior = DayColumnPool.Get().(*DayColumns)
+				defer DayColumnPool.Put(prior)
+			}
+
+			loader := NewDayLoader(BaseDir, sym)
+			defer loader.Close()
+			// streamRes is copied into localStore before the next day.
+			streamBufs := &StreamBuffers{}
+
+			for task := range taskCh {
+				raw, ok := loader.Load(task)
+				if !ok {
+					continue
+				}
+				if _, err := InflateGNC(raw, cols); err != nil || cols.Count == 0 {
+					continue
+				}
+				lastP := cols.Prices[cols.Count-1]
+				localStore.DayPrices = append(localStore.DayPrices, dayPrices{task, cols.Prices[0], lastP})
+
+				// Event clocks are sized from the previous day (see
+				// newDaySampler); without one the day can't be sampled.
+				var priorCols *DayColumns
+				if prior != nil {
+					if raw, ok := loader.Load(task.prev()); ok {
+						if _, err := InflateGNC(raw, prior); err == nil && prior.Count > 0 {
+							priorCols = prior
+						}
+					}
+				}
+				sampler := newDaySampler(priorCols)
+				if sampler == nil {
+					continue
+				}
+
+				// The next day is decoded after this one: cols is a copy, so
+				// the loader may reuse its buffer.
+				var nextCols *DayColumns
+				if next != nil {
+					if raw, ok := loader.Load(task.next()); ok {
+						if _, err := InflateGNC(raw, next); err == nil && next.Count > 0 {
+							nextCols = next
+						}
+					}
+				}
+				if nextCols != nil && isPriceBreak(lastP, nextCols.Prices[0]) {
+					if PriceBreakMode == "adjust" {
+						// Continuity factor: join the next day onto this one.
+						f := lastP / nextCols.Prices[0]
+						for i := range nextCols.Prices[:nextCols.Count] {
+							nextCols.Prices[i] *= f
+						}
+					} else {
+						nextCols = nil
+					}
+				}
+
+				streamRes := RunStream(cols, nextCols, localModels, HorizonDelays, sampler, streamBufs)
+				if len(streamRes.Times) == 0 {
+					continue
+				}
+
+				numSamples := len(streamRes.Times)
+				localStore.DaySamples = append(localStore.DaySamples, numSamples)
+				numModels := streamRes.NumModels
+				numHorizons := streamRes.NumHorizons
+
+				if streamRes.Labels != nil {
+					for s, t := range streamRes.Times {
+						for hIdx := range numHorizons {
+							k := s*numHorizons + hIdx
+							localStore.Barriers[hIdx].add(streamRes.Labels[k], streamRes.TouchTimes[k]-t)
+						}
+					}
+				}
+
+				if splitMs > 0 {
+					for s := 0; s < numSamples; s++ {
+						t := float64(streamRes.Times[s])
+						for mIdx := 0; mIdx < numModels; mIdx++ {
+							featVal := streamRes.Features[s*numModels+mIdx]
+							for hIdx, delay := range HorizonDelays {
+								targVal := streamRes.Targets[s*numHorizons+hIdx]
+								localStore.Moments[hIdx][mIdx].add(t, featVal, targVal, splitMs, delay)
+							}
+						}
+					}
+					processed.Add(1)
+					continue
+				}
+
+				// Append into thread-local storage.
+				localStore.Prices = append(localStore.Prices, streamRes.Prices...)
+				for s := 0; s < numSamples; s++ {
+					t := float64(streamRes.Times[s])
+
+					featBase := s * numModels
+					targBase := s * numHorizons
+
+					for mIdx := 0; mIdx < numModels; mIdx++ {
+						featVal := streamRes.Features[featBase+mIdx]
+						for hIdx := 0; hIdx < numHorizons; hIdx++ {
+							targVal := streamRes.Targets[targBase+hIdx]
+
+							rc := localStore.Data[hIdx][mIdx]
+							rc.Times = append(rc.Times, t)
+							rc.Feats = append(rc.Feats, featVal)
+							rc.Targs = append(rc.Targs, targVal)
+						}
+					}
+				}
+
+				processed.Add(1)
+			}
+		}(wID)
+	}
+	wg.Wait()
+
+	var daySamples []int
+	var days []dayPrices
+	var prices []float64
+	var barriers []BarrierSummary
+	if TripleBarrier {
+		barriers = make([]BarrierSummary, len(HorizonLabels))
+	}
+	for _, wr := range workerResults {
+		for hIdx, b := range wr.Barriers {
+			barriers[hIdx].merge(b)
+		}
+		daySamples = append(daySamples, wr.DaySamples...)
+		days = append(days, wr.DayPrices...)
+		prices = append(prices, wr.Prices...)
+	}
+	breaks := detectPriceBreaks(days)
+
+	var moments [][]CellMoments
+	if splitMs > 0 {
+		moments = newCellMoments(len(models))
+		for _, wr := range workerResults {
+			for hIdx := range moments {
+				for mIdx := range moments[hIdx] {
+					moments[hIdx][mIdx].Merge(wr.Moments[hIdx][mIdx])
+				}
+			}
+		}
+	}
+
+	modelNames := make([]string, len(models))
+	for i, m := range models {
+		modelNames[i] = m.Name()
+	}
+	prices = mergeWorkerCells(results, workerResults, prices, modelNames)
+
+	return streamOutput{
+		Results:    results,
+		Prices:     prices,
+		DaySamples: daySamples,
+		Processed:  processed.Load(),
+		Breaks:     breaks,
+		Moments:    moments,
+		Barriers:   barriers,
+	}
+}
+
+// mergeWorkerCells merges every worker's cells into results[horizon][model]
+// in chronological order and returns prices, the workers' label prices
+// concatenated in worker-ID order, in that same order.
+//
+// Workers pull days off a shared channel, so the worker-ID concatenation
+// is in scheduling order. Every cell (and prices) holds the same samples
+// in that order, so one stable chronological permutation sorts them all;
+// only samples of the same day can share a timestamp, so the result
+// doesn't depend on which worker ran which day.
+//
+// Cells are merged one at a time: every worker's column is concatenated in
+// worker-ID order into scratch, gathered chronologically into an exactly
+// sized destination, and the worker's slices are dropped so they can be
+// collected before the next cell is merged. Peak memory is the worker data
+// plus one merged cell and one scratch column, instead of worker data plus
+// all merged cells.
+func mergeWorkerCells(results [][]*ResultContainer, workers []*WorkerResults, prices []float64, modelNames []string) []float64 {
+	var refTimes []float64
+	for _, wr := range workers {
+		refTimes = append(refTimes, wr.Data[0][0].Times...)
+	}
+	order := make([]int, len(refTimes))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int { return cmp.Compare(refTimes[a], refTimes[b]) })
+	refTimes = nil
+	scratch := make([]float64, len(order))
+	gather := func(cat []float64) []float64 {
+		out := make([]float64, len(order))
+		for i, j := range order {
+			out[i] = cat[j]
+		}
+		return out
+	}
+	prices = gather(prices)
+	if len(order) == 0 {
+		return prices
+	}
+
+	for hIdx := range results {
+		for mIdx := range results[hIdx] {
+			column := func(get func(*ResultContainer) []float64) []float64 {
+				cat := scratch[:0]
+				for _, wr := range workers {
+					cat = append(cat, get(wr.Data[hIdx][mIdx])...)
+				}
+				if len(cat) != len(order) {
+					// Every column of every cell gets one value per sample.
+					panic(fmt.Sprintf("streamTasks: %s %s cell has %d values for %d samples",
+						modelNames[mIdx], HorizonLabels[hIdx], len(cat), len(order)))
+				}
+				return gather(cat)
+			}
+
+			dst := results[hIdx][mIdx]
+			dst.Times = column(func(rc *ResultContainer) []float64 { return rc.Times })
+			dst.Feats = column(func(rc *ResultContainer) []float64 { return rc.Feats })
+			dst.Targs = column(func(rc *ResultContainer) []float64 { return rc.Targs })
+			for _, wr := range workers {
+				*wr.Data[hIdx][mIdx] = ResultContainer{}
+			}
+		}
+	}
+	return prices
 }
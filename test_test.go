@@ -0,0 +1,197 @@
+package main
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// horizonRow builds one model's core row from synthetic horizons: horizon h
+// has returns beta[h]·feat + noise, n samples 1s apart with 1s labels, so no
+// sample overlaps another. Cells get the core table's DSR.
+func horizonRow(betas []float64, n int, rng *rand.Rand) []ReportStats {
+	row := make([]ReportStats, len(betas))
+	for h, beta := range betas {
+		times := make([]float64, n)
+		feats := make([]float64, n)
+		rets := make([]float64, n)
+		for i := range n {
+			times[i] = float64(i * 1000)
+			feats[i] = rng.NormFloat64()
+			rets[i] = beta*feats[i] + rng.NormFloat64()
+		}
+		row[h] = AnalyzeFullSuiteOOS(times, feats, rets, 0.7, 1000)
+	}
+	nTrials, trialVar := trialSharpeVariance([][]ReportStats{row})
+	for h, st := range row {
+		row[h].DSR, _ = DeflatedSharpe(st.Sharpe, st.TradeSkew, st.TradeKurtosis, st.EffectiveN, nTrials, trialVar)
+	}
+	return row
+}
+
+func TestSelectHorizonFindsGenuineBest(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	row := horizonRow([]float64{0, 0.05, 0.3, 0.1, 0}, 4000, rng)
+	sel, best := selectHorizon(row)
+	if sel != 2 || best != 2 {
+		t.Fatalf("sel=%d best=%d, want 2 and 2", sel, best)
+	}
+	if row[sel].DSR < 0.95 {
+		t.Fatalf("DSR of a genuine signal = %.3f, want >= 0.95", row[sel].DSR)
+	}
+}
+
+// TestSelectHorizonOverfitControl selects among pure-noise horizons, over
+// many independent models: IS selection always finds a winner, but its edge
+// shouldn't survive OOS and the DSR shouldn't vouch for it.
+func TestSelectHorizonOverfitControl(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	const models = 50
+	var isSum, oosSum float64
+	passed := 0
+	for range models {
+		row := horizonRow(make([]float64, 10), 1000, rng)
+		sel, _ := selectHorizon(row)
+		if sel < 0 {
+			t.Fatal("no horizon selected")
+		}
+		isSum += row[sel].ISSharpe
+		oosSum += row[sel].Sharpe
+		if row[sel].DSR >= 0.95 {
+			passed++
+		}
+	}
+	isMean, oosMean := isSum/models, oosSum/models
+	if isMean <= 0 {
+		t.Fatalf("mean IS Sharpe of the IS-best noise horizon = %.4f, want > 0", isMean)
+	}
+	if oosMean > isMean/3 {
+		t.Fatalf("mean OOS Sharpe %.4f kept too much of the IS %.4f", oosMean, isMean)
+	}
+	if passed > models/10 {
+		t.Fatalf("DSR >= 0.95 for %d of %d noise selections", passed, models)
+	}
+}
+
+// TestCombinedRowsOutsideFDRFamily runs a report on a synthetic tree and
+// checks that the Gated_Ensemble and Ridge_Combo rows, picked across every
+// model, get q = 1 and never pass the FDR column.
+func TestCombinedRowsOutsideFDRFamily(t *testing.T) {
+	root := t.TempDir()
+	withBaseDir(t, root)
+	rng := rand.New(rand.NewSource(2))
+	days := make(map[int]synthDay)
+	for d := 1; d <= 4; d++ {
+		days[d] = randomDay(ofiTask{2024, 1, d}, 6000, 40000, rng)
+	}
+	writeSynthMonth(t, root, "BTCUSDT", 2024, 1, days)
+	defer func(prev string) { ReportFormat = prev }(ReportFormat)
+	ReportFormat = "json"
+
+	js := newJSONReport()
+	RunTestForSymbol("BTCUSDT", GetContinuousModels, filepath.Join(t.TempDir(), "report.txt"), nil, js)
+	rows := 0
+	for _, model := range []string{"Gated_Ensemble", "Ridge_Combo"} {
+		for h, c := range js.Symbols["BTCUSDT"][model] {
+			rows++
+			if c.OOS.ICQValue != 1 || c.OOS.FDRPass() {
+				t.Errorf("%s %s: ic_q = %v, FDRPass %v; want 1, false", model, h, c.OOS.ICQValue, c.OOS.FDRPass())
+			}
+		}
+	}
+	if rows == 0 {
+		t.Fatal("no combined rows reported")
+	}
+}
+
+// TestReportKeepsSectionsOnStop stops a report run right after its first
+// section, the core table, and checks that the text and CSV reports on disk
+// at that point already hold that section's last rows. The files are read
+// inside the stop, before RunTestForSymbol's deferred closes can flush
+// them, as after a killed run.
+func TestReportKeepsSectionsOnStop(t *testing.T) {
+	root := t.TempDir()
+	withBaseDir(t, root)
+	rng := rand.New(rand.NewSource(3))
+	days := make(map[int]synthDay)
+	for d := 1; d <= 4; d++ {
+		days[d] = randomDay(ofiTask{2024, 1, d}, 6000, 40000, rng)
+	}
+	writeSynthMonth(t, root, "BTCUSDT", 2024, 1, days)
+	defer func(prev string) { ReportFormat = prev }(ReportFormat)
+	defer func(prev func()) { reportSectionDone = prev }(reportSectionDone)
+	// The last row of the section is the one a buffered writer holds back.
+	models := GetContinuousModels()
+	model := models[len(models)-1].Name()
+
+	for _, format := range []string{"text", "csv"} {
+		ReportFormat = format
+		filename := filepath.Join(t.TempDir(), "report.txt")
+		path, want := filename, model+" "
+		if format == "csv" {
+			path, want = strings.TrimSuffix(filename, ".txt")+".csv", "core,BTCUSDT,"+model+","
+		}
+
+		type stop struct{}
+		var onDisk []byte
+		reportSectionDone = func() {
+			var err error
+			if onDisk, err = os.ReadFile(path); err != nil {
+				t.Error(err)
+			}
+			panic(stop{})
+		}
+		func() {
+			defer func() {
+				if r := recover(); r != (stop{}) {
+					panic(r)
+				}
+			}()
+			RunTestForSymbol("BTCUSDT", GetContinuousModels, filename, nil, nil)
+			t.Fatalf("%s: run not stopped", format)
+		}()
+
+		if !strings.Contains(string(onDisk), want) {
+			t.Fatalf("%s report at stop has no %q row:\n%s", format, want, onDisk)
+		}
+	}
+}
+
+// TestReportEmitsSuppressedCells runs a report on four synthetic days, where
+// the 1h horizon's test segment falls below MinEffectiveSamples and 15m
+// doesn't, and checks that the suppressed cells are still reported, marked,
+// with their effective sample size and without passing the FDR column.
+func TestReportEmitsSuppressedCells(t *testing.T) {
+	root := t.TempDir()
+	withBaseDir(t, root)
+	rng := rand.New(rand.NewSource(4))
+	days := make(map[int]synthDay)
+	for d := 1; d <= 4; d++ {
+		days[d] = randomDay(ofiTask{2024, 1, d}, 6000, 40000, rng)
+	}
+	writeSynthMonth(t, root, "BTCUSDT", 2024, 1, days)
+	defer func(prev string) { ReportFormat = prev }(ReportFormat)
+	ReportFormat = "json"
+
+	js := newJSONReport()
+	RunTestForSymbol("BTCUSDT", GetContinuousModels, filepath.Join(t.TempDir(), "report.txt"), nil, js)
+	for _, m := range GetContinuousModels() {
+		cells := js.Symbols["BTCUSDT"][m.Name()]
+		short, long := cells["15m"], cells["1h"]
+		if short == nil || short.OOS.Suppressed {
+			t.Fatalf("%s 15m: %+v, want a reported cell", m.Name(), short)
+		}
+		if long == nil {
+			t.Fatalf("%s 1h: suppressed cell not reported", m.Name())
+		}
+		st := long.OOS
+		if !st.Suppressed || st.TestCount == 0 || st.EffectiveN <= 0 || st.EffectiveN >= MinEffectiveSamples {
+			t.Fatalf("%s 1h: suppressed %v, test n %d, effective n %v", m.Name(), st.Suppressed, st.TestCount, st.EffectiveN)
+		}
+		if st.ICQValue != 1 || st.FDRPass() {
+			t.Fatalf("%s 1h: ic_q %v, FDRPass %v; want 1, false", m.Name(), st.ICQValue, st.FDRPass())
+		}
+	}
+}